@@ -1,20 +1,54 @@
 package neuralnet
 
 import (
+	"encoding/json"
+	"math"
+
 	"github.com/unixpickle/autofunc"
 	"github.com/unixpickle/num-analysis/linalg"
 )
 
+// DefaultLeakyReLULeak is the negative slope used by a
+// LeakyReLU whose Leak field is 0.
+const DefaultLeakyReLULeak = 0.01
+
+// DefaultELUAlpha is the saturation value used by an ELU
+// whose Alpha field is 0.
+const DefaultELUAlpha = 1.0
+
+// DefaultSwishBeta is the sigmoid scale used by a Swish
+// whose Beta field is 0, making it equivalent to SiLU.
+const DefaultSwishBeta = 1.0
+
+// DefaultHardTanhMin and DefaultHardTanhMax are the
+// clamp bounds used by a HardTanh whose Min and Max
+// fields are both 0.
+const (
+	DefaultHardTanhMin = -1.0
+	DefaultHardTanhMax = 1.0
+)
+
 // Sigmoid is a Layer which applies the
 // logistic sigmoid function.
+//
+// It is numerically stable for inputs of any
+// magnitude, and its derivatives are computed from
+// the cached outputs as y*(1-y).
 type Sigmoid struct{}
 
 func (_ Sigmoid) Apply(r autofunc.Result) autofunc.Result {
-	return autofunc.Sigmoid{}.Apply(r)
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		y := logistic(x)
+		return y, y * (1 - y)
+	})
 }
 
 func (_ Sigmoid) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
-	return autofunc.Sigmoid{}.ApplyR(v, r)
+	return applyElementwiseR(r, func(x float64) (float64, float64, float64) {
+		y := logistic(x)
+		dy := y * (1 - y)
+		return y, dy, dy * (1 - 2*y)
+	})
 }
 
 func (_ Sigmoid) Batch(inputs autofunc.Result, n int) autofunc.Result {
@@ -33,6 +67,13 @@ func (_ Sigmoid) SerializerType() string {
 	return serializerTypeSigmoid
 }
 
+// ReLU is a Layer which applies the rectified linear
+// function max(0, x).
+//
+// Since ReLUs are so common, ReLU uses dedicated loops
+// rather than applyElementwise, avoiding a function call
+// per component in both the forward and backward
+// passes.
 type ReLU struct{}
 
 func (_ ReLU) Apply(r autofunc.Result) autofunc.Result {
@@ -140,16 +181,287 @@ func (r *reLURResult) PropagateRGradient(upstream, upstreamR linalg.Vector,
 	r.Input.PropagateRGradient(upstream, upstreamR, rgrad, grad)
 }
 
+// LeakyReLU is a Layer which applies a leaky
+// rectified linear unit.
+// Unlike ReLU, it scales negative inputs by a small
+// constant rather than setting them to zero, so that
+// gradients keep flowing through inactive neurons.
+type LeakyReLU struct {
+	// Leak is the slope for negative inputs.
+	// If it is 0, DefaultLeakyReLULeak is used.
+	Leak float64
+}
+
+func DeserializeLeakyReLU(d []byte) (*LeakyReLU, error) {
+	var res LeakyReLU
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (l *LeakyReLU) Apply(r autofunc.Result) autofunc.Result {
+	leak := l.leak()
+	inVec := r.Output()
+	vec := make(linalg.Vector, len(inVec))
+	for i, x := range inVec {
+		if x > 0 {
+			vec[i] = x
+		} else {
+			vec[i] = x * leak
+		}
+	}
+	return &leakyReLUResult{
+		OutputVec: vec,
+		Input:     r,
+		Leak:      leak,
+	}
+}
+
+func (l *LeakyReLU) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	leak := l.leak()
+	inVec := r.Output()
+	inVecR := r.ROutput()
+	vec := make(linalg.Vector, len(inVec))
+	vecR := make(linalg.Vector, len(inVec))
+	for i, x := range inVec {
+		if x > 0 {
+			vec[i] = x
+			vecR[i] = inVecR[i]
+		} else {
+			vec[i] = x * leak
+			vecR[i] = inVecR[i] * leak
+		}
+	}
+	return &leakyReLURResult{
+		OutputVec:  vec,
+		ROutputVec: vecR,
+		Input:      r,
+		Leak:       leak,
+	}
+}
+
+func (l *LeakyReLU) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return l.Apply(inputs)
+}
+
+func (l *LeakyReLU) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return l.ApplyR(v, inputs)
+}
+
+func (l *LeakyReLU) Serialize() ([]byte, error) {
+	return json.Marshal(l)
+}
+
+func (l *LeakyReLU) SerializerType() string {
+	return serializerTypeLeakyReLU
+}
+
+func (l *LeakyReLU) leak() float64 {
+	if l.Leak == 0 {
+		return DefaultLeakyReLULeak
+	}
+	return l.Leak
+}
+
+type leakyReLUResult struct {
+	OutputVec linalg.Vector
+	Input     autofunc.Result
+	Leak      float64
+}
+
+func (l *leakyReLUResult) Output() linalg.Vector {
+	return l.OutputVec
+}
+
+func (l *leakyReLUResult) Constant(g autofunc.Gradient) bool {
+	return l.Input.Constant(g)
+}
+
+func (l *leakyReLUResult) PropagateGradient(upstream linalg.Vector, grad autofunc.Gradient) {
+	if l.Input.Constant(grad) {
+		return
+	}
+	for i, x := range l.Input.Output() {
+		if x <= 0 {
+			upstream[i] *= l.Leak
+		}
+	}
+	l.Input.PropagateGradient(upstream, grad)
+}
+
+type leakyReLURResult struct {
+	OutputVec  linalg.Vector
+	ROutputVec linalg.Vector
+	Input      autofunc.RResult
+	Leak       float64
+}
+
+func (l *leakyReLURResult) Output() linalg.Vector {
+	return l.OutputVec
+}
+
+func (l *leakyReLURResult) ROutput() linalg.Vector {
+	return l.ROutputVec
+}
+
+func (l *leakyReLURResult) Constant(rg autofunc.RGradient, g autofunc.Gradient) bool {
+	return l.Input.Constant(rg, g)
+}
+
+func (l *leakyReLURResult) PropagateRGradient(upstream, upstreamR linalg.Vector,
+	rgrad autofunc.RGradient, grad autofunc.Gradient) {
+	if l.Input.Constant(rgrad, grad) {
+		return
+	}
+	for i, x := range l.Input.Output() {
+		if x <= 0 {
+			upstream[i] *= l.Leak
+			upstreamR[i] *= l.Leak
+		}
+	}
+	l.Input.PropagateRGradient(upstream, upstreamR, rgrad, grad)
+}
+
+// ELU is a Layer which applies an exponential linear
+// unit, which is the identity for non-negative inputs
+// and alpha*(exp(x)-1) for negative inputs.
+type ELU struct {
+	// Alpha determines the value which the ELU
+	// approaches for very negative inputs (-Alpha).
+	// If it is 0, DefaultELUAlpha is used.
+	Alpha float64
+}
+
+func DeserializeELU(d []byte) (*ELU, error) {
+	var res ELU
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (e *ELU) Apply(r autofunc.Result) autofunc.Result {
+	alpha := e.alpha()
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		if x >= 0 {
+			return x, 1
+		}
+		y := alpha * (math.Exp(x) - 1)
+		return y, y + alpha
+	})
+}
+
+func (e *ELU) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	alpha := e.alpha()
+	return applyElementwiseR(r, func(x float64) (float64, float64, float64) {
+		if x >= 0 {
+			return x, 1, 0
+		}
+		y := alpha * (math.Exp(x) - 1)
+		return y, y + alpha, y + alpha
+	})
+}
+
+func (e *ELU) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return e.Apply(inputs)
+}
+
+func (e *ELU) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return e.ApplyR(v, inputs)
+}
+
+func (e *ELU) Serialize() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (e *ELU) SerializerType() string {
+	return serializerTypeELU
+}
+
+func (e *ELU) alpha() float64 {
+	if e.Alpha == 0 {
+		return DefaultELUAlpha
+	}
+	return e.Alpha
+}
+
+// Swish is a Layer which applies the function
+// x*sigmoid(beta*x).
+// With beta = 1, this is also known as SiLU.
+type Swish struct {
+	// Beta scales the input to the sigmoid.
+	// If it is 0, DefaultSwishBeta is used.
+	Beta float64
+}
+
+func DeserializeSwish(d []byte) (*Swish, error) {
+	var res Swish
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *Swish) Apply(r autofunc.Result) autofunc.Result {
+	beta := s.beta()
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		sig := logistic(beta * x)
+		return x * sig, sig * (1 + beta*x*(1-sig))
+	})
+}
+
+func (s *Swish) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	beta := s.beta()
+	return applyElementwiseR(r, func(x float64) (float64, float64, float64) {
+		sig := logistic(beta * x)
+		sigDeriv := sig * (1 - sig)
+		return x * sig, sig + beta*x*sigDeriv,
+			beta * sigDeriv * (2 + beta*x*(1-2*sig))
+	})
+}
+
+func (s *Swish) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return s.Apply(inputs)
+}
+
+func (s *Swish) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return s.ApplyR(v, inputs)
+}
+
+func (s *Swish) Serialize() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+func (s *Swish) SerializerType() string {
+	return serializerTypeSwish
+}
+
+func (s *Swish) beta() float64 {
+	if s.Beta == 0 {
+		return DefaultSwishBeta
+	}
+	return s.Beta
+}
+
+// HyperbolicTangent applies math.Tanh to each input.
+// Its derivatives are computed from its outputs, using
+// the identity tanh'(x) = 1 - tanh(x)^2.
 type HyperbolicTangent struct{}
 
 func (_ HyperbolicTangent) Apply(r autofunc.Result) autofunc.Result {
-	stretched := autofunc.Scale(autofunc.Sigmoid{}.Apply(autofunc.Scale(r, 2)), 2)
-	return autofunc.AddScaler(stretched, -1)
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		y := math.Tanh(x)
+		return y, 1 - y*y
+	})
 }
 
 func (_ HyperbolicTangent) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
-	stretched := autofunc.ScaleR(autofunc.Sigmoid{}.ApplyR(v, autofunc.ScaleR(r, 2)), 2)
-	return autofunc.AddScalerR(stretched, -1)
+	return applyElementwiseR(r, func(x float64) (float64, float64, float64) {
+		y := math.Tanh(x)
+		dy := 1 - y*y
+		return y, dy, -2 * y * dy
+	})
 }
 
 func (_ HyperbolicTangent) Batch(inputs autofunc.Result, n int) autofunc.Result {
@@ -169,6 +481,289 @@ func (_ HyperbolicTangent) SerializerType() string {
 	return serializerTypeHyperbolicTangent
 }
 
+// GELU is a Layer which applies the Gaussian error
+// linear unit, using the tanh approximation
+// 0.5*x*(1+tanh(sqrt(2/pi)*(x+0.044715*x^3))).
+type GELU struct{}
+
+func (_ GELU) Apply(r autofunc.Result) autofunc.Result {
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		y, dy, _ := gelu(x)
+		return y, dy
+	})
+}
+
+func (_ GELU) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	return applyElementwiseR(r, gelu)
+}
+
+func (_ GELU) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return GELU{}.Apply(inputs)
+}
+
+func (_ GELU) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return GELU{}.ApplyR(v, inputs)
+}
+
+func (_ GELU) Serialize() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (_ GELU) SerializerType() string {
+	return serializerTypeGELU
+}
+
+// gelu evaluates the tanh approximation of GELU and its
+// first two derivatives.
+func gelu(x float64) (y, dy, ddy float64) {
+	const a = 0.044715
+	c := math.Sqrt(2 / math.Pi)
+	u := c * (x + a*x*x*x)
+	du := c * (1 + 3*a*x*x)
+	ddu := 6 * a * c * x
+	t := math.Tanh(u)
+	sech2 := 1 - t*t
+	y = 0.5 * x * (1 + t)
+	dy = 0.5*(1+t) + 0.5*x*sech2*du
+	ddy = sech2*du + 0.5*x*sech2*(ddu-2*t*du*du)
+	return
+}
+
+// Softplus is a Layer which applies the smooth,
+// always-positive function log(1+exp(x)).
+type Softplus struct{}
+
+func (_ Softplus) Apply(r autofunc.Result) autofunc.Result {
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		return softplus(x), logistic(x)
+	})
+}
+
+func (_ Softplus) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	return applyElementwiseR(r, func(x float64) (float64, float64, float64) {
+		sig := logistic(x)
+		return softplus(x), sig, sig * (1 - sig)
+	})
+}
+
+func (_ Softplus) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return Softplus{}.Apply(inputs)
+}
+
+func (_ Softplus) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return Softplus{}.ApplyR(v, inputs)
+}
+
+func (_ Softplus) Serialize() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (_ Softplus) SerializerType() string {
+	return serializerTypeSoftplus
+}
+
+// softplus computes log(1+exp(x)) in a way that does
+// not overflow for large x.
+func softplus(x float64) float64 {
+	return math.Max(x, 0) + math.Log1p(math.Exp(-math.Abs(x)))
+}
+
+// Mish is a Layer which applies the function
+// x*tanh(softplus(x)).
+type Mish struct{}
+
+func (_ Mish) Apply(r autofunc.Result) autofunc.Result {
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		y, dy, _ := mish(x)
+		return y, dy
+	})
+}
+
+func (_ Mish) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	return applyElementwiseR(r, mish)
+}
+
+func (_ Mish) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return Mish{}.Apply(inputs)
+}
+
+func (_ Mish) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return Mish{}.ApplyR(v, inputs)
+}
+
+func (_ Mish) Serialize() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (_ Mish) SerializerType() string {
+	return serializerTypeMish
+}
+
+// mish evaluates Mish and its first two derivatives.
+func mish(x float64) (y, dy, ddy float64) {
+	t := math.Tanh(softplus(x))
+	sig := logistic(x)
+	sech2 := 1 - t*t
+	y = x * t
+	dy = t + x*sech2*sig
+	ddy = 2*sech2*sig + x*sech2*sig*(1-sig-2*t*sig)
+	return
+}
+
+// Softsign is a Layer which applies the bounded
+// function x/(1+|x|).
+// It is shaped like tanh, but it is cheaper to compute
+// and it approaches its asymptotes polynomially rather
+// than exponentially.
+type Softsign struct{}
+
+func (_ Softsign) Apply(r autofunc.Result) autofunc.Result {
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		y, dy, _ := softsign(x)
+		return y, dy
+	})
+}
+
+func (_ Softsign) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	return applyElementwiseR(r, softsign)
+}
+
+func (_ Softsign) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return Softsign{}.Apply(inputs)
+}
+
+func (_ Softsign) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return Softsign{}.ApplyR(v, inputs)
+}
+
+func (_ Softsign) Serialize() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (_ Softsign) SerializerType() string {
+	return serializerTypeSoftsign
+}
+
+// softsign evaluates Softsign and its first two
+// derivatives.
+func softsign(x float64) (y, dy, ddy float64) {
+	denom := 1 + math.Abs(x)
+	y = x / denom
+	dy = 1 / (denom * denom)
+	ddy = -2 * math.Copysign(dy, x) / denom
+	return
+}
+
+// HardSigmoid is a Layer which applies the
+// piecewise-linear approximation of the logistic
+// sigmoid clamp(0.2*x+0.5, 0, 1).
+// It never calls math.Exp, making it much cheaper
+// than Sigmoid.
+// Its gradient is 0 where it saturates.
+type HardSigmoid struct{}
+
+func (_ HardSigmoid) Apply(r autofunc.Result) autofunc.Result {
+	f := hardClamp(0.2, 0.5, 0, 1)
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		y, dy, _ := f(x)
+		return y, dy
+	})
+}
+
+func (_ HardSigmoid) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	return applyElementwiseR(r, hardClamp(0.2, 0.5, 0, 1))
+}
+
+func (_ HardSigmoid) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return HardSigmoid{}.Apply(inputs)
+}
+
+func (_ HardSigmoid) BatchR(v autofunc.RVector, inputs autofunc.RResult,
+	n int) autofunc.RResult {
+	return HardSigmoid{}.ApplyR(v, inputs)
+}
+
+func (_ HardSigmoid) Serialize() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (_ HardSigmoid) SerializerType() string {
+	return serializerTypeHardSigmoid
+}
+
+// HardTanh is a Layer which clamps its inputs to the
+// range [Min, Max].
+// It is a cheap, piecewise-linear alternative to
+// HyperbolicTangent, and its gradient is 0 where it
+// saturates.
+type HardTanh struct {
+	// Min and Max are the clamp bounds.
+	// If both are 0, DefaultHardTanhMin and
+	// DefaultHardTanhMax are used.
+	Min float64
+	Max float64
+}
+
+func DeserializeHardTanh(d []byte) (*HardTanh, error) {
+	var res HardTanh
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (h *HardTanh) Apply(r autofunc.Result) autofunc.Result {
+	min, max := h.bounds()
+	f := hardClamp(1, 0, min, max)
+	return applyElementwise(r, func(x float64) (float64, float64) {
+		y, dy, _ := f(x)
+		return y, dy
+	})
+}
+
+func (h *HardTanh) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	min, max := h.bounds()
+	return applyElementwiseR(r, hardClamp(1, 0, min, max))
+}
+
+func (h *HardTanh) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return h.Apply(inputs)
+}
+
+func (h *HardTanh) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return h.ApplyR(v, inputs)
+}
+
+func (h *HardTanh) Serialize() ([]byte, error) {
+	return json.Marshal(h)
+}
+
+func (h *HardTanh) SerializerType() string {
+	return serializerTypeHardTanh
+}
+
+func (h *HardTanh) bounds() (min, max float64) {
+	if h.Min == 0 && h.Max == 0 {
+		return DefaultHardTanhMin, DefaultHardTanhMax
+	}
+	return h.Min, h.Max
+}
+
+// hardClamp creates a function which evaluates
+// clamp(slope*x+offset, min, max) and its first two
+// derivatives.
+func hardClamp(slope, offset, min, max float64) elementwiseFuncR {
+	return func(x float64) (y, dy, ddy float64) {
+		y = slope*x + offset
+		if y <= min {
+			return min, 0, 0
+		} else if y >= max {
+			return max, 0, 0
+		}
+		return y, slope, 0
+	}
+}
+
 type Sin struct {
 	autofunc.Sin
 }
@@ -0,0 +1,299 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func TestSigmoidOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-1000, -2, 0, 0.5, 1000}}
+	expected := []float64{0, 1 / (1 + math.Exp(2)), 0.5, 1 / (1 + math.Exp(-0.5)), 1}
+	output := Sigmoid{}.Apply(input)
+	actual := output.Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+
+	grad := autofunc.NewGradient([]*autofunc.Variable{input})
+	output.PropagateGradient([]float64{1, 1, 1, 1, 1}, grad)
+	for i, x := range grad[input] {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			t.Errorf("derivative %d is not finite: %f", i, x)
+		}
+	}
+}
+
+func TestSigmoidGradients(t *testing.T) {
+	testActivationGradients(t, &Sigmoid{})
+}
+
+func TestLeakyReLUOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-2, -0.5, 0, 0.5, 3}}
+	for _, layer := range []*LeakyReLU{{}, {Leak: 0.2}} {
+		leak := layer.Leak
+		if leak == 0 {
+			leak = DefaultLeakyReLULeak
+		}
+		expected := []float64{-2 * leak, -0.5 * leak, 0, 0.5, 3}
+		actual := layer.Apply(input).Output()
+		for i, x := range expected {
+			if math.Abs(actual[i]-x) > 1e-8 {
+				t.Errorf("leak %f: output %d should be %f but got %f", leak, i,
+					x, actual[i])
+			}
+		}
+	}
+}
+
+func TestLeakyReLUGradients(t *testing.T) {
+	testActivationGradients(t, &LeakyReLU{Leak: 0.1})
+}
+
+func TestLeakyReLUSerialize(t *testing.T) {
+	testActivationSerialize(t, &LeakyReLU{Leak: 0.3})
+}
+
+func TestELUOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-2, -0.5, 0, 0.5, 3}}
+	layer := &ELU{Alpha: 1.5}
+	expected := []float64{1.5 * (math.Exp(-2) - 1), 1.5 * (math.Exp(-0.5) - 1), 0, 0.5, 3}
+	actual := layer.Apply(input).Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestELUGradients(t *testing.T) {
+	testActivationGradients(t, &ELU{Alpha: 0.7})
+}
+
+func TestELUSerialize(t *testing.T) {
+	testActivationSerialize(t, &ELU{Alpha: 0.7})
+}
+
+// testActivationGradients checks the gradients and
+// r-gradients of an element-wise activation Layer.
+func testActivationGradients(t *testing.T, layer Layer) {
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 10)}
+	rVec := autofunc.RVector{input: make(linalg.Vector, len(input.Vector))}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64() * 2
+		rVec[input][i] = rand.NormFloat64()
+	}
+	vars := []*autofunc.Variable{input}
+	t.Run("Gradient", func(t *testing.T) {
+		checker := &functest.FuncChecker{F: layer, Vars: vars, Input: input}
+		checker.FullCheck(t)
+	})
+	t.Run("RGradient", func(t *testing.T) {
+		checker := &functest.RFuncChecker{F: layer, Vars: vars, Input: input, RV: rVec}
+		checker.FullCheck(t)
+	})
+}
+
+// testActivationSerialize checks that an activation
+// Layer survives a serialization round-trip.
+func testActivationSerialize(t *testing.T, layer Layer) {
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, layer) {
+		t.Errorf("expected %#v but got %#v", layer, decoded)
+	}
+}
+
+func TestSwishOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-2, -0.5, 0, 0.5, 3}}
+	for _, beta := range []float64{0, 1.7} {
+		layer := &Swish{Beta: beta}
+		if beta == 0 {
+			beta = 1
+		}
+		actual := layer.Apply(input).Output()
+		for i, x := range input.Vector {
+			expected := x / (1 + math.Exp(-beta*x))
+			if math.Abs(actual[i]-expected) > 1e-8 {
+				t.Errorf("beta %f: output %d should be %f but got %f", beta, i,
+					expected, actual[i])
+			}
+		}
+	}
+}
+
+func TestSwishGradients(t *testing.T) {
+	testActivationGradients(t, &Swish{})
+	testActivationGradients(t, &Swish{Beta: 1.7})
+}
+
+func TestSwishSerialize(t *testing.T) {
+	testActivationSerialize(t, &Swish{Beta: 1.7})
+	testActivationSerialize(t, Network{NewDenseLayer(3, 2), &Swish{Beta: 0.5}})
+}
+
+func TestHyperbolicTangentOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-30, -0.5, 0, 0.5, 3}}
+	actual := HyperbolicTangent{}.Apply(input).Output()
+	for i, x := range input.Vector {
+		if expected := math.Tanh(x); math.Abs(actual[i]-expected) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, expected, actual[i])
+		}
+	}
+}
+
+func TestHyperbolicTangentGradients(t *testing.T) {
+	testActivationGradients(t, &HyperbolicTangent{})
+}
+
+func TestHyperbolicTangentSerialize(t *testing.T) {
+	testActivationSerialize(t, &HyperbolicTangent{})
+}
+
+func TestGELUOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-3, -0.5, 0, 0.5, 3}}
+	expected := []float64{-0.00363739, -0.15428599, 0, 0.34571401, 2.99636261}
+	actual := GELU{}.Apply(input).Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-6 {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestGELUGradients(t *testing.T) {
+	testActivationGradients(t, &GELU{})
+}
+
+func TestGELUSerialize(t *testing.T) {
+	testActivationSerialize(t, &GELU{})
+}
+
+func TestSoftplusOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-800, -2, 0, 0.5, 800}}
+	expected := []float64{0, math.Log(1 + math.Exp(-2)), math.Log(2),
+		math.Log(1 + math.Exp(0.5)), 800}
+	actual := Softplus{}.Apply(input).Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestSoftplusGradients(t *testing.T) {
+	testActivationGradients(t, &Softplus{})
+}
+
+func TestSoftplusSerialize(t *testing.T) {
+	testActivationSerialize(t, &Softplus{})
+}
+
+func TestMishOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-800, -2, 0, 0.5, 800}}
+	actual := Mish{}.Apply(input).Output()
+	for i, x := range input.Vector {
+		expected := x * math.Tanh(math.Log1p(math.Exp(x)))
+		if math.IsInf(math.Exp(x), 1) {
+			expected = x
+		}
+		if math.Abs(actual[i]-expected) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, expected, actual[i])
+		}
+	}
+}
+
+func TestMishGradients(t *testing.T) {
+	testActivationGradients(t, &Mish{})
+}
+
+func TestMishSerialize(t *testing.T) {
+	testActivationSerialize(t, &Mish{})
+}
+
+func TestSoftsignOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-3, -0.5, 0, 1, 1e300}}
+	expected := []float64{-0.75, -1.0 / 3, 0, 0.5, 1}
+	actual := Softsign{}.Apply(input).Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestSoftsignGradients(t *testing.T) {
+	testActivationGradients(t, &Softsign{})
+}
+
+func TestSoftsignSerialize(t *testing.T) {
+	testActivationSerialize(t, &Softsign{})
+}
+
+func TestHardSigmoidOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-5, -2, 0, 1, 2.5, 4}}
+	expected := []float64{0, 0.1, 0.5, 0.7, 1, 1}
+	testHardOutput(t, &HardSigmoid{}, input, expected, []float64{0, 0.2, 0.2, 0.2, 0, 0})
+}
+
+func TestHardSigmoidGradients(t *testing.T) {
+	testActivationGradients(t, &HardSigmoid{})
+}
+
+func TestHardSigmoidSerialize(t *testing.T) {
+	testActivationSerialize(t, &HardSigmoid{})
+}
+
+func TestHardTanhOutput(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{-2, -0.5, 0, 0.5, 3}}
+	testHardOutput(t, &HardTanh{}, input, []float64{-1, -0.5, 0, 0.5, 1},
+		[]float64{0, 1, 1, 1, 0})
+	testHardOutput(t, &HardTanh{Min: 0, Max: 2}, input, []float64{0, 0, 0, 0.5, 2},
+		[]float64{0, 0, 0, 1, 0})
+}
+
+func TestHardTanhGradients(t *testing.T) {
+	testActivationGradients(t, &HardTanh{})
+	testActivationGradients(t, &HardTanh{Min: -0.5, Max: 2})
+}
+
+func TestHardTanhSerialize(t *testing.T) {
+	testActivationSerialize(t, &HardTanh{Min: -0.5, Max: 2})
+}
+
+// testHardOutput checks the outputs and the input
+// gradient of a piecewise-linear activation Layer.
+func testHardOutput(t *testing.T, layer Layer, input *autofunc.Variable,
+	expected, expectedGrad []float64) {
+	output := layer.Apply(input)
+	for i, x := range expected {
+		if math.Abs(output.Output()[i]-x) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, x, output.Output()[i])
+		}
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{input})
+	upstream := make(linalg.Vector, len(expected))
+	for i := range upstream {
+		upstream[i] = 1
+	}
+	output.PropagateGradient(upstream, grad)
+	for i, x := range expectedGrad {
+		if math.Abs(grad[input][i]-x) > 1e-8 {
+			t.Errorf("derivative %d should be %f but got %f", i, x, grad[input][i])
+		}
+	}
+}
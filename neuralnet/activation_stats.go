@@ -0,0 +1,81 @@
+package neuralnet
+
+import "github.com/unixpickle/num-analysis/linalg"
+
+// ActivationStats tracks how often each unit of a layer
+// is inactive (i.e. outputs a value <= 0), which can be
+// used to find "dead" ReLU units that never activate.
+//
+// Statistics accumulate across calls to Observe until
+// Reset is called.
+// For example, to check the units of a DenseLayer which
+// is followed by a ReLU:
+//
+//	stats := neuralnet.NewActivationStats(dense.OutputCount)
+//	for _, in := range inputs {
+//		stats.Observe(dense.Apply(&autofunc.Variable{Vector: in}).Output())
+//	}
+//	dead := stats.DeadUnits()
+type ActivationStats struct {
+	// Inactive counts the inactive outputs of each unit.
+	Inactive []int
+
+	// Samples is the number of samples observed.
+	Samples int
+}
+
+// NewActivationStats creates an ActivationStats for a
+// layer with the given number of units.
+func NewActivationStats(units int) *ActivationStats {
+	return &ActivationStats{Inactive: make([]int, units)}
+}
+
+// Observe records the outputs of the layer for one or
+// more samples.
+// The length of outputs must be a multiple of the number
+// of units.
+func (a *ActivationStats) Observe(outputs linalg.Vector) {
+	if len(a.Inactive) == 0 || len(outputs)%len(a.Inactive) != 0 {
+		panic("invalid output size")
+	}
+	for i, x := range outputs {
+		if x <= 0 {
+			a.Inactive[i%len(a.Inactive)]++
+		}
+	}
+	a.Samples += len(outputs) / len(a.Inactive)
+}
+
+// InactiveFractions returns the fraction of samples for
+// which each unit was inactive.
+func (a *ActivationStats) InactiveFractions() []float64 {
+	res := make([]float64, len(a.Inactive))
+	for i, count := range a.Inactive {
+		res[i] = safeRatio(count, a.Samples)
+	}
+	return res
+}
+
+// DeadUnits returns the indices of the units which were
+// inactive for every observed sample.
+// It returns nil if no samples have been observed.
+func (a *ActivationStats) DeadUnits() []int {
+	if a.Samples == 0 {
+		return nil
+	}
+	var res []int
+	for i, count := range a.Inactive {
+		if count == a.Samples {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// Reset clears the accumulated statistics.
+func (a *ActivationStats) Reset() {
+	for i := range a.Inactive {
+		a.Inactive[i] = 0
+	}
+	a.Samples = 0
+}
@@ -0,0 +1,43 @@
+package neuralnet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestActivationStats(t *testing.T) {
+	stats := NewActivationStats(3)
+	stats.Observe(linalg.Vector{1, 0, -2})
+	stats.Observe(linalg.Vector{-1, -3, 0.5, 2, 0, -1})
+	if stats.Samples != 3 {
+		t.Errorf("expected 3 samples but got %d", stats.Samples)
+	}
+	expected := []float64{1.0 / 3, 1, 2.0 / 3}
+	if actual := stats.InactiveFractions(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected fractions %v but got %v", expected, actual)
+	}
+	if dead := stats.DeadUnits(); !reflect.DeepEqual(dead, []int{1}) {
+		t.Errorf("expected dead units [1] but got %v", dead)
+	}
+
+	stats.Reset()
+	if stats.Samples != 0 || stats.DeadUnits() != nil {
+		t.Error("statistics were not reset")
+	}
+}
+
+func TestActivationStatsDenseLayer(t *testing.T) {
+	layer := NewDenseLayer(2, 2)
+	copy(layer.Weights.Data.Vector, []float64{1, 0, 0, 0})
+	copy(layer.Biases.Var.Vector, []float64{0, -1})
+	stats := NewActivationStats(layer.OutputCount)
+	for _, in := range []linalg.Vector{{1, 2}, {-1, 3}, {2, -5}} {
+		stats.Observe(layer.Apply(&autofunc.Variable{Vector: in}).Output())
+	}
+	if dead := stats.DeadUnits(); !reflect.DeepEqual(dead, []int{1}) {
+		t.Errorf("expected dead units [1] but got %v", dead)
+	}
+}
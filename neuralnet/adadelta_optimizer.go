@@ -0,0 +1,129 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+const (
+	defaultAdadeltaDecayRate = 0.95
+	defaultAdadeltaDamping   = 1e-6
+)
+
+// AdadeltaOptimizer implements the Adadelta update
+// rule, which scales each gradient component by the
+// ratio between the RMS of recent updates and the RMS
+// of recent gradients for that component.
+// Since the units of the updates come from the updates
+// themselves, there is no global learning rate to tune.
+//
+// The transformed gradients are the full updates, so
+// they should be applied with a step size of 1.
+//
+// The rolling averages are stored per parameter of a
+// Learner, so that they can be serialized to resume
+// training.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type AdadeltaOptimizer struct {
+	Gradienter sgd.Gradienter `json:"-"`
+
+	// Learner determines the order of the parameters
+	// in the rolling averages.
+	// It should not change once training starts.
+	Learner sgd.Learner `json:"-"`
+
+	// DecayRate is used for both rolling averages.
+	// If it is 0, a default is used.
+	DecayRate float64
+
+	// Damping is added to both rolling averages before
+	// their square roots are taken.
+	// It also determines the size of the first updates.
+	// If it is 0, a default is used.
+	Damping float64
+
+	// SquaredGradients is the rolling average of the
+	// squares of the gradient entries.
+	// It is set automatically after the first batch.
+	SquaredGradients []linalg.Vector
+
+	// SquaredUpdates is the rolling average of the
+	// squares of the update entries.
+	// It is set automatically after the first batch.
+	SquaredUpdates []linalg.Vector
+
+	params optimizerParams
+}
+
+// DeserializeAdadeltaOptimizer deserializes an
+// AdadeltaOptimizer.
+// The Gradienter and Learner must be set before it can
+// be used again.
+func DeserializeAdadeltaOptimizer(d []byte) (*AdadeltaOptimizer, error) {
+	var res AdadeltaOptimizer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (a *AdadeltaOptimizer) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return a.Transform(a.Gradienter.Gradient(s))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (a *AdadeltaOptimizer) WrappedGradienter() sgd.Gradienter {
+	return a.Gradienter
+}
+
+func (a *AdadeltaOptimizer) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	a.params.update(a.Learner, 0, &a.SquaredGradients, &a.SquaredUpdates)
+
+	decay := a.decayRate()
+	damping := a.damping()
+	for i, vec := range learnerGradient(a.Learner, grad) {
+		gradVec := a.SquaredGradients[i]
+		updateVec := a.SquaredUpdates[i]
+		for j, x := range vec {
+			gradVec[j] = decay*gradVec[j] + (1-decay)*x*x
+			update := x * math.Sqrt(updateVec[j]+damping) / math.Sqrt(gradVec[j]+damping)
+			updateVec[j] = decay*updateVec[j] + (1-decay)*update*update
+			vec[j] = update
+		}
+	}
+
+	return grad
+}
+
+// Serialize serializes the optimizer's hyper-parameters
+// and rolling averages.
+func (a *AdadeltaOptimizer) Serialize() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+// SerializerType returns the unique ID used to serialize
+// an AdadeltaOptimizer with the serializer package.
+func (a *AdadeltaOptimizer) SerializerType() string {
+	return serializerTypeAdadeltaOptimizer
+}
+
+func (a *AdadeltaOptimizer) decayRate() float64 {
+	if a.DecayRate == 0 {
+		return defaultAdadeltaDecayRate
+	}
+	return a.DecayRate
+}
+
+func (a *AdadeltaOptimizer) damping() float64 {
+	if a.Damping == 0 {
+		return defaultAdadeltaDamping
+	}
+	return a.Damping
+}
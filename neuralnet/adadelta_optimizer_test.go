@@ -0,0 +1,83 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+// adadeltaReference is a direct implementation of
+// Algorithm 1 from Zeiler's Adadelta paper.
+type adadeltaReference struct {
+	Rho     float64
+	Epsilon float64
+
+	accumGrad   map[*autofunc.Variable][]float64
+	accumUpdate map[*autofunc.Variable][]float64
+}
+
+func (a *adadeltaReference) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	if a.accumGrad == nil {
+		a.accumGrad = map[*autofunc.Variable][]float64{}
+		a.accumUpdate = map[*autofunc.Variable][]float64{}
+	}
+	for variable, vec := range grad {
+		if a.accumGrad[variable] == nil {
+			a.accumGrad[variable] = make([]float64, len(vec))
+			a.accumUpdate[variable] = make([]float64, len(vec))
+		}
+		eg, ex := a.accumGrad[variable], a.accumUpdate[variable]
+		for i, g := range vec {
+			eg[i] = a.Rho*eg[i] + (1-a.Rho)*g*g
+			rmsX := math.Sqrt(ex[i] + a.Epsilon)
+			rmsG := math.Sqrt(eg[i] + a.Epsilon)
+			dx := rmsX / rmsG * g
+			ex[i] = a.Rho*ex[i] + (1-a.Rho)*dx*dx
+			vec[i] = dx
+		}
+	}
+	return grad
+}
+
+func TestAdadeltaOptimizerTransform(t *testing.T) {
+	net := optimizerTestNetwork()
+	actual := &AdadeltaOptimizer{Learner: net, DecayRate: 0.9, Damping: 1e-4}
+	expected := &adadeltaReference{Rho: 0.9, Epsilon: 1e-4}
+	testOptimizerTransform(t, net, actual, expected)
+}
+
+func TestAdadeltaOptimizerTraining(t *testing.T) {
+	samples := sgd.SliceSampleSet{
+		VectorSample{Input: []float64{1, 0}, Output: []float64{1}},
+		VectorSample{Input: []float64{0, 1}, Output: []float64{-1}},
+	}
+	net := Network{NewDenseLayer(2, 1)}
+	net.RandomizeWithRand(rand.New(rand.NewSource(1337)))
+	opt := &AdadeltaOptimizer{
+		Gradienter: &BatchRGradienter{
+			Learner:  net.BatchLearner(),
+			CostFunc: MeanSquaredCost{},
+		},
+		Learner: net,
+		Damping: 1e-4,
+	}
+	start := TotalCost(MeanSquaredCost{}, net, samples)
+	sgd.SGD(opt, samples, 1, 500, 2)
+	if end := TotalCost(MeanSquaredCost{}, net, samples); end >= start/10 {
+		t.Errorf("cost only went from %f to %f", start, end)
+	}
+}
+
+func TestAdadeltaOptimizerResume(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &AdadeltaOptimizer{Learner: net, DecayRate: 0.8}
+	testOptimizerResume(t, net, opt, func(s serializer.Serializer) sgd.Transformer {
+		restored := s.(*AdadeltaOptimizer)
+		restored.Learner = net
+		return restored
+	})
+}
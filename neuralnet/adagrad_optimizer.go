@@ -0,0 +1,109 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+const defaultAdagradDamping = 1e-8
+
+// AdagradOptimizer divides each gradient component by
+// the square root of the sum of the squares of every
+// value that component has had, giving each parameter
+// its own, decreasing, learning rate.
+// This works well for sparse features, such as the
+// embeddings of rare tokens.
+//
+// It is like sgd.AdaGrad, except that its accumulated
+// squares are stored per parameter of a Learner, so
+// that they can be serialized to resume training.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type AdagradOptimizer struct {
+	Gradienter sgd.Gradienter `json:"-"`
+
+	// Learner determines the order of the parameters
+	// in the accumulator.
+	// It should not change once training starts.
+	Learner sgd.Learner `json:"-"`
+
+	// InitialAccumulator is the value with which every
+	// entry of the accumulator starts.
+	// A positive value keeps the first steps from being
+	// too large.
+	InitialAccumulator float64
+
+	// Damping is added to the accumulator before its
+	// square root is taken, preventing divisions by zero.
+	// If it is 0, a default is used.
+	Damping float64
+
+	// Accumulator is the current sum of the squares of
+	// the gradient entries (plus InitialAccumulator) for
+	// each of the Learner's parameters.
+	// It is set automatically after the first batch.
+	Accumulator []linalg.Vector
+
+	params optimizerParams
+}
+
+// DeserializeAdagradOptimizer deserializes an
+// AdagradOptimizer.
+// The Gradienter and Learner must be set before it can
+// be used again.
+func DeserializeAdagradOptimizer(d []byte) (*AdagradOptimizer, error) {
+	var res AdagradOptimizer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (a *AdagradOptimizer) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return a.Transform(a.Gradienter.Gradient(s))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (a *AdagradOptimizer) WrappedGradienter() sgd.Gradienter {
+	return a.Gradienter
+}
+
+func (a *AdagradOptimizer) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	a.params.update(a.Learner, a.InitialAccumulator, &a.Accumulator)
+
+	damping := a.damping()
+	for i, vec := range learnerGradient(a.Learner, grad) {
+		accVec := a.Accumulator[i]
+		for j, x := range vec {
+			accVec[j] += x * x
+			vec[j] = x / math.Sqrt(accVec[j]+damping)
+		}
+	}
+
+	return grad
+}
+
+// Serialize serializes the optimizer's hyper-parameters
+// and accumulator.
+func (a *AdagradOptimizer) Serialize() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+// SerializerType returns the unique ID used to serialize
+// an AdagradOptimizer with the serializer package.
+func (a *AdagradOptimizer) SerializerType() string {
+	return serializerTypeAdagradOptimizer
+}
+
+func (a *AdagradOptimizer) damping() float64 {
+	if a.Damping == 0 {
+		return defaultAdagradDamping
+	}
+	return a.Damping
+}
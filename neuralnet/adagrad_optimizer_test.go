@@ -0,0 +1,42 @@
+package neuralnet
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+func TestAdagradOptimizerTransform(t *testing.T) {
+	net := optimizerTestNetwork()
+	actual := &AdagradOptimizer{Learner: net, Damping: 1e-300}
+	expected := &sgd.AdaGrad{}
+	testOptimizerTransform(t, net, actual, expected)
+}
+
+func TestAdagradOptimizerInitialAccumulator(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &AdagradOptimizer{Learner: net, InitialAccumulator: 3, Damping: 1e-300}
+	grad := randomOptimizerGradient(net)
+	original := grad.Copy()
+	opt.Transform(grad)
+	for variable, vec := range original {
+		for i, x := range vec {
+			expected := x / math.Sqrt(3+x*x)
+			if math.Abs(grad[variable][i]-expected) > 1e-8 {
+				t.Fatalf("expected %f but got %f", expected, grad[variable][i])
+			}
+		}
+	}
+}
+
+func TestAdagradOptimizerResume(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &AdagradOptimizer{Learner: net, InitialAccumulator: 0.1}
+	testOptimizerResume(t, net, opt, func(s serializer.Serializer) sgd.Transformer {
+		restored := s.(*AdagradOptimizer)
+		restored.Learner = net
+		return restored
+	})
+}
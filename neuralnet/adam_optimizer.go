@@ -0,0 +1,133 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+const (
+	defaultAdamDecayRate1 = 0.9
+	defaultAdamDecayRate2 = 0.999
+	defaultAdamDamping    = 1e-8
+)
+
+// AdamOptimizer implements the adaptive moments SGD
+// technique described in https://arxiv.org/pdf/1412.6980.pdf.
+//
+// It is like sgd.Adam, except that its moment estimates
+// are stored per parameter of a Learner (e.g. a Network),
+// so that they can be serialized to resume training.
+// The learning rate is the step size passed to sgd.SGD.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type AdamOptimizer struct {
+	Gradienter sgd.Gradienter `json:"-"`
+
+	// Learner determines the order of the parameters
+	// in the moment estimates.
+	// It should not change once training starts.
+	Learner sgd.Learner `json:"-"`
+
+	// These are decay rates for the first and second
+	// moments of the gradient.
+	// If these are 0, defaults as suggested in the
+	// original Adam paper are used.
+	DecayRate1, DecayRate2 float64
+
+	// Damping is used to prevent divisions by zero.
+	// This should be very small.
+	// If it is 0, a default is used.
+	Damping float64
+
+	// FirstMoment and SecondMoment store the moment
+	// estimates for each of the Learner's parameters.
+	// They are set automatically after the first batch.
+	FirstMoment  []linalg.Vector
+	SecondMoment []linalg.Vector
+
+	// Iteration is the number of batches seen so far.
+	Iteration float64
+
+	params optimizerParams
+}
+
+// DeserializeAdamOptimizer deserializes an AdamOptimizer.
+// The Gradienter and Learner must be set before it can
+// be used again.
+func DeserializeAdamOptimizer(d []byte) (*AdamOptimizer, error) {
+	var res AdamOptimizer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (a *AdamOptimizer) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return a.Transform(a.Gradienter.Gradient(s))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (a *AdamOptimizer) WrappedGradienter() sgd.Gradienter {
+	return a.Gradienter
+}
+
+func (a *AdamOptimizer) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	a.params.update(a.Learner, 0, &a.FirstMoment, &a.SecondMoment)
+
+	decay1, decay2 := a.decayRate1(), a.decayRate2()
+	a.Iteration++
+	scalingFactor := math.Sqrt(1-math.Pow(decay2, a.Iteration)) /
+		(1 - math.Pow(decay1, a.Iteration))
+	damping := a.damping()
+
+	for i, vec := range learnerGradient(a.Learner, grad) {
+		firstVec := a.FirstMoment[i]
+		secondVec := a.SecondMoment[i]
+		for j, x := range vec {
+			firstVec[j] = decay1*firstVec[j] + (1-decay1)*x
+			secondVec[j] = decay2*secondVec[j] + (1-decay2)*x*x
+			vec[j] = scalingFactor * firstVec[j] / math.Sqrt(secondVec[j]+damping)
+		}
+	}
+
+	return grad
+}
+
+// Serialize serializes the optimizer's hyper-parameters
+// and moment estimates.
+func (a *AdamOptimizer) Serialize() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+// SerializerType returns the unique ID used to serialize
+// an AdamOptimizer with the serializer package.
+func (a *AdamOptimizer) SerializerType() string {
+	return serializerTypeAdamOptimizer
+}
+
+func (a *AdamOptimizer) decayRate1() float64 {
+	if a.DecayRate1 == 0 {
+		return defaultAdamDecayRate1
+	}
+	return a.DecayRate1
+}
+
+func (a *AdamOptimizer) decayRate2() float64 {
+	if a.DecayRate2 == 0 {
+		return defaultAdamDecayRate2
+	}
+	return a.DecayRate2
+}
+
+func (a *AdamOptimizer) damping() float64 {
+	if a.Damping == 0 {
+		return defaultAdamDamping
+	}
+	return a.Damping
+}
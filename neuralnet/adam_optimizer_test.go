@@ -0,0 +1,28 @@
+package neuralnet
+
+import (
+	"testing"
+
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+func TestAdamOptimizerTransform(t *testing.T) {
+	net := optimizerTestNetwork()
+	actual := &AdamOptimizer{Learner: net, DecayRate1: 0.8, Damping: 1e-3}
+	expected := &sgd.Adam{DecayRate1: 0.8, Damping: 1e-3}
+	testOptimizerTransform(t, net, actual, expected)
+}
+
+func TestAdamOptimizerResume(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &AdamOptimizer{Learner: net, DecayRate2: 0.99}
+	testOptimizerResume(t, net, opt, func(s serializer.Serializer) sgd.Transformer {
+		restored := s.(*AdamOptimizer)
+		if restored.DecayRate2 != 0.99 {
+			t.Errorf("unexpected decay rate: %f", restored.DecayRate2)
+		}
+		restored.Learner = net
+		return restored
+	})
+}
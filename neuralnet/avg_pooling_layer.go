@@ -0,0 +1,274 @@
+package neuralnet
+
+import (
+	"encoding/json"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/tensor"
+)
+
+// An AvgPoolingLayer reduces the width and height
+// of an input tensor by returning the average value
+// of each of many small two-dimensional regions in
+// each depth layer of the input tensor.
+//
+// Its geometry is described just like that of a
+// MaxPoolingLayer.
+// Pools at the edges of the input which are cut off
+// average only the inputs they cover.
+type AvgPoolingLayer struct {
+	// XSpan indicates how many consecutive
+	// horizontal inputs correspond to a pool.
+	XSpan int
+
+	// YSpan indicates how many consecutive
+	// vertical inputs correspond to a pool.
+	YSpan int
+
+	// InputWidth indicates the width of the
+	// layer's input tensor.
+	InputWidth int
+
+	// InputHeight indicates the height of the
+	// layer's input tensor.
+	InputHeight int
+
+	// InputDepth indicates the depth of the
+	// layer's input tensor.
+	InputDepth int
+
+	// XStride is the horizontal distance between
+	// the starts of consecutive pools.
+	// If it is 0, XSpan is used.
+	XStride int
+
+	// YStride is the vertical distance between
+	// the starts of consecutive pools.
+	// If it is 0, YSpan is used.
+	YStride int
+}
+
+// DeserializeAvgPoolingLayer deserializes an AvgPoolingLayer.
+func DeserializeAvgPoolingLayer(d []byte) (*AvgPoolingLayer, error) {
+	var res AvgPoolingLayer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// OutputWidth returns the output tensor width.
+func (a *AvgPoolingLayer) OutputWidth() int {
+	return poolOutputSize(a.InputWidth, a.XSpan, a.xStride())
+}
+
+// OutputHeight returns the output tensor height.
+func (a *AvgPoolingLayer) OutputHeight() int {
+	return poolOutputSize(a.InputHeight, a.YSpan, a.yStride())
+}
+
+// Apply applies the layer to an input, which is treated
+// as a tensor.
+func (a *AvgPoolingLayer) Apply(in autofunc.Result) autofunc.Result {
+	return a.Batch(in, 1)
+}
+
+// ApplyR is like Apply, but for RResults.
+func (a *AvgPoolingLayer) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return a.BatchR(rv, in, 1)
+}
+
+// Batch applies the layer to inputs in batch.
+func (a *AvgPoolingLayer) Batch(in autofunc.Result, n int) autofunc.Result {
+	return &avgPoolingResult{
+		OutputVec: a.forward(in.Output(), n),
+		Input:     in,
+		N:         n,
+		Layer:     a,
+	}
+}
+
+// BatchR is like Batch, but for RResults.
+func (a *AvgPoolingLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	return &avgPoolingRResult{
+		OutputVec:  a.forward(in.Output(), n),
+		ROutputVec: a.forward(in.ROutput(), n),
+		Input:      in,
+		N:          n,
+		Layer:      a,
+	}
+}
+
+// InputSize returns the length of each input.
+func (a *AvgPoolingLayer) InputSize() int {
+	return a.InputWidth * a.InputHeight * a.InputDepth
+}
+
+// OutputSize returns the length of each output.
+func (a *AvgPoolingLayer) OutputSize() int {
+	return a.OutputWidth() * a.OutputHeight() * a.InputDepth
+}
+
+// Serialize serializes the layer.
+func (a *AvgPoolingLayer) Serialize() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+// SerializerType returns the unique ID used to serialize
+// this layer with the serializer package.
+func (a *AvgPoolingLayer) SerializerType() string {
+	return serializerTypeAvgPoolingLayer
+}
+
+func (a *AvgPoolingLayer) forward(inVec linalg.Vector, n int) linalg.Vector {
+	outSize := a.OutputWidth() * a.OutputHeight() * a.InputDepth
+	inSize := a.InputWidth * a.InputHeight * a.InputDepth
+	if len(inVec) != n*inSize {
+		panic("invalid input size")
+	}
+	outVec := make(linalg.Vector, n*outSize)
+	for i := 0; i < n; i++ {
+		inTensor := a.inputTensor(inVec[i*inSize : (i+1)*inSize])
+		outTensor := a.outputTensor(outVec[i*outSize : (i+1)*outSize])
+		a.forEachPool(func(x, y, minX, maxX, minY, maxY int) {
+			scaler := 1 / float64((maxX-minX+1)*(maxY-minY+1))
+			for z := 0; z < a.InputDepth; z++ {
+				var sum float64
+				for poolY := minY; poolY <= maxY; poolY++ {
+					for poolX := minX; poolX <= maxX; poolX++ {
+						sum += inTensor.Get(poolX, poolY, z)
+					}
+				}
+				outTensor.Set(x, y, z, sum*scaler)
+			}
+		})
+	}
+	return outVec
+}
+
+func (a *AvgPoolingLayer) backward(upstream linalg.Vector, n int) linalg.Vector {
+	outSize := a.OutputWidth() * a.OutputHeight() * a.InputDepth
+	inSize := a.InputWidth * a.InputHeight * a.InputDepth
+	downstream := make(linalg.Vector, n*inSize)
+	for i := 0; i < n; i++ {
+		upTensor := a.outputTensor(upstream[i*outSize : (i+1)*outSize])
+		downTensor := a.inputTensor(downstream[i*inSize : (i+1)*inSize])
+		a.forEachPool(func(x, y, minX, maxX, minY, maxY int) {
+			scaler := 1 / float64((maxX-minX+1)*(maxY-minY+1))
+			for z := 0; z < a.InputDepth; z++ {
+				val := upTensor.Get(x, y, z) * scaler
+				for poolY := minY; poolY <= maxY; poolY++ {
+					for poolX := minX; poolX <= maxX; poolX++ {
+						oldVal := downTensor.Get(poolX, poolY, z)
+						downTensor.Set(poolX, poolY, z, oldVal+val)
+					}
+				}
+			}
+		})
+	}
+	return downstream
+}
+
+// forEachPool calls f with the output coordinates and
+// the inclusive input bounds of every pool.
+func (a *AvgPoolingLayer) forEachPool(f func(x, y, minX, maxX, minY, maxY int)) {
+	outWidth, outHeight := a.OutputWidth(), a.OutputHeight()
+	for y := 0; y < outHeight; y++ {
+		minY := y * a.yStride()
+		maxY := minY + a.YSpan - 1
+		if maxY >= a.InputHeight {
+			maxY = a.InputHeight - 1
+		}
+		for x := 0; x < outWidth; x++ {
+			minX := x * a.xStride()
+			maxX := minX + a.XSpan - 1
+			if maxX >= a.InputWidth {
+				maxX = a.InputWidth - 1
+			}
+			f(x, y, minX, maxX, minY, maxY)
+		}
+	}
+}
+
+func (a *AvgPoolingLayer) xStride() int {
+	if a.XStride == 0 {
+		return a.XSpan
+	}
+	return a.XStride
+}
+
+func (a *AvgPoolingLayer) yStride() int {
+	if a.YStride == 0 {
+		return a.YSpan
+	}
+	return a.YStride
+}
+
+func (a *AvgPoolingLayer) inputTensor(inVec linalg.Vector) *tensor.Float64 {
+	return &tensor.Float64{
+		Width:  a.InputWidth,
+		Height: a.InputHeight,
+		Depth:  a.InputDepth,
+		Data:   inVec,
+	}
+}
+
+func (a *AvgPoolingLayer) outputTensor(outVec linalg.Vector) *tensor.Float64 {
+	return &tensor.Float64{
+		Width:  a.OutputWidth(),
+		Height: a.OutputHeight(),
+		Depth:  a.InputDepth,
+		Data:   outVec,
+	}
+}
+
+type avgPoolingResult struct {
+	OutputVec linalg.Vector
+	Input     autofunc.Result
+	N         int
+	Layer     *AvgPoolingLayer
+}
+
+func (a *avgPoolingResult) Output() linalg.Vector {
+	return a.OutputVec
+}
+
+func (a *avgPoolingResult) Constant(g autofunc.Gradient) bool {
+	return a.Input.Constant(g)
+}
+
+func (a *avgPoolingResult) PropagateGradient(upstream linalg.Vector, grad autofunc.Gradient) {
+	if !a.Input.Constant(grad) {
+		a.Input.PropagateGradient(a.Layer.backward(upstream, a.N), grad)
+	}
+}
+
+type avgPoolingRResult struct {
+	OutputVec  linalg.Vector
+	ROutputVec linalg.Vector
+	Input      autofunc.RResult
+	N          int
+	Layer      *AvgPoolingLayer
+}
+
+func (a *avgPoolingRResult) Output() linalg.Vector {
+	return a.OutputVec
+}
+
+func (a *avgPoolingRResult) ROutput() linalg.Vector {
+	return a.ROutputVec
+}
+
+func (a *avgPoolingRResult) Constant(rg autofunc.RGradient, g autofunc.Gradient) bool {
+	return a.Input.Constant(rg, g)
+}
+
+func (a *avgPoolingRResult) PropagateRGradient(upstream, upstreamR linalg.Vector,
+	rgrad autofunc.RGradient, grad autofunc.Gradient) {
+	if !a.Input.Constant(rgrad, grad) {
+		a.Input.PropagateRGradient(a.Layer.backward(upstream, a.N),
+			a.Layer.backward(upstreamR, a.N), rgrad, grad)
+	}
+}
@@ -0,0 +1,122 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func TestAvgPoolingForward(t *testing.T) {
+	layer := &AvgPoolingLayer{XSpan: 2, YSpan: 2, InputWidth: 3, InputHeight: 3,
+		InputDepth: 1}
+	input := &autofunc.Variable{Vector: []float64{
+		1, 2, 3,
+		4, 5, 6,
+		7, 8, 9,
+	}}
+	expected := []float64{
+		3, 4.5,
+		7.5, 9,
+	}
+	actual := layer.Apply(input).Output()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d outputs but got %d", len(expected), len(actual))
+	}
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestAvgPoolingSparseStride(t *testing.T) {
+	layer := &AvgPoolingLayer{XSpan: 2, YSpan: 1, XStride: 5, InputWidth: 10,
+		InputHeight: 1, InputDepth: 1}
+	input := &autofunc.Variable{Vector: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+	expected := []float64{1.5, 6.5}
+	actual := layer.Apply(input).Output()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d outputs but got %d", len(expected), len(actual))
+	}
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestAvgPoolingGradients(t *testing.T) {
+	layers := []*AvgPoolingLayer{
+		{XSpan: 3, YSpan: 3, InputWidth: 10, InputHeight: 11, InputDepth: 2},
+		{XSpan: 3, YSpan: 2, InputWidth: 8, InputHeight: 7, InputDepth: 2,
+			XStride: 2, YStride: 1},
+		{XSpan: 2, YSpan: 1, InputWidth: 10, InputHeight: 7, InputDepth: 2,
+			XStride: 5, YStride: 4},
+	}
+	for _, layer := range layers {
+		inputVar := &autofunc.Variable{
+			Vector: make(linalg.Vector, layer.InputWidth*layer.InputHeight*2),
+		}
+		rVector := autofunc.RVector{inputVar: make(linalg.Vector, len(inputVar.Vector))}
+		for i := range inputVar.Vector {
+			inputVar.Vector[i] = rand.Float64()*2 - 1
+			rVector[inputVar][i] = rand.Float64()*2 - 1
+		}
+		funcTest := &functest.RFuncChecker{
+			F:     layer,
+			Vars:  []*autofunc.Variable{inputVar},
+			Input: inputVar,
+			RV:    rVector,
+		}
+		funcTest.FullCheck(t)
+	}
+}
+
+func TestAvgPoolingBatch(t *testing.T) {
+	layer := &AvgPoolingLayer{
+		XSpan:       5,
+		YSpan:       4,
+		InputWidth:  17,
+		InputHeight: 19,
+		InputDepth:  3,
+	}
+
+	n := 3
+	batchInput := make(linalg.Vector, n*layer.InputWidth*layer.InputHeight*layer.InputDepth)
+	batchInputR := make(linalg.Vector, len(batchInput))
+	for i := range batchInput {
+		batchInput[i] = rand.NormFloat64()
+		batchInputR[i] = rand.NormFloat64()
+	}
+	batchRes := &autofunc.Variable{Vector: batchInput}
+	rVec := autofunc.RVector{batchRes: batchInputR}
+
+	testBatcher(t, layer, batchRes, n, []*autofunc.Variable{batchRes})
+	testRBatcher(t, rVec, layer, autofunc.NewRVariable(batchRes, rVec),
+		n, []*autofunc.Variable{batchRes})
+}
+
+func TestAvgPoolingSerialize(t *testing.T) {
+	layer := &AvgPoolingLayer{XSpan: 3, YSpan: 2, InputWidth: 10, InputHeight: 11,
+		InputDepth: 2, XStride: 1}
+	encoded, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLayer, ok := decoded.(*AvgPoolingLayer)
+	if !ok {
+		t.Fatalf("expected *AvgPoolingLayer but got %T", decoded)
+	}
+	if *newLayer != *layer {
+		t.Errorf("expected %v but got %v", layer, newLayer)
+	}
+}
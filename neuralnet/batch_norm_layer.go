@@ -0,0 +1,257 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+const (
+	defaultBatchNormEpsilon  = 1e-5
+	defaultBatchNormMomentum = 0.9
+)
+
+// BatchNormLayer implements batch normalization, where
+// each input component is normalized to have a mean of
+// 0 and a variance of 1 across a batch, and is then
+// scaled and translated by learnable parameters.
+//
+// In training mode, the layer uses the statistics of
+// each batch it is given, and it updates a running
+// average of those statistics.
+// Since the statistics of a single sample are not
+// meaningful, Batch should be used instead of Apply
+// while training.
+// In usage mode, the running statistics are used, so
+// that the layer is a fixed linear transformation.
+//
+// Unlike most Layers, a BatchNormLayer in training mode
+// modifies its running statistics each time it is
+// evaluated.
+type BatchNormLayer struct {
+	InputCount int
+
+	// Epsilon is added to variances to prevent division
+	// by zero.
+	// If it is 0, a reasonable default is used.
+	Epsilon float64
+
+	// Momentum determines how slowly the running
+	// statistics change.
+	// After each batch, the running statistics are set
+	// to Momentum*oldStats + (1-Momentum)*batchStats.
+	// If it is 0, a reasonable default is used.
+	Momentum float64
+
+	// Training is true if batch statistics should be
+	// used (and accumulated) rather than the running
+	// statistics.
+	Training bool
+
+	Scales *autofunc.Variable
+	Biases *autofunc.Variable
+
+	RunningMean     linalg.Vector
+	RunningVariance linalg.Vector
+
+	statLock sync.Mutex
+}
+
+// NewBatchNormLayer creates a BatchNormLayer with unit
+// scales, zero biases, and running statistics which
+// correspond to the identity transformation.
+func NewBatchNormLayer(inCount int) *BatchNormLayer {
+	res := &BatchNormLayer{
+		InputCount:      inCount,
+		Scales:          &autofunc.Variable{Vector: make(linalg.Vector, inCount)},
+		Biases:          &autofunc.Variable{Vector: make(linalg.Vector, inCount)},
+		RunningMean:     make(linalg.Vector, inCount),
+		RunningVariance: make(linalg.Vector, inCount),
+	}
+	for i := 0; i < inCount; i++ {
+		res.Scales.Vector[i] = 1
+		res.RunningVariance[i] = 1
+	}
+	return res
+}
+
+// DeserializeBatchNormLayer deserializes a BatchNormLayer.
+func DeserializeBatchNormLayer(d []byte) (*BatchNormLayer, error) {
+	var res BatchNormLayer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Parameters returns a slice containing the scale and
+// bias variables.
+func (b *BatchNormLayer) Parameters() []*autofunc.Variable {
+	if b.Scales == nil || b.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	return []*autofunc.Variable{b.Scales, b.Biases}
+}
+
+// SetTraining sets whether or not batch statistics
+// should be used and accumulated.
+func (b *BatchNormLayer) SetTraining(training bool) {
+	b.Training = training
+}
+
+// Apply applies the layer to a single input.
+func (b *BatchNormLayer) Apply(in autofunc.Result) autofunc.Result {
+	return b.Batch(in, 1)
+}
+
+// ApplyR is like Apply, but for RResults.
+func (b *BatchNormLayer) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return b.BatchR(rv, in, 1)
+}
+
+// Batch applies the layer to inputs in batch.
+func (b *BatchNormLayer) Batch(in autofunc.Result, n int) autofunc.Result {
+	b.checkInput(len(in.Output()), n)
+	if !b.Training {
+		shift, scale := b.runningTransform()
+		shiftVar := &autofunc.Variable{Vector: shift}
+		scaleVar := &autofunc.Variable{Vector: scale}
+		normalized := autofunc.Mul(autofunc.Add(in, autofunc.Repeat(shiftVar, n)),
+			autofunc.Repeat(scaleVar, n))
+		return autofunc.Add(autofunc.Mul(normalized, autofunc.Repeat(b.Scales, n)),
+			autofunc.Repeat(b.Biases, n))
+	}
+	averager := &autofunc.Variable{Vector: b.averager(n)}
+	return autofunc.Pool(in, func(in autofunc.Result) autofunc.Result {
+		mean := autofunc.MatMulVec(autofunc.Transpose(in, n, b.InputCount),
+			b.InputCount, n, averager)
+		centered := autofunc.Sub(in, autofunc.Repeat(mean, n))
+		return autofunc.Pool(centered, func(centered autofunc.Result) autofunc.Result {
+			variance := autofunc.MatMulVec(
+				autofunc.Transpose(autofunc.Square(centered), n, b.InputCount),
+				b.InputCount, n, averager)
+			b.updateStats(mean.Output(), variance.Output())
+			invStd := autofunc.Pow(autofunc.AddScaler(variance, b.epsilon()), -0.5)
+			normalized := autofunc.Mul(centered, autofunc.Repeat(invStd, n))
+			return autofunc.Add(autofunc.Mul(normalized, autofunc.Repeat(b.Scales, n)),
+				autofunc.Repeat(b.Biases, n))
+		})
+	})
+}
+
+// BatchR is like Batch, but for RResults.
+func (b *BatchNormLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	b.checkInput(len(in.Output()), n)
+	scales := autofunc.NewRVariable(b.Scales, rv)
+	biases := autofunc.NewRVariable(b.Biases, rv)
+	if !b.Training {
+		shift, scale := b.runningTransform()
+		shiftVar := autofunc.NewRVariable(&autofunc.Variable{Vector: shift}, rv)
+		scaleVar := autofunc.NewRVariable(&autofunc.Variable{Vector: scale}, rv)
+		normalized := autofunc.MulR(autofunc.AddR(in, autofunc.RepeatR(shiftVar, n)),
+			autofunc.RepeatR(scaleVar, n))
+		return autofunc.AddR(autofunc.MulR(normalized, autofunc.RepeatR(scales, n)),
+			autofunc.RepeatR(biases, n))
+	}
+	averager := autofunc.NewRVariable(&autofunc.Variable{Vector: b.averager(n)}, rv)
+	return autofunc.PoolR(in, func(in autofunc.RResult) autofunc.RResult {
+		mean := autofunc.MatMulVecR(autofunc.TransposeR(in, n, b.InputCount),
+			b.InputCount, n, averager)
+		centered := autofunc.SubR(in, autofunc.RepeatR(mean, n))
+		return autofunc.PoolR(centered, func(centered autofunc.RResult) autofunc.RResult {
+			variance := autofunc.MatMulVecR(
+				autofunc.TransposeR(autofunc.SquareR(centered), n, b.InputCount),
+				b.InputCount, n, averager)
+			b.updateStats(mean.Output(), variance.Output())
+			invStd := autofunc.PowR(autofunc.AddScalerR(variance, b.epsilon()), -0.5)
+			normalized := autofunc.MulR(centered, autofunc.RepeatR(invStd, n))
+			return autofunc.AddR(autofunc.MulR(normalized, autofunc.RepeatR(scales, n)),
+				autofunc.RepeatR(biases, n))
+		})
+	})
+}
+
+// InputSize returns the length of each input.
+func (b *BatchNormLayer) InputSize() int {
+	return b.InputCount
+}
+
+// OutputSize returns the length of each output.
+func (b *BatchNormLayer) OutputSize() int {
+	return b.InputCount
+}
+
+// Serialize serializes the layer.
+func (b *BatchNormLayer) Serialize() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+// SerializerType returns the unique ID used to serialize
+// this layer with the serializer package.
+func (b *BatchNormLayer) SerializerType() string {
+	return serializerTypeBatchNormLayer
+}
+
+func (b *BatchNormLayer) checkInput(inLen, n int) {
+	if b.Scales == nil || b.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	if !b.Training && (b.RunningMean == nil || b.RunningVariance == nil) {
+		panic(uninitPanicMessage)
+	}
+	if inLen != n*b.InputCount {
+		panic("invalid input size")
+	}
+}
+
+// runningTransform returns the shift and scale which
+// normalize inputs using the running statistics.
+func (b *BatchNormLayer) runningTransform() (shift, scale linalg.Vector) {
+	b.statLock.Lock()
+	defer b.statLock.Unlock()
+	shift = b.RunningMean.Copy().Scale(-1)
+	scale = make(linalg.Vector, len(b.RunningVariance))
+	for i, x := range b.RunningVariance {
+		scale[i] = 1 / math.Sqrt(x+b.epsilon())
+	}
+	return
+}
+
+func (b *BatchNormLayer) updateStats(mean, variance linalg.Vector) {
+	b.statLock.Lock()
+	defer b.statLock.Unlock()
+	if b.RunningMean == nil {
+		b.RunningMean = mean.Copy()
+		b.RunningVariance = variance.Copy()
+		return
+	}
+	momentum := b.momentum()
+	b.RunningMean.Scale(momentum).Add(mean.Copy().Scale(1 - momentum))
+	b.RunningVariance.Scale(momentum).Add(variance.Copy().Scale(1 - momentum))
+}
+
+func (b *BatchNormLayer) averager(n int) linalg.Vector {
+	res := make(linalg.Vector, n)
+	for i := range res {
+		res[i] = 1 / float64(n)
+	}
+	return res
+}
+
+func (b *BatchNormLayer) epsilon() float64 {
+	if b.Epsilon == 0 {
+		return defaultBatchNormEpsilon
+	}
+	return b.Epsilon
+}
+
+func (b *BatchNormLayer) momentum() float64 {
+	if b.Momentum == 0 {
+		return defaultBatchNormMomentum
+	}
+	return b.Momentum
+}
@@ -0,0 +1,133 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+type batchNormTestFunc struct {
+	Layer *BatchNormLayer
+	N     int
+}
+
+func (b batchNormTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return b.Layer.Batch(in, b.N)
+}
+
+func (b batchNormTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return b.Layer.BatchR(v, in, b.N)
+}
+
+func TestBatchNormLayerOutput(t *testing.T) {
+	layer := NewBatchNormLayer(2)
+	layer.Training = true
+	copy(layer.Scales.Vector, []float64{2, -1})
+	copy(layer.Biases.Vector, []float64{0.5, 1})
+
+	input := &autofunc.Variable{Vector: []float64{1, 3, 2, 5, 3, 7}}
+	output := layer.Batch(input, 3).Output()
+
+	invStd1 := 1 / math.Sqrt(2.0/3+defaultBatchNormEpsilon)
+	invStd2 := 1 / math.Sqrt(8.0/3+defaultBatchNormEpsilon)
+	expected := []float64{
+		-2*invStd1 + 0.5, 2*invStd2 + 1,
+		0.5, 1,
+		2*invStd1 + 0.5, -2*invStd2 + 1,
+	}
+	for i, x := range expected {
+		if math.Abs(output[i]-x) > 1e-5 {
+			t.Errorf("output %d: expected %f but got %f", i, x, output[i])
+		}
+	}
+
+	expMean := []float64{0.1 * 2, 0.1 * 5}
+	expVar := []float64{0.9 + 0.1*2.0/3, 0.9 + 0.1*8.0/3}
+	for i := range expMean {
+		if math.Abs(layer.RunningMean[i]-expMean[i]) > 1e-5 {
+			t.Errorf("running mean %d: expected %f but got %f", i, expMean[i],
+				layer.RunningMean[i])
+		}
+		if math.Abs(layer.RunningVariance[i]-expVar[i]) > 1e-5 {
+			t.Errorf("running variance %d: expected %f but got %f", i, expVar[i],
+				layer.RunningVariance[i])
+		}
+	}
+}
+
+func TestBatchNormLayerUsage(t *testing.T) {
+	layer := NewBatchNormLayer(2)
+	copy(layer.Scales.Vector, []float64{2, -1})
+	copy(layer.Biases.Vector, []float64{0.5, 1})
+	copy(layer.RunningMean, []float64{1, -1})
+	copy(layer.RunningVariance, []float64{4, 0.25})
+	layer.Epsilon = 1e-10
+
+	output := layer.Apply(&autofunc.Variable{Vector: []float64{3, 0}}).Output()
+	expected := []float64{2*(2.0/2) + 0.5, -1*(1/0.5) + 1}
+	for i, x := range expected {
+		if math.Abs(output[i]-x) > 1e-5 {
+			t.Errorf("output %d: expected %f but got %f", i, x, output[i])
+		}
+	}
+}
+
+func TestBatchNormLayerGradients(t *testing.T) {
+	for _, training := range []bool{false, true} {
+		layer := NewBatchNormLayer(3)
+		layer.Training = training
+		input := &autofunc.Variable{Vector: make(linalg.Vector, 24)}
+		params := append(layer.Parameters(), input)
+		rVec := autofunc.RVector{}
+		for _, p := range params {
+			rVec[p] = make(linalg.Vector, len(p.Vector))
+			for i := range p.Vector {
+				p.Vector[i] = rand.NormFloat64()
+				rVec[p][i] = rand.NormFloat64()
+			}
+		}
+		f := batchNormTestFunc{Layer: layer, N: 8}
+		checker := &functest.RFuncChecker{F: f, Vars: params, Input: input, RV: rVec}
+		checker.FullCheck(t)
+	}
+}
+
+func TestBatchNormLayerSerialize(t *testing.T) {
+	layer := NewBatchNormLayer(3)
+	layer.Momentum = 0.5
+	layer.Training = true
+	layer.Batch(&autofunc.Variable{Vector: []float64{1, 2, 3, -1, 0, 5}}, 2)
+
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLayer, ok := decoded.(*BatchNormLayer)
+	if !ok {
+		t.Fatalf("expected *BatchNormLayer but got %T", decoded)
+	}
+	if newLayer.Momentum != layer.Momentum || !newLayer.Training {
+		t.Error("hyper-parameters were not preserved")
+	}
+	vecs := [][2]linalg.Vector{
+		{layer.Scales.Vector, newLayer.Scales.Vector},
+		{layer.Biases.Vector, newLayer.Biases.Vector},
+		{layer.RunningMean, newLayer.RunningMean},
+		{layer.RunningVariance, newLayer.RunningVariance},
+	}
+	for i, pair := range vecs {
+		if len(pair[0]) != len(pair[1]) ||
+			pair[0].Copy().Scale(-1).Add(pair[1]).MaxAbs() != 0 {
+			t.Errorf("vector %d: expected %v but got %v", i, pair[0], pair[1])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package neuralnet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// A BatchPredictor applies a Network to many inputs,
+// splitting them into batches of a fixed size and
+// reusing its buffers from one call to the next.
+//
+// Unlike Network.PredictBatch, a BatchPredictor builds
+// the Network's batcher once, copies inputs into a
+// single pre-allocated batch vector instead of joining
+// them, and writes outputs into a reused buffer.
+// The layers themselves still allocate their outputs
+// for each batch, so larger batch sizes amortize more
+// of the per-call overhead.
+//
+// A BatchPredictor may be reused sequentially, but it
+// may not be used from multiple goroutines at once.
+// Create one BatchPredictor per goroutine instead.
+type BatchPredictor struct {
+	batcher   BatchLearner
+	batchSize int
+	inSize    int
+
+	inVar   *autofunc.Variable
+	outData linalg.Vector
+	outputs []linalg.Vector
+}
+
+// NewBatchPredictor creates a BatchPredictor which runs
+// n on batches of up to batchSize inputs.
+// It fails if the input size of n cannot be determined
+// (see NewNetwork).
+//
+// If n is modified, the BatchPredictor should be
+// considered invalid.
+func NewBatchPredictor(n Network, batchSize int) (*BatchPredictor, error) {
+	if batchSize <= 0 {
+		return nil, errors.New("batch size must be positive")
+	}
+	inSize, ok := inputSize(n)
+	if !ok {
+		return nil, errors.New("network input size is unknown")
+	}
+	return &BatchPredictor{
+		batcher:   n.BatchLearner(),
+		batchSize: batchSize,
+		inSize:    inSize,
+		inVar: &autofunc.Variable{
+			Vector: make(linalg.Vector, batchSize*inSize),
+		},
+	}, nil
+}
+
+// Predict applies the network to every input.
+//
+// The returned vectors are owned by the BatchPredictor
+// and are overwritten by the next call to Predict, so
+// they must be copied if they are needed afterwards.
+func (b *BatchPredictor) Predict(inputs []linalg.Vector) ([]linalg.Vector, error) {
+	for i, in := range inputs {
+		if len(in) != b.inSize {
+			return nil, fmt.Errorf("input %d has length %d but expected %d",
+				i, len(in), b.inSize)
+		}
+	}
+	for start := 0; start < len(inputs); start += b.batchSize {
+		batch := inputs[start:]
+		if len(batch) > b.batchSize {
+			batch = batch[:b.batchSize]
+		}
+		b.inVar.Vector = b.inVar.Vector[:cap(b.inVar.Vector)]
+		for i, in := range batch {
+			copy(b.inVar.Vector[i*b.inSize:], in)
+		}
+		b.inVar.Vector = b.inVar.Vector[:len(batch)*b.inSize]
+		output := b.batcher.Batch(b.inVar, len(batch)).Output()
+		b.storeOutputs(start, len(inputs), len(batch), output)
+	}
+	return b.outputs[:len(inputs)], nil
+}
+
+// storeOutputs copies the outputs of the batch which
+// begins at input index start into the output buffer.
+func (b *BatchPredictor) storeOutputs(start, total, n int, output linalg.Vector) {
+	outSize := len(output) / n
+	if start == 0 {
+		if len(b.outData) < total*outSize {
+			b.outData = make(linalg.Vector, total*outSize)
+		}
+		if len(b.outputs) < total {
+			b.outputs = make([]linalg.Vector, total)
+		}
+		for i := range b.outputs[:total] {
+			b.outputs[i] = b.outData[i*outSize : (i+1)*outSize]
+		}
+	}
+	for i := 0; i < n; i++ {
+		copy(b.outputs[start+i], output[i*outSize:(i+1)*outSize])
+	}
+}
@@ -0,0 +1,83 @@
+package neuralnet
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestBatchPredictor(t *testing.T) {
+	net := Network{NewDenseLayer(3, 4), &Sigmoid{}, NewDenseLayer(4, 2)}
+	predictor, err := NewBatchPredictor(net, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, count := range []int{7, 4, 2, 9} {
+		inputs := make([]linalg.Vector, count)
+		for i := range inputs {
+			inputs[i] = linalg.Vector{rand.NormFloat64(), rand.NormFloat64(),
+				rand.NormFloat64()}
+		}
+		expected, err := net.PredictBatch(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := predictor.Predict(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(actual) != count {
+			t.Fatalf("expected %d outputs but got %d", count, len(actual))
+		}
+		for i, exp := range expected {
+			if actual[i].Copy().Scale(-1).Add(exp).MaxAbs() > 1e-8 {
+				t.Errorf("count %d: output %d should be %v but got %v", count, i,
+					exp, actual[i])
+			}
+		}
+	}
+
+	if _, err := predictor.Predict([]linalg.Vector{{1, 2}}); err == nil {
+		t.Error("expected error for invalid input size")
+	}
+	if _, err := NewBatchPredictor(Network{&Sigmoid{}}, 4); err == nil {
+		t.Error("expected error for unknown input size")
+	}
+}
+
+func BenchmarkPredictPerSample(b *testing.B) {
+	net, inputs := batchPredictorBenchmarkData()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, in := range inputs {
+			net.Predict(in)
+		}
+	}
+}
+
+func BenchmarkBatchPredictor(b *testing.B) {
+	net, inputs := batchPredictorBenchmarkData()
+	predictor, err := NewBatchPredictor(net, 64)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		predictor.Predict(inputs)
+	}
+}
+
+func batchPredictorBenchmarkData() (Network, []linalg.Vector) {
+	net := Network{NewDenseLayer(100, 50), &ReLU{}, NewDenseLayer(50, 10), &Sigmoid{}}
+	inputs := make([]linalg.Vector, 256)
+	for i := range inputs {
+		inputs[i] = make(linalg.Vector, 100)
+		for j := range inputs[i] {
+			inputs[i][j] = rand.NormFloat64()
+		}
+	}
+	return net, inputs
+}
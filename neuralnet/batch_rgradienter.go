@@ -29,6 +29,10 @@ type BatchRGradienter struct {
 
 	// MaxBatchSize is the maximum number of samples the
 	// BatchRGradienter will pass to the learner at once.
+	// The gradients for larger SampleSets are summed
+	// over several sub-batches, so a small MaxBatchSize
+	// bounds memory usage without limiting the
+	// effective batch size used for each step.
 	// If this is 0, a reasonable default is used.
 	MaxBatchSize int
 
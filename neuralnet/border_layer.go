@@ -47,6 +47,17 @@ func (b *BorderLayer) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.
 	}
 }
 
+// InputSize returns the length of each input.
+func (b *BorderLayer) InputSize() int {
+	return b.InputWidth * b.InputHeight * b.InputDepth
+}
+
+// OutputSize returns the length of each output.
+func (b *BorderLayer) OutputSize() int {
+	return (b.InputWidth + b.LeftBorder + b.RightBorder) *
+		(b.InputHeight + b.TopBorder + b.BottomBorder) * b.InputDepth
+}
+
 func (b *BorderLayer) Serialize() ([]byte, error) {
 	return json.Marshal(b)
 }
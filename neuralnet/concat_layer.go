@@ -0,0 +1,117 @@
+package neuralnet
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/serializer"
+)
+
+// A ConcatLayer feeds its input to several branches of
+// layers and concatenates the branches' outputs.
+// This makes it possible to build networks which split
+// into multiple paths and then merge again.
+//
+// If InputSizes is nil, every branch is given the
+// entire input.
+// Otherwise, the input is split into consecutive
+// segments, one for each branch, whose sizes are given
+// by InputSizes.
+type ConcatLayer struct {
+	Layers     []Layer
+	InputSizes []int
+}
+
+// DeserializeConcatLayer deserializes a ConcatLayer.
+func DeserializeConcatLayer(d []byte) (*ConcatLayer, error) {
+	var n Network
+	var sizes []int
+	if err := serializer.DeserializeAny(d, &n, &sizes); err != nil {
+		return nil, err
+	}
+	res := &ConcatLayer{Layers: n}
+	if len(sizes) > 0 {
+		res.InputSizes = sizes
+	}
+	return res, nil
+}
+
+// Apply applies the layer.
+func (c *ConcatLayer) Apply(in autofunc.Result) autofunc.Result {
+	return autofunc.Pool(in, func(in autofunc.Result) autofunc.Result {
+		outs := make([]autofunc.Result, len(c.Layers))
+		for i, layer := range c.Layers {
+			if c.InputSizes == nil {
+				outs[i] = layer.Apply(in)
+			} else {
+				start, end := c.segment(len(in.Output()), i)
+				outs[i] = layer.Apply(autofunc.Slice(in, start, end))
+			}
+		}
+		return autofunc.Concat(outs...)
+	})
+}
+
+// ApplyR applies the layer.
+func (c *ConcatLayer) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return autofunc.PoolR(in, func(in autofunc.RResult) autofunc.RResult {
+		outs := make([]autofunc.RResult, len(c.Layers))
+		for i, layer := range c.Layers {
+			if c.InputSizes == nil {
+				outs[i] = layer.ApplyR(rv, in)
+			} else {
+				start, end := c.segment(len(in.Output()), i)
+				outs[i] = layer.ApplyR(rv, autofunc.SliceR(in, start, end))
+			}
+		}
+		return autofunc.ConcatR(outs...)
+	})
+}
+
+// Randomize randomizes every branch which implements
+// Randomizer.
+func (c *ConcatLayer) Randomize() {
+	Network(c.Layers).Randomize()
+}
+
+// RandomizeWithRand is like Randomize, but it passes r
+// to every branch which implements RandRandomizer.
+func (c *ConcatLayer) RandomizeWithRand(r *rand.Rand) {
+	Network(c.Layers).RandomizeWithRand(r)
+}
+
+// Parameters returns the parameters of every branch.
+func (c *ConcatLayer) Parameters() []*autofunc.Variable {
+	return Network(c.Layers).Parameters()
+}
+
+// SerializerType returns the unique ID used to serialize
+// a ConcatLayer with the serializer package.
+func (c *ConcatLayer) SerializerType() string {
+	return serializerTypeConcatLayer
+}
+
+// Serialize serializes the layer.
+func (c *ConcatLayer) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(Network(c.Layers), serializer.IntSlice(c.InputSizes))
+}
+
+// segment returns the bounds of the input segment for
+// the given branch, validating the input size.
+func (c *ConcatLayer) segment(inSize, branch int) (start, end int) {
+	if len(c.InputSizes) != len(c.Layers) {
+		panic("input size count must match layer count")
+	}
+	var total int
+	for i, size := range c.InputSizes {
+		if i == branch {
+			start = total
+			end = total + size
+		}
+		total += size
+	}
+	if total != inSize {
+		panic("invalid input size")
+	}
+	return
+}
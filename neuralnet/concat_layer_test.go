@@ -0,0 +1,156 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func TestConcatLayerOutput(t *testing.T) {
+	dense1 := NewDenseLayer(3, 2)
+	dense2 := NewDenseLayer(3, 4)
+	input := &autofunc.Variable{Vector: []float64{1, -1, 0.5}}
+	expected := append(dense1.Apply(input).Output().Copy(), dense2.Apply(input).Output()...)
+	layer := &ConcatLayer{Layers: []Layer{dense1, dense2}}
+	checkConcatOutput(t, layer, input, expected)
+
+	split := &ConcatLayer{
+		Layers:     []Layer{dense1, &Sigmoid{}},
+		InputSizes: []int{3, 2},
+	}
+	input = &autofunc.Variable{Vector: []float64{1, -1, 0.5, 2, -3}}
+	expected = append(dense1.Apply(&autofunc.Variable{Vector: input.Vector[:3]}).Output().Copy(),
+		1/(1+math.Exp(-2)), 1/(1+math.Exp(3)))
+	checkConcatOutput(t, split, input, expected)
+}
+
+func TestConcatLayerInputSize(t *testing.T) {
+	layer := &ConcatLayer{
+		Layers:     []Layer{&Sigmoid{}, &Sigmoid{}},
+		InputSizes: []int{2, 2},
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid input size")
+		}
+	}()
+	layer.Apply(&autofunc.Variable{Vector: make(linalg.Vector, 5)})
+}
+
+func TestConcatLayerGradients(t *testing.T) {
+	layer := &ConcatLayer{
+		Layers: []Layer{
+			Network{NewDenseLayer(2, 3), &Sigmoid{}},
+			NewDenseLayer(2, 2),
+			Network{&HyperbolicTangent{}, NewDenseLayer(1, 2)},
+		},
+		InputSizes: []int{2, 2, 1},
+	}
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 5)}
+	params := append(layer.Parameters(), input)
+	rVec := autofunc.RVector{}
+	for _, p := range params {
+		rVec[p] = make(linalg.Vector, len(p.Vector))
+		for i := range rVec[p] {
+			rVec[p][i] = rand.NormFloat64()
+		}
+	}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+	}
+	t.Run("Gradient", func(t *testing.T) {
+		checker := &functest.FuncChecker{F: layer, Vars: params, Input: input}
+		checker.FullCheck(t)
+	})
+	t.Run("RGradient", func(t *testing.T) {
+		checker := &functest.RFuncChecker{F: layer, Vars: params, Input: input, RV: rVec}
+		checker.FullCheck(t)
+	})
+}
+
+func TestConcatLayerSerialize(t *testing.T) {
+	layers := []*ConcatLayer{
+		{Layers: []Layer{NewDenseLayer(2, 2), NewDenseLayer(2, 1)}},
+		{Layers: []Layer{NewDenseLayer(2, 2), NewDenseLayer(3, 1)}, InputSizes: []int{2, 3}},
+	}
+	inputs := []linalg.Vector{{1, -2}, {1, -2, 3, 0.5, -1}}
+	for i, layer := range layers {
+		data, err := layer.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newLayer, ok := decoded.(*ConcatLayer)
+		if !ok {
+			t.Fatalf("expected *ConcatLayer but got %T", decoded)
+		}
+		if len(newLayer.InputSizes) != len(layer.InputSizes) ||
+			(newLayer.InputSizes == nil) != (layer.InputSizes == nil) {
+			t.Errorf("layer %d: expected InputSizes %v but got %v", i,
+				layer.InputSizes, newLayer.InputSizes)
+		}
+		input := &autofunc.Variable{Vector: inputs[i]}
+		checkConcatOutput(t, newLayer, input, layer.Apply(input).Output())
+	}
+}
+
+func TestNewNetworkConcat(t *testing.T) {
+	_, err := NewNetwork(
+		&ConcatLayer{Layers: []Layer{NewDenseLayer(3, 2), NewDenseLayer(3, 4)}},
+		NewDenseLayer(6, 1),
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = NewNetwork(
+		&ConcatLayer{Layers: []Layer{NewDenseLayer(3, 2), NewDenseLayer(3, 4)}},
+		NewDenseLayer(4, 1),
+	)
+	if err == nil {
+		t.Error("expected error for mismatched sizes")
+	}
+
+	invalid := []*ConcatLayer{
+		{Layers: []Layer{NewDenseLayer(3, 2), NewDenseLayer(3, 4)}, InputSizes: []int{3}},
+		{Layers: []Layer{NewDenseLayer(3, 2), NewDenseLayer(2, 4)}},
+		{Layers: []Layer{NewDenseLayer(3, 2), NewDenseLayer(2, 4)}, InputSizes: []int{3, 3}},
+	}
+	for i, layer := range invalid {
+		if _, _, ok := layerSizes(layer); ok {
+			t.Errorf("layer %d: expected unknown sizes", i)
+		}
+		if _, err := NewNetwork(layer, NewDenseLayer(6, 1)); err == nil {
+			t.Errorf("layer %d: expected error for invalid branch sizes", i)
+		}
+	}
+
+	_, err = NewNetwork(
+		NewDenseLayer(2, 3),
+		&ConcatLayer{Layers: []Layer{NewDenseLayer(3, 2), &Sigmoid{}}},
+		NewDenseLayer(5, 1),
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func checkConcatOutput(t *testing.T, layer *ConcatLayer, input *autofunc.Variable,
+	expected linalg.Vector) {
+	actual := layer.Apply(input).Output()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d outputs but got %d", len(expected), len(actual))
+	}
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d: expected %f but got %f", i, x, actual[i])
+		}
+	}
+}
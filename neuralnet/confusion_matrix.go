@@ -0,0 +1,163 @@
+package neuralnet
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// A ConfusionMatrix counts the predictions made by a
+// classifier.
+// The entry at m[i][j] is the number of samples of
+// class i which were classified as class j.
+type ConfusionMatrix [][]int
+
+// NewConfusionMatrix creates a ConfusionMatrix from a
+// list of predicted labels and the corresponding list
+// of true labels.
+// Predicted labels can be obtained from a classifier's
+// outputs using ArgMax.
+func NewConfusionMatrix(predicted, actual []int, numClasses int) ConfusionMatrix {
+	if len(predicted) != len(actual) {
+		panic("predicted and actual label counts do not match")
+	}
+	res := make(ConfusionMatrix, numClasses)
+	for i := range res {
+		res[i] = make([]int, numClasses)
+	}
+	for i, label := range actual {
+		res[label][predicted[i]]++
+	}
+	return res
+}
+
+// Accuracy returns the fraction of all samples which
+// were classified correctly.
+func (c ConfusionMatrix) Accuracy() float64 {
+	var correct, total int
+	for i, row := range c {
+		for j, count := range row {
+			if i == j {
+				correct += count
+			}
+			total += count
+		}
+	}
+	return safeRatio(correct, total)
+}
+
+// Precision returns the fraction of samples classified
+// as the given class which actually belong to it.
+func (c ConfusionMatrix) Precision(class int) float64 {
+	var total int
+	for _, row := range c {
+		total += row[class]
+	}
+	return safeRatio(c[class][class], total)
+}
+
+// Recall returns the fraction of samples of the given
+// class which were classified as that class.
+func (c ConfusionMatrix) Recall(class int) float64 {
+	var total int
+	for _, count := range c[class] {
+		total += count
+	}
+	return safeRatio(c[class][class], total)
+}
+
+// F1 returns the harmonic mean of the precision and
+// recall for the given class.
+func (c ConfusionMatrix) F1(class int) float64 {
+	precision, recall := c.Precision(class), c.Recall(class)
+	if precision+recall == 0 {
+		return 0
+	}
+	return 2 * precision * recall / (precision + recall)
+}
+
+// String formats the matrix as a table, with one row
+// for each true class and one column for each
+// predicted class.
+func (c ConfusionMatrix) String() string {
+	width := len(strconv.Itoa(len(c) - 1))
+	for _, row := range c {
+		for _, count := range row {
+			if w := len(strconv.Itoa(count)); w > width {
+				width = w
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%*s", width, "")
+	for j := range c {
+		fmt.Fprintf(&buf, " %*d", width, j)
+	}
+	for i, row := range c {
+		fmt.Fprintf(&buf, "\n%*d", width, i)
+		for _, count := range row {
+			fmt.Fprintf(&buf, " %*d", width, count)
+		}
+	}
+	return buf.String()
+}
+
+// Accuracy returns the fraction of predicted labels
+// which match the corresponding true labels.
+func Accuracy(predicted, actual []int) float64 {
+	if len(predicted) != len(actual) {
+		panic("predicted and actual label counts do not match")
+	}
+	var correct int
+	for i, label := range actual {
+		if predicted[i] == label {
+			correct++
+		}
+	}
+	return safeRatio(correct, len(actual))
+}
+
+// TopKAccuracy returns the fraction of samples whose
+// true label is among the k highest-scoring classes of
+// the corresponding prediction vector.
+//
+// Ties are broken like they are in ArgMax, in favor of
+// the lowest index, so TopKAccuracy with k=1 agrees
+// with Accuracy on the ArgMax of each prediction.
+// It panics if k is not between 1 and the number of
+// classes in a prediction.
+func TopKAccuracy(predictions []linalg.Vector, labels []int, k int) float64 {
+	if len(predictions) != len(labels) {
+		panic("prediction and label counts do not match")
+	}
+	var correct int
+	for i, pred := range predictions {
+		if k < 1 || k > len(pred) {
+			panic("k out of range")
+		}
+		label := labels[i]
+		if label < 0 || label >= len(pred) {
+			panic("label out of range")
+		}
+		var rank int
+		for j, x := range pred {
+			if x > pred[label] || (x == pred[label] && j < label) {
+				rank++
+			}
+		}
+		if rank < k {
+			correct++
+		}
+	}
+	return safeRatio(correct, len(labels))
+}
+
+func safeRatio(num, denom int) float64 {
+	if denom == 0 {
+		return 0
+	}
+	return float64(num) / float64(denom)
+}
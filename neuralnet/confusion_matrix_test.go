@@ -0,0 +1,84 @@
+package neuralnet
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestConfusionMatrix(t *testing.T) {
+	actual := []int{0, 0, 0, 1, 1, 2, 2, 2}
+	predicted := []int{0, 1, 0, 1, 1, 0, 2, 2}
+	m := NewConfusionMatrix(predicted, actual, 4)
+	expected := ConfusionMatrix{
+		{2, 1, 0, 0},
+		{0, 2, 0, 0},
+		{1, 0, 2, 0},
+		{0, 0, 0, 0},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("expected %v but got %v", expected, m)
+	}
+
+	checks := []struct {
+		Name     string
+		Actual   float64
+		Expected float64
+	}{
+		{"accuracy", m.Accuracy(), 6.0 / 8},
+		{"label accuracy", Accuracy(predicted, actual), 6.0 / 8},
+		{"precision 0", m.Precision(0), 2.0 / 3},
+		{"precision 1", m.Precision(1), 2.0 / 3},
+		{"recall 0", m.Recall(0), 2.0 / 3},
+		{"recall 1", m.Recall(1), 1},
+		{"F1 1", m.F1(1), 0.8},
+		{"precision 3", m.Precision(3), 0},
+		{"F1 3", m.F1(3), 0},
+	}
+	for _, c := range checks {
+		if math.Abs(c.Actual-c.Expected) > 1e-8 {
+			t.Errorf("%s: expected %f but got %f", c.Name, c.Expected, c.Actual)
+		}
+	}
+}
+
+func TestConfusionMatrixString(t *testing.T) {
+	m := ConfusionMatrix{{12, 0}, {3, 5}}
+	expected := "    0  1\n 0 12  0\n 1  3  5"
+	if s := m.String(); s != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+func TestTopKAccuracy(t *testing.T) {
+	predictions := []linalg.Vector{
+		{0.1, 0.5, 0.3, 0.1},
+		{0.4, 0.1, 0.3, 0.2},
+		{0.2, 0.2, 0.2, 0.4},
+		{0.25, 0.25, 0.25, 0.25},
+	}
+	labels := []int{2, 3, 1, 3}
+	for i, expected := range []float64{0, 0.25, 0.75, 1} {
+		k := i + 1
+		if actual := TopKAccuracy(predictions, labels, k); math.Abs(actual-expected) > 1e-8 {
+			t.Errorf("k=%d: expected %f but got %f", k, expected, actual)
+		}
+	}
+
+	var argMaxes []int
+	for _, pred := range predictions {
+		argMaxes = append(argMaxes, ArgMax(pred))
+	}
+	if top1, acc := TopKAccuracy(predictions, labels, 1), Accuracy(argMaxes, labels); top1 != acc {
+		t.Errorf("top-1 accuracy %f does not match accuracy %f", top1, acc)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for k greater than the number of classes")
+		}
+	}()
+	TopKAccuracy(predictions, labels, 5)
+}
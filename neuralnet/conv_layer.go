@@ -1,7 +1,10 @@
 package neuralnet
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"math"
 	"math/rand"
 	"sync"
@@ -14,6 +17,12 @@ import (
 	"github.com/unixpickle/tensor"
 )
 
+// convLayerBinaryTag is the first byte of data produced
+// by ConvLayer.SerializeBinary.
+// It distinguishes binary data from JSON data, which
+// always begins with '{'.
+const convLayerBinaryTag byte = 'b'
+
 var conv32Bit bool
 var conv32BitLock sync.RWMutex
 
@@ -58,8 +67,32 @@ type ConvLayer struct {
 	FilterVar *autofunc.Variable `json:"-"`
 }
 
+// NewConvLayer creates a randomized ConvLayer which
+// applies filterCount filters of the given size to an
+// input tensor of the given size.
+func NewConvLayer(inWidth, inHeight, inDepth, filterWidth, filterHeight,
+	filterCount, stride int) *ConvLayer {
+	res := &ConvLayer{
+		FilterCount:  filterCount,
+		FilterWidth:  filterWidth,
+		FilterHeight: filterHeight,
+		Stride:       stride,
+		InputWidth:   inWidth,
+		InputHeight:  inHeight,
+		InputDepth:   inDepth,
+	}
+	res.Randomize()
+	return res
+}
+
 // DeserializeConvLayer deserializes a ConvLayer.
+// It accepts data from either Serialize or
+// SerializeBinary.
 func DeserializeConvLayer(data []byte) (*ConvLayer, error) {
+	if len(data) > 0 && data[0] == convLayerBinaryTag {
+		return deserializeConvLayerBinary(data[1:])
+	}
+
 	var c ConvLayer
 	if err := json.Unmarshal(data, &c); err != nil {
 		return nil, err
@@ -106,6 +139,13 @@ func (c *ConvLayer) OutputDepth() int {
 // This will allocate c.Filters, c.Biases,
 // c.FilterVars, and c.BiasVars if needed.
 func (c *ConvLayer) Randomize() {
+	c.RandomizeWithRand(nil)
+}
+
+// RandomizeWithRand is like Randomize, but it uses r as
+// its source of randomness.
+// If r is nil, the global math/rand source is used.
+func (c *ConvLayer) RandomizeWithRand(r *rand.Rand) {
 	if c.Filters == nil {
 		filterSize := c.FilterWidth * c.FilterHeight * c.InputDepth
 		weightCount := c.FilterCount * filterSize
@@ -129,9 +169,9 @@ func (c *ConvLayer) Randomize() {
 	for i, filter := range c.Filters {
 		coeff := math.Sqrt(3.0 / float64(len(filter.Data)))
 		for i := range filter.Data {
-			filter.Data[i] = coeff * ((rand.Float64() * 2) - 1)
+			filter.Data[i] = coeff * ((randFloat64(r) * 2) - 1)
 		}
-		c.Biases.Vector[i] = (rand.Float64() * 2) - 1
+		c.Biases.Vector[i] = (randFloat64(r) * 2) - 1
 	}
 }
 
@@ -238,17 +278,92 @@ func (c *ConvLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
 	return res
 }
 
+// InputSize returns the length of each input.
+func (c *ConvLayer) InputSize() int {
+	return c.InputWidth * c.InputHeight * c.InputDepth
+}
+
+// OutputSize returns the length of each output.
+func (c *ConvLayer) OutputSize() int {
+	return c.OutputWidth() * c.OutputHeight() * c.OutputDepth()
+}
+
 // Serialize serializes the layer.
 func (c *ConvLayer) Serialize() ([]byte, error) {
 	return json.Marshal(c)
 }
 
+// SerializeBinary is like Serialize, but it encodes
+// the layer in a compact binary format rather than
+// JSON.
+// This makes the data of large layers considerably
+// smaller and faster to decode.
+func (c *ConvLayer) SerializeBinary() ([]byte, error) {
+	if c.Biases == nil || c.FilterVar == nil {
+		panic(uninitPanicMessage)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(convLayerBinaryTag)
+	header := []int64{
+		int64(c.FilterCount), int64(c.FilterWidth), int64(c.FilterHeight),
+		int64(c.Stride), int64(c.InputWidth), int64(c.InputHeight),
+		int64(c.InputDepth),
+	}
+	for _, x := range [][]float64{c.FilterVar.Vector, c.Biases.Vector} {
+		header = append(header, int64(len(x)))
+	}
+	binary.Write(&buf, denseLayerByteOrder, header)
+	binary.Write(&buf, denseLayerByteOrder, []float64(c.FilterVar.Vector))
+	binary.Write(&buf, denseLayerByteOrder, []float64(c.Biases.Vector))
+	return buf.Bytes(), nil
+}
+
 // SerializerType returns the unique ID used to serialize
 // this layer with the serializer package.
 func (c *ConvLayer) SerializerType() string {
 	return serializerTypeConvLayer
 }
 
+func deserializeConvLayerBinary(data []byte) (*ConvLayer, error) {
+	reader := bytes.NewReader(data)
+	header := make([]int64, 9)
+	if err := binary.Read(reader, denseLayerByteOrder, header); err != nil {
+		return nil, err
+	}
+	c := &ConvLayer{
+		FilterCount:  int(header[0]),
+		FilterWidth:  int(header[1]),
+		FilterHeight: int(header[2]),
+		Stride:       int(header[3]),
+		InputWidth:   int(header[4]),
+		InputHeight:  int(header[5]),
+		InputDepth:   int(header[6]),
+	}
+	filterSize := c.FilterWidth * c.FilterHeight * c.InputDepth
+	if header[7] != int64(c.FilterCount*filterSize) || header[8] != int64(c.FilterCount) {
+		return nil, fmt.Errorf("unexpected ConvLayer parameter counts: %d and %d",
+			header[7], header[8])
+	}
+	if dataSize := 8 * (header[7] + header[8]); int64(reader.Len()) != dataSize {
+		return nil, fmt.Errorf("expected %d ConvLayer bytes but have %d",
+			dataSize, reader.Len())
+	}
+
+	c.FilterVar = &autofunc.Variable{Vector: make(linalg.Vector, header[7])}
+	c.Biases = &autofunc.Variable{Vector: make(linalg.Vector, header[8])}
+	binary.Read(reader, denseLayerByteOrder, []float64(c.FilterVar.Vector))
+	binary.Read(reader, denseLayerByteOrder, []float64(c.Biases.Vector))
+	for i := 0; i < c.FilterCount; i++ {
+		c.Filters = append(c.Filters, &tensor.Float64{
+			Width:  c.FilterWidth,
+			Height: c.FilterHeight,
+			Depth:  c.InputDepth,
+			Data:   c.FilterVar.Vector[i*filterSize : (i+1)*filterSize],
+		})
+	}
+	return c, nil
+}
+
 func (c *ConvLayer) im2ColDims() *tensor.Im2ColDims {
 	return &tensor.Im2ColDims{
 		ImageWidth:  c.InputWidth,
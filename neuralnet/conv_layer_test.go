@@ -34,6 +34,25 @@ func TestConvDimensions(t *testing.T) {
 	}
 }
 
+func TestNewConvLayer(t *testing.T) {
+	layer := NewConvLayer(17, 56, 3, 5, 4, 7, 2)
+	if layer.OutputWidth() != 7 || layer.OutputHeight() != 27 || layer.OutputDepth() != 7 {
+		t.Errorf("unexpected output dimensions %dx%dx%d", layer.OutputWidth(),
+			layer.OutputHeight(), layer.OutputDepth())
+	}
+	if len(layer.Filters) != 7 || len(layer.Biases.Vector) != 7 {
+		t.Fatalf("expected 7 filters and biases but got %d and %d", len(layer.Filters),
+			len(layer.Biases.Vector))
+	}
+	if len(layer.FilterVar.Vector) != 7*5*4*3 {
+		t.Errorf("unexpected filter variable size %d", len(layer.FilterVar.Vector))
+	}
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 17*56*3)}
+	if outLen := len(layer.Apply(input).Output()); outLen != 7*27*7 {
+		t.Errorf("unexpected output length %d", outLen)
+	}
+}
+
 func TestConvForward(t *testing.T) {
 	convTestBothSizes(t, func(t *testing.T) {
 		network, input, _ := convLayerTestInfo()
@@ -154,6 +173,42 @@ func TestConvLayerSerialize(t *testing.T) {
 	}
 }
 
+func TestConvLayerSerializeBinary(t *testing.T) {
+	layer := NewConvLayer(5, 4, 3, 2, 3, 4, 1)
+	data, err := layer.SerializeBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, _ := layer.Serialize()
+	if len(data) >= len(jsonData) {
+		t.Errorf("binary data (%d bytes) not smaller than JSON (%d bytes)",
+			len(data), len(jsonData))
+	}
+
+	decoded, err := DeserializeConvLayer(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.OutputWidth() != layer.OutputWidth() ||
+		decoded.OutputHeight() != layer.OutputHeight() ||
+		decoded.FilterCount != layer.FilterCount {
+		t.Fatal("layer dimensions were not preserved")
+	}
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 5*4*3)}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+	}
+	expected := layer.Apply(input).Output()
+	actual := decoded.Apply(input).Output()
+	if actual.Copy().Scale(-1).Add(expected).MaxAbs() != 0 {
+		t.Errorf("expected output %v but got %v", expected, actual)
+	}
+
+	if _, err := DeserializeConvLayer(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
 func TestConvLayerRProp(t *testing.T) {
 	convTestBothSizes(t, func(t *testing.T) {
 		layer := &ConvLayer{
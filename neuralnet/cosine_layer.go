@@ -0,0 +1,167 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// cosineLayerEpsilon is added to squared norms to
+// prevent division by zero for zero vectors.
+const cosineLayerEpsilon = 1e-10
+
+// A CosineLayer computes the cosine similarity between
+// its input and each of a set of learnable prototype
+// vectors, producing one output per prototype.
+//
+// This can be placed on top of an embedding network to
+// build prototype-based classifiers or to learn
+// metrics.
+// Gradients are propagated through the normalization of
+// both the input and the prototypes.
+type CosineLayer struct {
+	InputCount     int
+	PrototypeCount int
+
+	// Prototypes stores the prototype matrix, with one
+	// row of InputCount components for each prototype.
+	Prototypes *autofunc.Variable
+}
+
+// NewCosineLayer creates a CosineLayer with randomized
+// prototypes.
+func NewCosineLayer(inCount, prototypeCount int) *CosineLayer {
+	res := &CosineLayer{InputCount: inCount, PrototypeCount: prototypeCount}
+	res.Randomize()
+	return res
+}
+
+// DeserializeCosineLayer deserializes a CosineLayer.
+func DeserializeCosineLayer(d []byte) (*CosineLayer, error) {
+	var res CosineLayer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Randomize sets the prototypes to random values drawn
+// from a normal distribution.
+// This will allocate c.Prototypes if needed.
+func (c *CosineLayer) Randomize() {
+	c.RandomizeWithRand(nil)
+}
+
+// RandomizeWithRand is like Randomize, but it uses r as
+// its source of randomness.
+// If r is nil, the global math/rand source is used.
+func (c *CosineLayer) RandomizeWithRand(r *rand.Rand) {
+	if c.Prototypes == nil {
+		c.Prototypes = &autofunc.Variable{
+			Vector: make(linalg.Vector, c.InputCount*c.PrototypeCount),
+		}
+	}
+	for i := range c.Prototypes.Vector {
+		c.Prototypes.Vector[i] = randNormFloat64(r)
+	}
+}
+
+// Parameters returns a slice containing the prototype
+// matrix variable.
+func (c *CosineLayer) Parameters() []*autofunc.Variable {
+	if c.Prototypes == nil {
+		panic(uninitPanicMessage)
+	}
+	return []*autofunc.Variable{c.Prototypes}
+}
+
+// Apply applies the layer to a single input.
+func (c *CosineLayer) Apply(in autofunc.Result) autofunc.Result {
+	return c.Batch(in, 1)
+}
+
+// ApplyR is like Apply, but for RResults.
+func (c *CosineLayer) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return c.BatchR(rv, in, 1)
+}
+
+// Batch applies the layer to inputs in batch.
+func (c *CosineLayer) Batch(in autofunc.Result, n int) autofunc.Result {
+	c.checkInput(len(in.Output()), n)
+
+	// Both the batch of inputs and the prototype matrix
+	// are column-major matrices with one column per
+	// vector, so they can be normalized the same way.
+	normRow, normCol := c.normVars()
+	normalize := func(vecs autofunc.Result) autofunc.Result {
+		return autofunc.Pool(vecs, func(vecs autofunc.Result) autofunc.Result {
+			sqNorms := autofunc.MatMulVecs(normRow, 1, c.InputCount, autofunc.Square(vecs))
+			invNorms := autofunc.Pow(autofunc.AddScaler(sqNorms, cosineLayerEpsilon), -0.5)
+			return autofunc.Mul(vecs, autofunc.MatMulVecs(normCol, c.InputCount, 1, invNorms))
+		})
+	}
+	return autofunc.MatMulVecs(normalize(c.Prototypes), c.PrototypeCount, c.InputCount,
+		normalize(in))
+}
+
+// BatchR is like Batch, but for RResults.
+func (c *CosineLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	c.checkInput(len(in.Output()), n)
+	normRowVar, normColVar := c.normVars()
+	normRow := autofunc.NewRVariable(normRowVar, rv)
+	normCol := autofunc.NewRVariable(normColVar, rv)
+	normalize := func(vecs autofunc.RResult) autofunc.RResult {
+		return autofunc.PoolR(vecs, func(vecs autofunc.RResult) autofunc.RResult {
+			sqNorms := autofunc.MatMulVecsR(normRow, 1, c.InputCount, autofunc.SquareR(vecs))
+			invNorms := autofunc.PowR(autofunc.AddScalerR(sqNorms, cosineLayerEpsilon), -0.5)
+			return autofunc.MulR(vecs, autofunc.MatMulVecsR(normCol, c.InputCount, 1, invNorms))
+		})
+	}
+	prototypes := autofunc.NewRVariable(c.Prototypes, rv)
+	return autofunc.MatMulVecsR(normalize(prototypes), c.PrototypeCount, c.InputCount,
+		normalize(in))
+}
+
+// InputSize returns the length of each input.
+func (c *CosineLayer) InputSize() int {
+	return c.InputCount
+}
+
+// OutputSize returns the length of each output.
+func (c *CosineLayer) OutputSize() int {
+	return c.PrototypeCount
+}
+
+// Serialize serializes the layer.
+func (c *CosineLayer) Serialize() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// SerializerType returns the unique ID used to serialize
+// this layer with the serializer package.
+func (c *CosineLayer) SerializerType() string {
+	return serializerTypeCosineLayer
+}
+
+func (c *CosineLayer) checkInput(inLen, n int) {
+	if c.Prototypes == nil {
+		panic(uninitPanicMessage)
+	}
+	if inLen != n*c.InputCount {
+		panic("invalid input size")
+	}
+}
+
+// normVars returns constant variables for summing the
+// components of a vector and for spreading a value
+// across the components of a vector.
+func (c *CosineLayer) normVars() (row, col *autofunc.Variable) {
+	row = &autofunc.Variable{Vector: make(linalg.Vector, c.InputCount)}
+	for i := range row.Vector {
+		row.Vector[i] = 1
+	}
+	return row, &autofunc.Variable{Vector: row.Vector}
+}
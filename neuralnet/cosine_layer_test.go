@@ -0,0 +1,74 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+type cosineTestFunc struct {
+	Layer *CosineLayer
+	N     int
+}
+
+func (c cosineTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return c.Layer.Batch(in, c.N)
+}
+
+func (c cosineTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return c.Layer.BatchR(v, in, c.N)
+}
+
+func TestCosineLayerOutput(t *testing.T) {
+	layer := &CosineLayer{
+		InputCount:     2,
+		PrototypeCount: 3,
+		Prototypes:     &autofunc.Variable{Vector: []float64{2, 0, 0, -1, 1, 1}},
+	}
+	input := &autofunc.Variable{Vector: []float64{3, 4, -1, 0}}
+	output := layer.Batch(input, 2).Output()
+	expected := []float64{0.6, -0.8, 7 / (5 * math.Sqrt2), -1, 0, -1 / math.Sqrt2}
+	for i, x := range expected {
+		if math.Abs(output[i]-x) > 1e-6 {
+			t.Errorf("output %d: expected %f but got %f", i, x, output[i])
+		}
+	}
+}
+
+func TestCosineLayerGradients(t *testing.T) {
+	layer := NewCosineLayer(4, 3)
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 8)}
+	params := append(layer.Parameters(), input)
+	rVec := autofunc.RVector{}
+	for _, p := range params {
+		rVec[p] = make(linalg.Vector, len(p.Vector))
+		for i := range p.Vector {
+			p.Vector[i] = rand.NormFloat64()
+			rVec[p][i] = rand.NormFloat64()
+		}
+	}
+	f := cosineTestFunc{Layer: layer, N: 2}
+	checker := &functest.RFuncChecker{F: f, Vars: params, Input: input, RV: rVec}
+	checker.FullCheck(t)
+}
+
+func TestCosineLayerSerialize(t *testing.T) {
+	layer := NewCosineLayer(4, 3)
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, layer) {
+		t.Errorf("expected %#v but got %#v", layer, decoded)
+	}
+}
@@ -1,6 +1,7 @@
 package neuralnet
 
 import (
+	"math"
 	"sync"
 
 	"github.com/unixpickle/autofunc"
@@ -14,6 +15,14 @@ import (
 // It may be beneficial for CostFuncs to lazily
 // compute their outputs, since they may be used
 // solely for their derivatives.
+//
+// Costs are minimized: back-propagating through a
+// cost yields the gradient of the cost with respect
+// to the network's parameters, and training moves
+// the parameters against this gradient (e.g. by
+// subtracting it, scaled by a step size, in sgd.SGD).
+// Thus, a derivative for MeanSquaredCost is positive
+// when the actual output exceeds the expected one.
 type CostFunc interface {
 	Cost(expected linalg.Vector, actual autofunc.Result) autofunc.Result
 	CostR(v autofunc.RVector, expected linalg.Vector,
@@ -210,6 +219,49 @@ func (_ DotCost) CostR(v autofunc.RVector, x linalg.Vector,
 	return autofunc.ScaleR(autofunc.SumAllR(autofunc.MulR(xVar, a)), -1)
 }
 
+// NLLCost computes the negative log-likelihood of the
+// expected outputs, given actual outputs which are
+// log-probabilities (e.g. from a LogSoftmaxLayer).
+//
+// The expected outputs may be one-hot vectors (or full
+// probability distributions) with one component per
+// actual output.
+// Alternatively, they may be class indices, with one
+// component per sample, in which case the number of
+// classes is inferred from the number of actual
+// outputs.
+// Both forms work for batches of samples.
+type NLLCost struct{}
+
+func (_ NLLCost) Cost(x linalg.Vector, a autofunc.Result) autofunc.Result {
+	return DotCost{}.Cost(nllTargets(x, len(a.Output())), a)
+}
+
+func (_ NLLCost) CostR(v autofunc.RVector, x linalg.Vector,
+	a autofunc.RResult) autofunc.RResult {
+	return DotCost{}.CostR(v, nllTargets(x, len(a.Output())), a)
+}
+
+// nllTargets converts class indices into concatenated
+// one-hot vectors, leaving one-hot targets unchanged.
+func nllTargets(x linalg.Vector, outputCount int) linalg.Vector {
+	if len(x) == outputCount {
+		return x
+	}
+	if len(x) == 0 || outputCount%len(x) != 0 {
+		panic("invalid target size")
+	}
+	numClasses := outputCount / len(x)
+	res := make(linalg.Vector, 0, outputCount)
+	for _, idx := range x {
+		if idx != math.Floor(idx) {
+			panic("class index must be an integer")
+		}
+		res = append(res, OneHot(int(idx), numClasses)...)
+	}
+	return res
+}
+
 // SigmoidCECost applies a sigmoid to the actual
 // output and then uses cross-entropy loss on the
 // result.
@@ -242,6 +294,347 @@ func (_ SigmoidCECost) CostR(v autofunc.RVector, x linalg.Vector,
 	return autofunc.ScaleR(autofunc.SumAllR(sums), -1)
 }
 
+// SoftmaxCECost applies a log-softmax to the actual
+// output and then computes the cross entropy between
+// the expected distribution and the resulting one.
+// This is more numerically stable than feeding the
+// output of a SoftmaxLayer to a cross-entropy loss,
+// since it never takes the log of a probability which
+// may have underflowed to zero.
+// When the expected outputs sum to 1 (e.g. for one-hot
+// vectors or the soft targets from Dataset.Mixup), the
+// gradient of the cost with respect to the actual output
+// is softmax(actual)-expected.
+//
+// Since CostFuncs are applied to entire batches of
+// outputs at once, OutputSize specifies the size of
+// each sample's output, so that the softmax can be
+// computed separately for each sample.
+// If OutputSize is 0, the entire output is treated as
+// a single sample.
+//
+// If Smoothing is non-zero, label smoothing is applied
+// to the expected outputs before the cross entropy is
+// computed.
+// With K classes, a one-hot target becomes 1-Smoothing
+// for the true class and Smoothing/(K-1) for the rest,
+// which discourages overconfident predictions.
+// In general, each expected value x becomes
+// x*(1-Smoothing) + (1-x)*Smoothing/(K-1), so targets
+// which sum to 1 still sum to 1.
+type SoftmaxCECost struct {
+	OutputSize int
+	Smoothing  float64
+}
+
+func (s SoftmaxCECost) Cost(x linalg.Vector, a autofunc.Result) autofunc.Result {
+	n := s.sampleCount(len(x))
+	x = s.smoothTargets(x, n)
+	var logProbs []autofunc.Result
+	for _, sample := range autofunc.Split(n, a) {
+		logProbs = append(logProbs, (&LogSoftmaxLayer{}).Apply(sample))
+	}
+	return DotCost{}.Cost(x, autofunc.Concat(logProbs...))
+}
+
+func (s SoftmaxCECost) CostR(v autofunc.RVector, x linalg.Vector,
+	a autofunc.RResult) autofunc.RResult {
+	n := s.sampleCount(len(x))
+	x = s.smoothTargets(x, n)
+	var logProbs []autofunc.RResult
+	for _, sample := range autofunc.SplitR(n, a) {
+		logProbs = append(logProbs, (&LogSoftmaxLayer{}).ApplyR(v, sample))
+	}
+	return DotCost{}.CostR(v, x, autofunc.ConcatR(logProbs...))
+}
+
+// smoothTargets applies label smoothing to a batch of n
+// expected outputs.
+func (s SoftmaxCECost) smoothTargets(x linalg.Vector, n int) linalg.Vector {
+	numClasses := len(x) / n
+	if s.Smoothing == 0 || numClasses < 2 {
+		return x
+	}
+	other := s.Smoothing / float64(numClasses-1)
+	res := make(linalg.Vector, len(x))
+	for i, val := range x {
+		res[i] = val*(1-s.Smoothing) + (1-val)*other
+	}
+	return res
+}
+
+func (s SoftmaxCECost) sampleCount(outputLen int) int {
+	if s.OutputSize == 0 {
+		return 1
+	}
+	if outputLen%s.OutputSize != 0 {
+		panic("output size does not divide output length")
+	}
+	return outputLen / s.OutputSize
+}
+
+// KLDivCost computes the KL divergence from the
+// expected distribution to the softmax of the actual
+// output, as used to distill a teacher network into a
+// student.
+//
+// The expected outputs are probabilities, e.g. the
+// teacher's softmax outputs, while the actual outputs
+// are the student's logits, i.e. they should not be fed
+// through a SoftmaxLayer first.
+// As with SoftmaxCECost, OutputSize specifies the size
+// of each sample's output, and the gradient is taken
+// with respect to the logits.
+//
+// The logits are divided by Temperature before the
+// softmax, and the divergence is multiplied by
+// Temperature^2, as in "Distilling the Knowledge in a
+// Neural Network" (Hinton et al., 2015), so that the
+// magnitude of the gradients does not depend much on
+// the temperature.
+// The teacher's probabilities should be computed at the
+// same temperature, e.g. by putting a TemperatureScaler
+// before the teacher's softmax.
+// If Temperature is 0, 1 is used.
+//
+// The cost differs from SoftmaxCECost only by the
+// entropy of the expected distribution, which is a
+// constant, so it is 0 when the distributions match.
+type KLDivCost struct {
+	OutputSize  int
+	Temperature float64
+}
+
+func (k KLDivCost) Cost(x linalg.Vector, a autofunc.Result) autofunc.Result {
+	temp := k.temperature()
+	ce := SoftmaxCECost{OutputSize: k.OutputSize}.Cost(x, autofunc.Scale(a, 1/temp))
+	return autofunc.Scale(autofunc.AddScaler(ce, negEntropy(x)), temp*temp)
+}
+
+func (k KLDivCost) CostR(v autofunc.RVector, x linalg.Vector,
+	a autofunc.RResult) autofunc.RResult {
+	temp := k.temperature()
+	ce := SoftmaxCECost{OutputSize: k.OutputSize}.CostR(v, x, autofunc.ScaleR(a, 1/temp))
+	return autofunc.ScaleR(autofunc.AddScalerR(ce, negEntropy(x)), temp*temp)
+}
+
+func (k KLDivCost) temperature() float64 {
+	if k.Temperature == 0 {
+		return 1
+	}
+	return k.Temperature
+}
+
+// negEntropy computes the sum of p*log(p) over the
+// components of a batch of probability vectors.
+func negEntropy(probs linalg.Vector) float64 {
+	var res float64
+	for _, p := range probs {
+		if p > 0 {
+			res += p * math.Log(p)
+		}
+	}
+	return res
+}
+
+// focalEpsilon is the smallest value of 1-p used in the
+// focusing term of a FocalCost.
+const focalEpsilon = 1e-8
+
+// FocalCost implements the focal loss from "Focal Loss
+// for Dense Object Detection" (Lin et al., 2017), which
+// down-weights the cost of samples that are already
+// classified well, so that training on imbalanced data
+// is not dominated by easy examples.
+//
+// Like SoftmaxCECost, it applies a softmax to the
+// actual output of each sample, and OutputSize
+// specifies the size of each sample's output.
+// For each class with probability p and expected value
+// x, the cost is -x*alpha*(1-p)^Gamma*log(p).
+// When Gamma is 0 and Alpha is nil, this is equivalent
+// to SoftmaxCECost.
+//
+// Gradients take the focusing term (1-p)^Gamma into
+// account, rather than treating it as a constant
+// weight.
+// Since the derivative of (1-p)^Gamma is infinite at
+// p=1 when Gamma is less than 1, 1-p is clamped to at
+// least a small epsilon, keeping the gradients of
+// confidently correct predictions finite.
+type FocalCost struct {
+	OutputSize int
+
+	// Gamma controls how much easy examples are
+	// down-weighted.
+	// A typical value is 2.
+	Gamma float64
+
+	// Alpha, if non-nil, contains a weight for each
+	// class, e.g. to balance rare classes.
+	Alpha linalg.Vector
+}
+
+func (f FocalCost) Cost(x linalg.Vector, a autofunc.Result) autofunc.Result {
+	n := SoftmaxCECost{OutputSize: f.OutputSize}.sampleCount(len(x))
+	var terms []autofunc.Result
+	for _, sample := range autofunc.Split(n, a) {
+		terms = append(terms, autofunc.Pool((&LogSoftmaxLayer{}).Apply(sample),
+			func(logProbs autofunc.Result) autofunc.Result {
+				if f.Gamma == 0 {
+					return logProbs
+				}
+				return autofunc.Mul(applyElementwise(logProbs, f.focus), logProbs)
+			}))
+	}
+	return DotCost{}.Cost(f.weightTargets(x, n), autofunc.Concat(terms...))
+}
+
+func (f FocalCost) CostR(v autofunc.RVector, x linalg.Vector,
+	a autofunc.RResult) autofunc.RResult {
+	n := SoftmaxCECost{OutputSize: f.OutputSize}.sampleCount(len(x))
+	var terms []autofunc.RResult
+	for _, sample := range autofunc.SplitR(n, a) {
+		terms = append(terms, autofunc.PoolR((&LogSoftmaxLayer{}).ApplyR(v, sample),
+			func(logProbs autofunc.RResult) autofunc.RResult {
+				if f.Gamma == 0 {
+					return logProbs
+				}
+				return autofunc.MulR(applyElementwiseR(logProbs, f.focusR), logProbs)
+			}))
+	}
+	return DotCost{}.CostR(v, f.weightTargets(x, n), autofunc.ConcatR(terms...))
+}
+
+// focus computes the focusing term (1-p)^Gamma and its
+// derivative from a log probability x.
+func (f FocalCost) focus(x float64) (y, dy float64) {
+	y, dy, _ = f.focusR(x)
+	return
+}
+
+func (f FocalCost) focusR(x float64) (y, dy, ddy float64) {
+	q := -math.Expm1(x)
+	if q < focalEpsilon {
+		return math.Pow(focalEpsilon, f.Gamma), 0, 0
+	}
+	p := math.Exp(x)
+	y = math.Pow(q, f.Gamma)
+	dy = -f.Gamma * p * math.Pow(q, f.Gamma-1)
+	ddy = dy + f.Gamma*(f.Gamma-1)*p*p*math.Pow(q, f.Gamma-2)
+	return
+}
+
+// weightTargets scales a batch of n expected outputs by
+// the class weights.
+func (f FocalCost) weightTargets(x linalg.Vector, n int) linalg.Vector {
+	if f.Alpha == nil {
+		return x
+	}
+	numClasses := len(x) / n
+	if len(f.Alpha) != numClasses {
+		panic("alpha count must match class count")
+	}
+	res := make(linalg.Vector, len(x))
+	for i, val := range x {
+		res[i] = val * f.Alpha[i%numClasses]
+	}
+	return res
+}
+
+// HuberCost implements the Huber loss, which is
+// quadratic for small differences between actual and
+// expected values and linear for large ones.
+// This makes it less sensitive to outliers than
+// MeanSquaredCost.
+//
+// For each difference d, the cost is d^2/2 if
+// |d| <= Delta, or Delta*(|d|-Delta/2) otherwise.
+// Thus, the magnitude of each partial derivative is
+// at most Delta.
+type HuberCost struct {
+	// Delta is the threshold above which the cost
+	// becomes linear.
+	// If it is 0, a default of 1 is used.
+	Delta float64
+}
+
+func (h HuberCost) Cost(x linalg.Vector, a autofunc.Result) autofunc.Result {
+	xVar := &autofunc.Variable{Vector: x.Copy().Scale(-1)}
+	diff := autofunc.Add(xVar, a)
+	delta := h.delta()
+	return autofunc.SumAll(applyElementwise(diff, func(d float64) (float64, float64) {
+		y, dy, _ := huberLoss(delta, d)
+		return y, dy
+	}))
+}
+
+func (h HuberCost) CostR(v autofunc.RVector, x linalg.Vector,
+	a autofunc.RResult) autofunc.RResult {
+	xVar := autofunc.NewRVariable(&autofunc.Variable{Vector: x.Copy().Scale(-1)}, v)
+	diff := autofunc.AddR(xVar, a)
+	delta := h.delta()
+	return autofunc.SumAllR(applyElementwiseR(diff, func(d float64) (float64, float64, float64) {
+		return huberLoss(delta, d)
+	}))
+}
+
+func (h HuberCost) delta() float64 {
+	if h.Delta == 0 {
+		return 1
+	}
+	return h.Delta
+}
+
+func huberLoss(delta, d float64) (y, dy, ddy float64) {
+	if d > delta {
+		return delta * (d - delta/2), delta, 0
+	} else if d < -delta {
+		return delta * (-d - delta/2), -delta, 0
+	}
+	return d * d / 2, d, 1
+}
+
+// HingeCost implements the hinge loss used to train
+// margin-based (SVM-style) classifiers.
+//
+// Every component of an expected output must be either
+// 1 or -1, indicating whether or not the sample belongs
+// to the corresponding class.
+// Thus, binary classifiers have a single output, while
+// multi-class classifiers have one output per class and
+// are trained one-vs-rest.
+// One-hot targets x can be converted to this encoding
+// with 2*x-1.
+//
+// For each output f with expected value y, the cost is
+// max(0, 1-y*f).
+// Outputs exactly on the margin (y*f = 1) are given a
+// subgradient of 0.
+type HingeCost struct{}
+
+func (_ HingeCost) Cost(x linalg.Vector, a autofunc.Result) autofunc.Result {
+	margins := autofunc.Mul(&autofunc.Variable{Vector: x}, a)
+	return autofunc.SumAll(applyElementwise(margins, func(m float64) (float64, float64) {
+		y, dy, _ := hingeLoss(m)
+		return y, dy
+	}))
+}
+
+func (_ HingeCost) CostR(v autofunc.RVector, x linalg.Vector,
+	a autofunc.RResult) autofunc.RResult {
+	xVar := autofunc.NewRVariable(&autofunc.Variable{Vector: x}, v)
+	margins := autofunc.MulR(xVar, a)
+	return autofunc.SumAllR(applyElementwiseR(margins, hingeLoss))
+}
+
+func hingeLoss(m float64) (y, dy, ddy float64) {
+	if m < 1 {
+		return 1 - m, -1, 0
+	}
+	return 0, 0, 0
+}
+
 // RegularizingCost adds onto another cost function
 // the squared magnitudes of various variables.
 type RegularizingCost struct {
@@ -74,3 +74,386 @@ func TestTotalCostBatcher(t *testing.T) {
 		}
 	}
 }
+
+type softmaxCETestFunc struct {
+	Cost     SoftmaxCECost
+	Expected linalg.Vector
+}
+
+func (s softmaxCETestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return s.Cost.Cost(s.Expected, in)
+}
+
+func (s softmaxCETestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return s.Cost.CostR(v, s.Expected, in)
+}
+
+func TestSoftmaxCECostGradient(t *testing.T) {
+	actual := &autofunc.Variable{make(linalg.Vector, 12)}
+	expected := make(linalg.Vector, len(actual.Vector))
+	rVector := autofunc.RVector{actual: make(linalg.Vector, len(expected))}
+	for i := range expected {
+		expected[i] = rand.Float64()
+		actual.Vector[i] = rand.NormFloat64()
+		rVector[actual][i] = rand.NormFloat64()
+	}
+	for _, cost := range []SoftmaxCECost{{}, {OutputSize: 4}, {OutputSize: 4, Smoothing: 0.2}} {
+		f := softmaxCETestFunc{cost, expected}
+		funcTest := &functest.RFuncChecker{
+			F:     f,
+			Vars:  []*autofunc.Variable{actual},
+			Input: actual,
+			RV:    rVector,
+		}
+		funcTest.FullCheck(t)
+	}
+}
+
+func TestSoftmaxCECostLargeInputs(t *testing.T) {
+	actual := &autofunc.Variable{Vector: linalg.Vector{1000, -1000, 0}}
+	expected := linalg.Vector{0, 1, 0}
+	cost := SoftmaxCECost{}.Cost(expected, actual)
+	if val := cost.Output()[0]; math.Abs(val-2000) > 1e-8 {
+		t.Errorf("expected cost 2000 but got %f", val)
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{actual})
+	cost.PropagateGradient(linalg.Vector{1}, grad)
+	expectedGrad := linalg.Vector{1, -1, 0}
+	for i, x := range expectedGrad {
+		if math.Abs(grad[actual][i]-x) > 1e-8 {
+			t.Errorf("partial %d: expected %f but got %f", i, x, grad[actual][i])
+		}
+	}
+}
+
+func TestSoftmaxCECostBatch(t *testing.T) {
+	samples := []linalg.Vector{{1, 2, -1}, {0.5, -3, 2}}
+	targets := []linalg.Vector{{0, 0, 1}, {1, 0, 0}}
+	var expected float64
+	for i, sample := range samples {
+		out := SoftmaxCECost{}.Cost(targets[i], &autofunc.Variable{Vector: sample})
+		expected += out.Output()[0]
+	}
+	joinedInput := &autofunc.Variable{Vector: append(samples[0].Copy(), samples[1]...)}
+	joinedTarget := append(targets[0].Copy(), targets[1]...)
+	actual := SoftmaxCECost{OutputSize: 3}.Cost(joinedTarget, joinedInput).Output()[0]
+	if math.Abs(actual-expected) > 1e-8 {
+		t.Errorf("expected %f but got %f", expected, actual)
+	}
+}
+
+func TestSoftmaxCECostSmoothing(t *testing.T) {
+	actual := &autofunc.Variable{Vector: linalg.Vector{1, 2, -1, 0.5, -3, 2}}
+	target := linalg.Vector{0, 0, 1, 1, 0, 0}
+	cost := SoftmaxCECost{OutputSize: 3, Smoothing: 0.1}.Cost(target, actual)
+	grad := autofunc.NewGradient([]*autofunc.Variable{actual})
+	cost.PropagateGradient(linalg.Vector{1}, grad)
+
+	smoothed := linalg.Vector{0.05, 0.05, 0.9, 0.9, 0.05, 0.05}
+	expectedCost := SoftmaxCECost{OutputSize: 3}.Cost(smoothed, actual).Output()[0]
+	if val := cost.Output()[0]; math.Abs(val-expectedCost) > 1e-8 {
+		t.Errorf("expected cost %f but got %f", expectedCost, val)
+	}
+	for sample := 0; sample < 2; sample++ {
+		probs := actual.Vector[sample*3 : (sample+1)*3].Copy()
+		var sum float64
+		for i, x := range probs {
+			probs[i] = math.Exp(x)
+			sum += probs[i]
+		}
+		for i, p := range probs {
+			idx := sample*3 + i
+			expected := p/sum - smoothed[idx]
+			if math.Abs(grad[actual][idx]-expected) > 1e-8 {
+				t.Errorf("partial %d: expected %f but got %f", idx, expected,
+					grad[actual][idx])
+			}
+		}
+	}
+}
+
+type klDivTestFunc struct {
+	Cost     KLDivCost
+	Expected linalg.Vector
+}
+
+func (k klDivTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return k.Cost.Cost(k.Expected, in)
+}
+
+func (k klDivTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return k.Cost.CostR(v, k.Expected, in)
+}
+
+func TestKLDivCostOutput(t *testing.T) {
+	teacher := linalg.Vector{0.7, 0.2, 0.1, 0.25, 0.25, 0.5}
+	actual := &autofunc.Variable{Vector: linalg.Vector{1, 2, -1, 0.5, -3, 2}}
+	cost := KLDivCost{OutputSize: 3, Temperature: 2}
+
+	var expected float64
+	for sample := 0; sample < 2; sample++ {
+		logits := actual.Vector[sample*3 : (sample+1)*3]
+		var sum float64
+		for _, x := range logits {
+			sum += math.Exp(x / 2)
+		}
+		for i, x := range logits {
+			p := teacher[sample*3+i]
+			q := math.Exp(x/2) / sum
+			expected += 4 * p * math.Log(p/q)
+		}
+	}
+	if val := cost.Cost(teacher, actual).Output()[0]; math.Abs(val-expected) > 1e-8 {
+		t.Errorf("expected cost %f but got %f", expected, val)
+	}
+
+	// With matching distributions, the divergence and its
+	// gradient should vanish.
+	matching := &autofunc.Variable{Vector: linalg.Vector{
+		2 * math.Log(0.7), 2 * math.Log(0.2), 2 * math.Log(0.1),
+		2 * math.Log(0.25), 2 * math.Log(0.25), 2 * math.Log(0.5),
+	}}
+	out := cost.Cost(teacher, matching)
+	if val := out.Output()[0]; math.Abs(val) > 1e-8 {
+		t.Errorf("expected cost 0 for matching distributions but got %f", val)
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{matching})
+	out.PropagateGradient(linalg.Vector{1}, grad)
+	if mag := grad[matching].MaxAbs(); mag > 1e-8 {
+		t.Errorf("expected zero gradient for matching distributions but got %v",
+			grad[matching])
+	}
+}
+
+func TestKLDivCostGradient(t *testing.T) {
+	actual := &autofunc.Variable{make(linalg.Vector, 12)}
+	expected := make(linalg.Vector, len(actual.Vector))
+	rVector := autofunc.RVector{actual: make(linalg.Vector, len(expected))}
+	for i := range expected {
+		expected[i] = rand.Float64()
+		actual.Vector[i] = rand.NormFloat64()
+		rVector[actual][i] = rand.NormFloat64()
+	}
+	for i := 0; i < len(expected); i += 4 {
+		sample := expected[i : i+4]
+		sample.Scale(1 / (sample[0] + sample[1] + sample[2] + sample[3]))
+	}
+	for _, cost := range []KLDivCost{{OutputSize: 4}, {OutputSize: 4, Temperature: 3}} {
+		funcTest := &functest.RFuncChecker{
+			F:     klDivTestFunc{cost, expected},
+			Vars:  []*autofunc.Variable{actual},
+			Input: actual,
+			RV:    rVector,
+		}
+		funcTest.FullCheck(t)
+	}
+}
+
+type focalTestFunc struct {
+	Cost     FocalCost
+	Expected linalg.Vector
+}
+
+func (f focalTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return f.Cost.Cost(f.Expected, in)
+}
+
+func (f focalTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return f.Cost.CostR(v, f.Expected, in)
+}
+
+func TestFocalCostOutput(t *testing.T) {
+	actual := &autofunc.Variable{Vector: linalg.Vector{1, 2, -1, 0.5, -3, 2}}
+	target := linalg.Vector{0, 0, 1, 1, 0, 0}
+	cost := FocalCost{OutputSize: 3, Gamma: 2, Alpha: linalg.Vector{0.25, 1, 0.5}}
+	var expected float64
+	for _, idx := range []int{2, 3} {
+		sample := actual.Vector[idx/3*3 : idx/3*3+3]
+		var sum float64
+		for _, x := range sample {
+			sum += math.Exp(x)
+		}
+		p := math.Exp(actual.Vector[idx]) / sum
+		expected -= cost.Alpha[idx%3] * math.Pow(1-p, 2) * math.Log(p)
+	}
+	if val := cost.Cost(target, actual).Output()[0]; math.Abs(val-expected) > 1e-8 {
+		t.Errorf("expected cost %f but got %f", expected, val)
+	}
+
+	ceCost := SoftmaxCECost{OutputSize: 3}.Cost(target, actual).Output()[0]
+	focalCost := FocalCost{OutputSize: 3}.Cost(target, actual).Output()[0]
+	if math.Abs(ceCost-focalCost) > 1e-8 {
+		t.Errorf("with gamma 0, expected cost %f but got %f", ceCost, focalCost)
+	}
+}
+
+func TestFocalCostGradient(t *testing.T) {
+	actual := &autofunc.Variable{make(linalg.Vector, 12)}
+	expected := make(linalg.Vector, len(actual.Vector))
+	rVector := autofunc.RVector{actual: make(linalg.Vector, len(expected))}
+	for i := range expected {
+		actual.Vector[i] = rand.NormFloat64()
+		rVector[actual][i] = rand.NormFloat64()
+	}
+	for i := 0; i < 3; i++ {
+		expected[i*4+rand.Intn(4)] = 1
+	}
+	costs := []FocalCost{
+		{OutputSize: 4, Gamma: 2},
+		{OutputSize: 4, Gamma: 0.5, Alpha: linalg.Vector{0.25, 1, 2, 0.5}},
+	}
+	for _, cost := range costs {
+		funcTest := &functest.RFuncChecker{
+			F:     focalTestFunc{cost, expected},
+			Vars:  []*autofunc.Variable{actual},
+			Input: actual,
+			RV:    rVector,
+		}
+		funcTest.FullCheck(t)
+	}
+}
+
+func TestFocalCostConfident(t *testing.T) {
+	target := linalg.Vector{1, 0, 0}
+	for _, gamma := range []float64{0.5, 2} {
+		cost := FocalCost{Gamma: gamma}
+		in := &autofunc.Variable{Vector: linalg.Vector{40, 0, 0}}
+		rVec := autofunc.RVector{in: linalg.Vector{1, -1, 0.5}}
+		grad := autofunc.NewGradient([]*autofunc.Variable{in})
+		rGrad := autofunc.NewRGradient([]*autofunc.Variable{in})
+		cost.Cost(target, in).PropagateGradient(linalg.Vector{1}, grad)
+		cost.CostR(rVec, target, autofunc.NewRVariable(in, rVec)).PropagateRGradient(
+			linalg.Vector{1}, linalg.Vector{0}, rGrad, grad)
+		for _, vec := range []linalg.Vector{grad[in], rGrad[in]} {
+			for _, x := range vec {
+				if math.IsNaN(x) || math.IsInf(x, 0) {
+					t.Errorf("gamma %f: invalid gradient %v", gamma, vec)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestFocalCostEasyExamples(t *testing.T) {
+	target := linalg.Vector{1, 0, 0}
+	easy := linalg.Vector{4, 0, 0}
+	hard := linalg.Vector{0, 2, 0}
+	gradMag := func(c CostFunc, logits linalg.Vector) float64 {
+		in := &autofunc.Variable{Vector: logits}
+		grad := autofunc.NewGradient([]*autofunc.Variable{in})
+		c.Cost(target, in).PropagateGradient(linalg.Vector{1}, grad)
+		return grad[in].Mag()
+	}
+	ce := SoftmaxCECost{}
+	focal := FocalCost{Gamma: 2}
+
+	// The focusing term should shrink the gradient of the
+	// easy example much more than that of the hard one.
+	ceRatio := gradMag(ce, easy) / gradMag(ce, hard)
+	focalRatio := gradMag(focal, easy) / gradMag(focal, hard)
+	if focalRatio > ceRatio/10 {
+		t.Errorf("easy/hard gradient ratio is %f for focal loss and %f for cross entropy",
+			focalRatio, ceRatio)
+	}
+	if gradMag(focal, easy) >= gradMag(ce, easy) {
+		t.Error("focal loss should down-weight the easy example")
+	}
+}
+
+type huberTestFunc struct {
+	Cost     HuberCost
+	Expected linalg.Vector
+}
+
+func (h huberTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return h.Cost.Cost(h.Expected, in)
+}
+
+func (h huberTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return h.Cost.CostR(v, h.Expected, in)
+}
+
+func TestHuberCostOutput(t *testing.T) {
+	actual := &autofunc.Variable{Vector: linalg.Vector{0.5, 3, -4, 1}}
+	expected := linalg.Vector{0, 0, 0, 1.5}
+	cost := HuberCost{Delta: 2}.Cost(expected, actual)
+	expectedCost := 0.125 + 2*(3-1) + 2*(4-1) + 0.125
+	if val := cost.Output()[0]; math.Abs(val-expectedCost) > 1e-8 {
+		t.Errorf("expected cost %f but got %f", expectedCost, val)
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{actual})
+	cost.PropagateGradient(linalg.Vector{1}, grad)
+	expectedGrad := linalg.Vector{0.5, 2, -2, -0.5}
+	for i, x := range expectedGrad {
+		if math.Abs(grad[actual][i]-x) > 1e-8 {
+			t.Errorf("partial %d: expected %f but got %f", i, x, grad[actual][i])
+		}
+	}
+}
+
+func TestHuberCostGradient(t *testing.T) {
+	actual := &autofunc.Variable{make(linalg.Vector, 10)}
+	expected := make(linalg.Vector, len(actual.Vector))
+	rVector := autofunc.RVector{actual: make(linalg.Vector, len(expected))}
+	for i := range expected {
+		expected[i] = rand.NormFloat64()
+		actual.Vector[i] = rand.NormFloat64()
+		rVector[actual][i] = rand.NormFloat64()
+	}
+	funcTest := &functest.RFuncChecker{
+		F:     huberTestFunc{HuberCost{Delta: 0.7}, expected},
+		Vars:  []*autofunc.Variable{actual},
+		Input: actual,
+		RV:    rVector,
+	}
+	funcTest.FullCheck(t)
+}
+
+func TestHingeCost(t *testing.T) {
+	actual := &autofunc.Variable{Vector: linalg.Vector{0.5, -2, 1, 3, -0.2}}
+	expected := linalg.Vector{1, 1, 1, -1, -1}
+	cost := HingeCost{}.Cost(expected, actual)
+	expectedCost := 0.5 + 3 + 0 + 4 + 0.8
+	if val := cost.Output()[0]; math.Abs(val-expectedCost) > 1e-8 {
+		t.Errorf("expected cost %f but got %f", expectedCost, val)
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{actual})
+	cost.PropagateGradient(linalg.Vector{1}, grad)
+	expectedGrad := linalg.Vector{-1, -1, 0, 1, 1}
+	for i, x := range expectedGrad {
+		if math.Abs(grad[actual][i]-x) > 1e-8 {
+			t.Errorf("partial %d: expected %f but got %f", i, x, grad[actual][i])
+		}
+	}
+
+	rVar := autofunc.NewRVariable(actual, autofunc.RVector{})
+	costR := HingeCost{}.CostR(autofunc.RVector{}, expected, rVar)
+	if val := costR.Output()[0]; math.Abs(val-expectedCost) > 1e-8 {
+		t.Errorf("expected r-cost %f but got %f", expectedCost, val)
+	}
+}
+
+func TestNLLCost(t *testing.T) {
+	logProbs := &autofunc.Variable{Vector: []float64{
+		math.Log(0.2), math.Log(0.5), math.Log(0.3),
+		math.Log(0.6), math.Log(0.3), math.Log(0.1),
+	}}
+	expected := -math.Log(0.5) - math.Log(0.6)
+	targets := []linalg.Vector{{0, 1, 0, 1, 0, 0}, {1, 0}}
+	for _, target := range targets {
+		cost := NLLCost{}.Cost(target, logProbs).Output()[0]
+		if math.Abs(cost-expected) > 1e-8 {
+			t.Errorf("targets %v: expected cost %f but got %f", target, expected, cost)
+		}
+		grad := autofunc.NewGradient([]*autofunc.Variable{logProbs})
+		NLLCost{}.Cost(target, logProbs).PropagateGradient([]float64{1}, grad)
+		expGrad := []float64{0, -1, 0, -1, 0, 0}
+		for i, x := range expGrad {
+			if grad[logProbs][i] != x {
+				t.Errorf("targets %v: gradient %d should be %f but got %f", target, i,
+					x, grad[logProbs][i])
+			}
+		}
+	}
+}
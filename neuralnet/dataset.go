@@ -0,0 +1,113 @@
+package neuralnet
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+// A Dataset is a list of input vectors and their
+// corresponding target vectors.
+//
+// A *Dataset is an sgd.SampleSet whose samples are
+// VectorSamples, so it can be used directly with
+// sgd.SGD, BatchRGradienter, Trainer, etc.
+type Dataset struct {
+	Inputs  []linalg.Vector
+	Targets []linalg.Vector
+}
+
+// NewDataset creates a Dataset from inputs and targets.
+// It panics if the two slices have different lengths.
+func NewDataset(inputs, targets []linalg.Vector) *Dataset {
+	if len(inputs) != len(targets) {
+		panic("input and target counts do not match")
+	}
+	return &Dataset{Inputs: inputs, Targets: targets}
+}
+
+// Len returns the number of samples.
+func (d *Dataset) Len() int {
+	return len(d.Inputs)
+}
+
+// Copy creates a shallow copy of the dataset.
+func (d *Dataset) Copy() sgd.SampleSet {
+	res := &Dataset{
+		Inputs:  make([]linalg.Vector, len(d.Inputs)),
+		Targets: make([]linalg.Vector, len(d.Targets)),
+	}
+	copy(res.Inputs, d.Inputs)
+	copy(res.Targets, d.Targets)
+	return res
+}
+
+// Swap swaps two samples.
+func (d *Dataset) Swap(i, j int) {
+	d.Inputs[i], d.Inputs[j] = d.Inputs[j], d.Inputs[i]
+	d.Targets[i], d.Targets[j] = d.Targets[j], d.Targets[i]
+}
+
+// GetSample returns the VectorSample at an index.
+func (d *Dataset) GetSample(idx int) interface{} {
+	return VectorSample{Input: d.Inputs[idx], Output: d.Targets[idx]}
+}
+
+// Subset returns a *Dataset which shares the samples
+// from the start index to the end index.
+func (d *Dataset) Subset(start, end int) sgd.SampleSet {
+	return d.Slice(start, end)
+}
+
+// Slice is like Subset, but it returns a *Dataset.
+func (d *Dataset) Slice(start, end int) *Dataset {
+	return &Dataset{Inputs: d.Inputs[start:end], Targets: d.Targets[start:end]}
+}
+
+// Shuffle randomly reorders the samples in place.
+// If r is nil, the global math/rand source is used.
+func (d *Dataset) Shuffle(r *rand.Rand) {
+	intn := rand.Intn
+	if r != nil {
+		intn = r.Intn
+	}
+	for i := d.Len() - 1; i > 0; i-- {
+		d.Swap(i, intn(i+1))
+	}
+}
+
+// Batches splits the dataset into consecutive batches
+// of the given size.
+// If the number of samples is not divisible by the size,
+// the last batch is smaller than the others, unless
+// dropLast is true, in which case it is omitted.
+func (d *Dataset) Batches(size int, dropLast bool) []*Dataset {
+	if size <= 0 {
+		panic("batch size must be positive")
+	}
+	var res []*Dataset
+	for i := 0; i < d.Len(); i += size {
+		end := i + size
+		if end > d.Len() {
+			if dropLast {
+				break
+			}
+			end = d.Len()
+		}
+		res = append(res, d.Slice(i, end))
+	}
+	return res
+}
+
+// Split divides the dataset into two parts, the first
+// of which contains the given fraction of the samples.
+// The parts share the dataset's samples, so the dataset
+// should be shuffled first if its order is meaningful.
+func (d *Dataset) Split(ratio float64) (first, second *Dataset) {
+	if ratio < 0 || ratio > 1 {
+		panic("split ratio must be between 0 and 1")
+	}
+	idx := int(ratio*float64(d.Len()) + 0.5)
+	return d.Slice(0, idx), d.Slice(idx, d.Len())
+}
@@ -0,0 +1,123 @@
+package neuralnet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// CSVOptions describes how to turn the columns of a CSV
+// file into the samples of a Dataset.
+type CSVOptions struct {
+	// LabelColumn is the index of the column which holds
+	// the target of each sample.
+	LabelColumn int
+
+	// FeatureColumns are the indices of the columns which
+	// make up each input vector, in order.
+	// If it is nil, every column except LabelColumn is
+	// used.
+	FeatureColumns []int
+
+	// Header is true if the first row contains column
+	// names rather than a sample.
+	Header bool
+
+	// LabelClasses, if non-zero, is the number of classes
+	// in a classification problem.
+	// Labels must then be integers in [0, LabelClasses),
+	// and targets are their one-hot vectors.
+	// If it is 0, each target is a vector containing the
+	// label value.
+	LabelClasses int
+}
+
+// LoadCSV reads a Dataset from CSV data.
+// Malformed rows produce errors which report their line
+// numbers.
+// If opts is nil, the label is the first column and
+// every other column is a feature.
+func LoadCSV(r io.Reader, opts *CSVOptions) (*Dataset, error) {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	reader := csv.NewReader(r)
+	res := &Dataset{}
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if first && opts.Header {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		input, target, err := opts.parseRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		res.Inputs = append(res.Inputs, input)
+		res.Targets = append(res.Targets, target)
+	}
+	return res, nil
+}
+
+// LoadCSVFile is like LoadCSV, but it reads from a file.
+func LoadCSVFile(path string, opts *CSVOptions) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadCSV(f, opts)
+}
+
+func (c *CSVOptions) parseRecord(record []string) (input, target linalg.Vector,
+	err error) {
+	columns := c.FeatureColumns
+	if columns == nil {
+		for i := range record {
+			if i != c.LabelColumn {
+				columns = append(columns, i)
+			}
+		}
+	}
+	input = make(linalg.Vector, len(columns))
+	for i, col := range columns {
+		input[i], err = parseCSVField(record, col)
+		if err != nil {
+			return
+		}
+	}
+
+	label, err := parseCSVField(record, c.LabelColumn)
+	if err != nil {
+		return
+	}
+	if c.LabelClasses == 0 {
+		return input, linalg.Vector{label}, nil
+	}
+	if label != math.Floor(label) || label < 0 || label >= float64(c.LabelClasses) {
+		return nil, nil, fmt.Errorf("invalid class label: %v", label)
+	}
+	return input, OneHot(int(label), c.LabelClasses), nil
+}
+
+func parseCSVField(record []string, col int) (float64, error) {
+	if col < 0 || col >= len(record) {
+		return 0, fmt.Errorf("missing column %d (row has %d columns)", col, len(record))
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(record[col]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("column %d: invalid number %q", col, record[col])
+	}
+	return x, nil
+}
@@ -0,0 +1,62 @@
+package neuralnet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestLoadCSV(t *testing.T) {
+	data := "a,label,b,c\n1.5,2,3,-1\n0, 0 ,1e2,7\n"
+	ds, err := LoadCSV(strings.NewReader(data), &CSVOptions{
+		LabelColumn:    1,
+		FeatureColumns: []int{3, 0},
+		Header:         true,
+		LabelClasses:   3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Dataset{
+		Inputs:  []linalg.Vector{{-1, 1.5}, {7, 0}},
+		Targets: []linalg.Vector{{0, 0, 1}, {1, 0, 0}},
+	}
+	if !reflect.DeepEqual(ds, expected) {
+		t.Errorf("expected %v but got %v", expected, ds)
+	}
+
+	ds, err = LoadCSV(strings.NewReader("1,2,3\n4,5,6\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = &Dataset{
+		Inputs:  []linalg.Vector{{2, 3}, {5, 6}},
+		Targets: []linalg.Vector{{1}, {4}},
+	}
+	if !reflect.DeepEqual(ds, expected) {
+		t.Errorf("expected %v but got %v", expected, ds)
+	}
+}
+
+func TestLoadCSVErrors(t *testing.T) {
+	cases := []struct {
+		Data string
+		Opts *CSVOptions
+		Line string
+	}{
+		{"x,y\n1,2\n3,abc\n", &CSVOptions{Header: true}, "line 3"},
+		{"1,0\n3,1\n5,1.5\n", &CSVOptions{LabelColumn: 1, LabelClasses: 2}, "line 3"},
+		{"1,2\n3,4\n", &CSVOptions{FeatureColumns: []int{0, 2}, LabelColumn: 1}, "line 1"},
+		{"1,2\n3,4,5\n", nil, "line 2"},
+	}
+	for _, c := range cases {
+		_, err := LoadCSV(strings.NewReader(c.Data), c.Opts)
+		if err == nil {
+			t.Errorf("expected error for %q", c.Data)
+		} else if !strings.Contains(err.Error(), c.Line) {
+			t.Errorf("error for %q should mention %s: %s", c.Data, c.Line, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package neuralnet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// These are the magic numbers of unsigned byte IDX
+// files, such as the ones which make up MNIST.
+const (
+	idxImagesMagic uint32 = 0x00000803
+	idxLabelsMagic uint32 = 0x00000801
+)
+
+// ReadIDXImages reads images from an IDX file, like the
+// MNIST image files.
+//
+// Each image is flattened in row-major order and its
+// pixels are scaled to the range [0, 1].
+// This is the layout that a ConvLayer with an input
+// depth of 1 expects.
+// The width and height of the images are returned as
+// well.
+func ReadIDXImages(r io.Reader) (images []linalg.Vector, width, height int,
+	err error) {
+	reader := bufio.NewReader(r)
+	header, err := readIDXHeader(reader, idxImagesMagic, 3)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	count, height, width := header[0], header[1], header[2]
+	if height != 0 && width > math.MaxInt32/height {
+		return nil, 0, 0, errors.New("read IDX images: images are too large")
+	}
+
+	// The header is not trusted to allocate memory, since
+	// a corrupt file could claim to hold far more data
+	// than it does.
+	var pixels []byte
+	for i := 0; i < count; i++ {
+		if pixels == nil {
+			pixels, err = readIDXData(reader, width*height)
+		} else {
+			_, err = io.ReadFull(reader, pixels)
+		}
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("read IDX images: image %d: %s", i, err)
+		}
+		img := make(linalg.Vector, len(pixels))
+		for j, p := range pixels {
+			img[j] = float64(p) / 0xff
+		}
+		images = append(images, img)
+	}
+	return images, width, height, nil
+}
+
+// ReadIDXLabels reads labels from an IDX file, like the
+// MNIST label files.
+func ReadIDXLabels(r io.Reader) ([]int, error) {
+	reader := bufio.NewReader(r)
+	header, err := readIDXHeader(reader, idxLabelsMagic, 1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readIDXData(reader, header[0])
+	if err != nil {
+		return nil, fmt.Errorf("read IDX labels: %s", err)
+	}
+	labels := make([]int, len(data))
+	for i, x := range data {
+		labels[i] = int(x)
+	}
+	return labels, nil
+}
+
+// LoadIDXImages is like ReadIDXImages, but it reads
+// from a file.
+func LoadIDXImages(path string) (images []linalg.Vector, width, height int,
+	err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+	return ReadIDXImages(f)
+}
+
+// LoadIDXLabels is like ReadIDXLabels, but it reads from
+// a file.
+func LoadIDXLabels(path string) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadIDXLabels(f)
+}
+
+// LoadIDXDataset creates a Dataset from an IDX image
+// file and the corresponding IDX label file.
+// The targets are one-hot vectors with numClasses
+// components (e.g. 10 for MNIST).
+func LoadIDXDataset(imagesPath, labelsPath string, numClasses int) (*Dataset, error) {
+	images, _, _, err := LoadIDXImages(imagesPath)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := LoadIDXLabels(labelsPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) != len(labels) {
+		return nil, fmt.Errorf("load IDX dataset: %d images but %d labels",
+			len(images), len(labels))
+	}
+	for i, label := range labels {
+		if label >= numClasses {
+			return nil, fmt.Errorf("load IDX dataset: label %d is %d, but there are "+
+				"%d classes", i, label, numClasses)
+		}
+	}
+	return NewDataset(images, OneHotBatch(labels, numClasses)), nil
+}
+
+// readIDXHeader reads the big-endian magic number and
+// dimensions at the start of an IDX file.
+func readIDXHeader(r io.Reader, magic uint32, dims int) ([]int, error) {
+	var actualMagic uint32
+	if err := binary.Read(r, binary.BigEndian, &actualMagic); err != nil {
+		return nil, fmt.Errorf("read IDX header: %s", err)
+	}
+	if actualMagic != magic {
+		return nil, fmt.Errorf("read IDX header: bad magic number 0x%08x "+
+			"(expected 0x%08x)", actualMagic, magic)
+	}
+	sizes := make([]uint32, dims)
+	if err := binary.Read(r, binary.BigEndian, sizes); err != nil {
+		return nil, fmt.Errorf("read IDX header: %s", err)
+	}
+	res := make([]int, dims)
+	for i, size := range sizes {
+		if size > 1<<30 {
+			return nil, errors.New("read IDX header: dimension too large")
+		}
+		res[i] = int(size)
+	}
+	return res, nil
+}
+
+// readIDXData reads size bytes, growing its buffer as
+// the data arrives rather than allocating all of it up
+// front.
+func readIDXData(r io.Reader, size int) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(size)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
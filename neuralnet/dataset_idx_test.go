@@ -0,0 +1,105 @@
+package neuralnet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+var (
+	testIDXImages = []byte{
+		0, 0, 8, 3,
+		0, 0, 0, 2,
+		0, 0, 0, 1,
+		0, 0, 0, 3,
+		0, 0xff, 0x33,
+		0x66, 0, 0xff,
+	}
+	testIDXLabels = []byte{
+		0, 0, 8, 1,
+		0, 0, 0, 2,
+		7, 1,
+	}
+)
+
+func TestReadIDX(t *testing.T) {
+	images, width, height, err := ReadIDXImages(bytes.NewReader(testIDXImages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 3 || height != 1 {
+		t.Errorf("expected 3x1 images but got %dx%d", width, height)
+	}
+	expImages := []linalg.Vector{{0, 1, 0.2}, {0.4, 0, 1}}
+	if !reflect.DeepEqual(images, expImages) {
+		t.Errorf("expected images %v but got %v", expImages, images)
+	}
+
+	labels, err := ReadIDXLabels(bytes.NewReader(testIDXLabels))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expLabels := []int{7, 1}; !reflect.DeepEqual(labels, expLabels) {
+		t.Errorf("expected labels %v but got %v", expLabels, labels)
+	}
+}
+
+func TestReadIDXErrors(t *testing.T) {
+	if _, err := ReadIDXLabels(bytes.NewReader(testIDXImages)); err == nil {
+		t.Error("expected error for wrong magic number")
+	}
+	truncated := testIDXImages[:len(testIDXImages)-1]
+	if _, _, _, err := ReadIDXImages(bytes.NewReader(truncated)); err == nil {
+		t.Error("expected error for truncated images")
+	}
+	if _, err := ReadIDXLabels(bytes.NewReader(testIDXLabels[:6])); err == nil {
+		t.Error("expected error for truncated header")
+	}
+
+	// These headers claim far more data than the files
+	// contain, which must not be allocated up front.
+	huge := []byte{0, 0, 8, 3, 0x40, 0, 0, 0, 0, 0, 0x80, 0, 0, 0, 0x80, 0, 1, 2, 3}
+	if _, _, _, err := ReadIDXImages(bytes.NewReader(huge)); err == nil {
+		t.Error("expected error for truncated huge images")
+	}
+	overflow := []byte{0, 0, 8, 3, 0, 0, 0, 1, 0x40, 0, 0, 0, 0x40, 0, 0, 0}
+	if _, _, _, err := ReadIDXImages(bytes.NewReader(overflow)); err == nil {
+		t.Error("expected error for overflowing image size")
+	}
+	hugeLabels := []byte{0, 0, 8, 1, 0x40, 0, 0, 0, 1, 2, 3}
+	if _, err := ReadIDXLabels(bytes.NewReader(hugeLabels)); err == nil {
+		t.Error("expected error for truncated huge labels")
+	}
+}
+
+func TestLoadIDXDataset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "neuralnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	imagesPath := filepath.Join(dir, "images")
+	labelsPath := filepath.Join(dir, "labels")
+	if err := ioutil.WriteFile(imagesPath, testIDXImages, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(labelsPath, testIDXLabels, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := LoadIDXDataset(imagesPath, labelsPath, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.Len() != 2 || ArgMax(ds.Targets[0]) != 7 || ArgMax(ds.Targets[1]) != 1 {
+		t.Errorf("unexpected dataset: %v", ds)
+	}
+	if _, err := LoadIDXDataset(imagesPath, labelsPath, 5); err == nil {
+		t.Error("expected error for out-of-range label")
+	}
+}
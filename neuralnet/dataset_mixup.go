@@ -0,0 +1,58 @@
+package neuralnet
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// Mixup creates a new dataset by taking convex
+// combinations of random pairs of samples, as described
+// in "mixup: Beyond Empirical Risk Minimization" (Zhang
+// et al., 2017).
+//
+// Sample i of the result is lambda*x_i + (1-lambda)*x_j,
+// where x_j is a randomly chosen sample and lambda is
+// drawn from Beta(alpha, alpha) for each sample.
+// The targets are mixed with the same coefficients, so
+// one-hot targets become soft targets, which work with
+// SoftmaxCECost.
+// Smaller values of alpha put lambda closer to 0 or 1,
+// so the mixed samples stay closer to the originals.
+//
+// Mixup can be applied to an entire dataset or to each
+// batch from Batches, typically once per epoch.
+// The receiver is not modified.
+// If r is nil, the global math/rand source is used.
+//
+// Mixup panics if alpha is not positive.
+func (d *Dataset) Mixup(alpha float64, r *rand.Rand) *Dataset {
+	if alpha <= 0 {
+		panic("mixup alpha must be positive")
+	}
+	perm := rand.Perm
+	if r != nil {
+		perm = r.Perm
+	}
+	res := &Dataset{
+		Inputs:  make([]linalg.Vector, d.Len()),
+		Targets: make([]linalg.Vector, d.Len()),
+	}
+	for i, j := range perm(d.Len()) {
+		lambda := randBeta(r, alpha, alpha)
+		res.Inputs[i] = mixVectors(d.Inputs[i], d.Inputs[j], lambda)
+		res.Targets[i] = mixVectors(d.Targets[i], d.Targets[j], lambda)
+	}
+	return res
+}
+
+func mixVectors(v1, v2 linalg.Vector, lambda float64) linalg.Vector {
+	if len(v1) != len(v2) {
+		panic("mixed vectors must have the same length")
+	}
+	res := make(linalg.Vector, len(v1))
+	for i, x := range v1 {
+		res[i] = lambda*x + (1-lambda)*v2[i]
+	}
+	return res
+}
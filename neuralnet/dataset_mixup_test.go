@@ -0,0 +1,101 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestDatasetMixup(t *testing.T) {
+	var inputs, targets []linalg.Vector
+	for i := 0; i < 10; i++ {
+		inputs = append(inputs, linalg.Vector{float64(i), 1})
+		target := make(linalg.Vector, 10)
+		target[i] = 1
+		targets = append(targets, target)
+	}
+	d := NewDataset(inputs, targets)
+	mixed := d.Mixup(0.4, rand.New(rand.NewSource(1337)))
+	if mixed.Len() != d.Len() {
+		t.Fatalf("expected %d samples but got %d", d.Len(), mixed.Len())
+	}
+	for i, in := range mixed.Inputs {
+		// Since the first input component is the index of
+		// the hot target, mixing preserves this relation.
+		var expected, sum float64
+		for j, x := range mixed.Targets[i] {
+			if x < 0 {
+				t.Errorf("sample %d: negative target %f", i, x)
+			}
+			expected += x * float64(j)
+			sum += x
+		}
+		if math.Abs(sum-1) > 1e-8 {
+			t.Errorf("sample %d: targets sum to %f", i, sum)
+		}
+		if math.Abs(in[0]-expected) > 1e-8 || math.Abs(in[1]-1) > 1e-8 {
+			t.Errorf("sample %d: input %v does not match target %v", i, in,
+				mixed.Targets[i])
+		}
+	}
+	for i, in := range d.Inputs {
+		if in[0] != float64(i) || d.Targets[i][i] != 1 {
+			t.Fatalf("sample %d of the original dataset was modified", i)
+		}
+	}
+
+	mixed2 := d.Mixup(0.4, rand.New(rand.NewSource(1337)))
+	for i, in := range mixed.Inputs {
+		if in[0] != mixed2.Inputs[i][0] {
+			t.Fatalf("sample %d: mixup is not reproducible", i)
+		}
+	}
+}
+
+func TestDatasetMixupSmallAlpha(t *testing.T) {
+	var inputs, targets []linalg.Vector
+	for i := 0; i < 200; i++ {
+		inputs = append(inputs, linalg.Vector{float64(i)})
+		targets = append(targets, linalg.Vector{1, 0})
+	}
+	d := NewDataset(inputs, targets)
+	mixed := d.Mixup(0.001, rand.New(rand.NewSource(1337)))
+	for i, in := range mixed.Inputs {
+		if math.IsNaN(in[0]) || math.IsInf(in[0], 0) {
+			t.Fatalf("sample %d: invalid input %f", i, in[0])
+		}
+		if math.Abs(mixed.Targets[i][0]-1) > 1e-8 || mixed.Targets[i][1] != 0 {
+			t.Fatalf("sample %d: invalid targets %v", i, mixed.Targets[i])
+		}
+	}
+}
+
+func TestRandBeta(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	for _, params := range [][2]float64{{0.4, 0.4}, {2, 2}, {2, 5}, {0.01, 0.01}} {
+		a, b := params[0], params[1]
+		const n = 20000
+		var sum, sqSum float64
+		for i := 0; i < n; i++ {
+			x := randBeta(r, a, b)
+			if x < 0 || x > 1 {
+				t.Fatalf("Beta(%f, %f): sample %f out of range", a, b, x)
+			}
+			sum += x
+			sqSum += x * x
+		}
+		mean := sum / n
+		variance := sqSum/n - mean*mean
+		expMean := a / (a + b)
+		expVariance := a * b / ((a + b) * (a + b) * (a + b + 1))
+		if math.Abs(mean-expMean) > 0.01 {
+			t.Errorf("Beta(%f, %f): expected mean %f but got %f", a, b, expMean, mean)
+		}
+		if math.Abs(variance-expVariance) > 0.01 {
+			t.Errorf("Beta(%f, %f): expected variance %f but got %f", a, b,
+				expVariance, variance)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package neuralnet
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func datasetTestData(n int) *Dataset {
+	var inputs, targets []linalg.Vector
+	for i := 0; i < n; i++ {
+		inputs = append(inputs, linalg.Vector{float64(i)})
+		targets = append(targets, linalg.Vector{float64(-i)})
+	}
+	return NewDataset(inputs, targets)
+}
+
+func TestDatasetShuffle(t *testing.T) {
+	d1 := datasetTestData(50)
+	d2 := datasetTestData(50)
+	d1.Shuffle(rand.New(rand.NewSource(1)))
+	d2.Shuffle(rand.New(rand.NewSource(1)))
+	seen := map[float64]bool{}
+	var moved bool
+	for i, in := range d1.Inputs {
+		if d1.Targets[i][0] != -in[0] {
+			t.Fatalf("sample %d: input and target were separated", i)
+		}
+		if in[0] != d2.Inputs[i][0] {
+			t.Fatalf("sample %d: shuffle is not reproducible", i)
+		}
+		if in[0] != float64(i) {
+			moved = true
+		}
+		seen[in[0]] = true
+	}
+	if !moved {
+		t.Error("shuffle did not change the order")
+	}
+	if len(seen) != 50 {
+		t.Errorf("expected 50 distinct samples but got %d", len(seen))
+	}
+}
+
+func TestDatasetBatches(t *testing.T) {
+	d := datasetTestData(10)
+	batches := d.Batches(4, false)
+	sizes := []int{4, 4, 2}
+	if len(batches) != len(sizes) {
+		t.Fatalf("expected %d batches but got %d", len(sizes), len(batches))
+	}
+	for i, b := range batches {
+		if b.Len() != sizes[i] {
+			t.Errorf("batch %d: expected %d samples but got %d", i, sizes[i], b.Len())
+		}
+		if b.Inputs[0][0] != float64(i*4) {
+			t.Errorf("batch %d starts at wrong sample", i)
+		}
+	}
+	if n := len(d.Batches(4, true)); n != 2 {
+		t.Errorf("expected 2 batches but got %d", n)
+	}
+	if n := len(d.Batches(5, true)); n != 2 {
+		t.Errorf("expected 2 batches but got %d", n)
+	}
+}
+
+func TestDatasetSplit(t *testing.T) {
+	d := datasetTestData(10)
+	train, val := d.Split(0.75)
+	if train.Len() != 8 || val.Len() != 2 {
+		t.Fatalf("unexpected split sizes: %d, %d", train.Len(), val.Len())
+	}
+	if val.Inputs[0][0] != 8 {
+		t.Errorf("validation should start at sample 8")
+	}
+	sample := val.GetSample(1).(VectorSample)
+	if sample.Input[0] != 9 || sample.Output[0] != -9 {
+		t.Errorf("unexpected sample: %v", sample)
+	}
+}
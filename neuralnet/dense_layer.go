@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -14,19 +15,45 @@ import (
 
 var denseLayerByteOrder = binary.LittleEndian
 
-const denseLayerDataVersion byte = '2'
+// denseLayerDataVersion is the first byte of binary
+// DenseLayer data.
+// Version '2' data lacks the frozen flag, and older
+// DenseLayers were serialized as JSON objects, so their
+// data begins with '{' instead.
+const (
+	denseLayerDataVersion  byte = '3'
+	denseLayerDataVersion2 byte = '2'
+)
 
 // DenseLayer is a fully-connected layer of
 // linear perceptrons.
 // To introduce non-linearities, you may wish
 // to follow a DenseLayer with an activation
 // function like Sigmoid or ReLU.
+//
+// The forward and backward passes are matrix
+// products computed by autofunc.LinTran through
+// BLAS, rather than loops over the neurons, so
+// the layer does not split its neurons across
+// goroutines itself.
+// To use several cores for a large layer, train it
+// with a BatchRGradienter, which spreads each batch
+// over MaxGoroutines goroutines (see the
+// BenchmarkDenseLayer2048 benchmarks).
 type DenseLayer struct {
 	InputCount  int
 	OutputCount int
 
 	Weights *autofunc.LinTran
 	Biases  *autofunc.LinAdd
+
+	// Frozen is true if the layer should not be trained.
+	// See SetTrainable for details.
+	Frozen bool
+
+	// spectralVec caches the power iteration vector used
+	// by SpectralNorm.
+	spectralVec linalg.Vector
 }
 
 // NewDenseLayer creates a randomized DenseLayer with the
@@ -37,14 +64,25 @@ func NewDenseLayer(in, out int) *DenseLayer {
 	return res
 }
 
+// DeserializeDenseLayer deserializes a DenseLayer.
+// It supports both the current binary format and the
+// legacy JSON format, and it fails for data written in
+// any other format.
 func DeserializeDenseLayer(data []byte) (*DenseLayer, error) {
-	// Backwards-compatible JSON-based layer data.
-	if len(data) == 0 || data[0] != denseLayerDataVersion {
+	if len(data) == 0 {
+		return nil, errors.New("empty DenseLayer data")
+	}
+	switch data[0] {
+	case denseLayerDataVersion, denseLayerDataVersion2:
+	case '{':
+		// Backwards-compatible JSON-based layer data.
 		var d DenseLayer
 		if err := json.Unmarshal(data, &d); err != nil {
 			return nil, err
 		}
 		return &d, nil
+	default:
+		return nil, fmt.Errorf("unsupported DenseLayer data version: %q", data[0])
 	}
 
 	reader := bytes.NewBuffer(data[1:])
@@ -61,6 +99,13 @@ func DeserializeDenseLayer(data []byte) (*DenseLayer, error) {
 		InputCount:  int(inCount),
 		OutputCount: int(outCount),
 	}
+	if data[0] == denseLayerDataVersion {
+		frozen, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		res.Frozen = frozen != 0
+	}
 
 	weightCount := res.InputCount * res.OutputCount
 	biasCount := res.OutputCount
@@ -102,44 +147,222 @@ func DeserializeDenseLayer(data []byte) (*DenseLayer, error) {
 // This will create d.Weights and d.Biases if
 // they are nil.
 func (d *DenseLayer) Randomize() {
-	if d.Biases == nil {
-		d.Biases = &autofunc.LinAdd{
-			Var: &autofunc.Variable{
-				Vector: make(linalg.Vector, d.OutputCount),
-			},
-		}
-	}
-	if d.Weights == nil {
-		d.Weights = &autofunc.LinTran{
-			Rows: d.OutputCount,
-			Cols: d.InputCount,
-			Data: &autofunc.Variable{
-				Vector: make(linalg.Vector, d.OutputCount*d.InputCount),
-			},
-		}
-	}
+	d.RandomizeWithRand(nil)
+}
+
+// RandomizeWithRand is like Randomize, but it uses r as
+// its source of randomness.
+// If r is nil, the global math/rand source is used.
+func (d *DenseLayer) RandomizeWithRand(r *rand.Rand) {
+	d.allocParams()
 
 	sqrt3 := math.Sqrt(3)
 	for i := 0; i < d.OutputCount; i++ {
-		d.Biases.Var.Vector[i] = sqrt3 * ((rand.Float64() * 2) - 1)
+		d.Biases.Var.Vector[i] = sqrt3 * ((randFloat64(r) * 2) - 1)
 	}
 
 	weightCoeff := math.Sqrt(3.0 / float64(d.InputCount))
 	for i := range d.Weights.Data.Vector {
-		d.Weights.Data.Vector[i] = weightCoeff * ((rand.Float64() * 2) - 1)
+		d.Weights.Data.Vector[i] = weightCoeff * ((randFloat64(r) * 2) - 1)
+	}
+}
+
+// FanIn returns the fan-in used by the initializers,
+// which is the number of inputs to each neuron.
+func (d *DenseLayer) FanIn() int {
+	return d.InputCount
+}
+
+// FanOut returns the fan-out used by the initializers,
+// which is the number of neurons fed by each input.
+func (d *DenseLayer) FanOut() int {
+	return d.OutputCount
+}
+
+// InitXavier initializes the weights using the uniform
+// Xavier (aka Glorot) scheme, drawing each weight from
+// [-b, b] where b = sqrt(6/(FanIn()+FanOut())).
+// This works well for networks with tanh or sigmoid
+// activations.
+// The biases are set to zero.
+//
+// This will create d.Weights and d.Biases if
+// they are nil.
+func (d *DenseLayer) InitXavier() {
+	d.InitXavierWithRand(nil)
+}
+
+// InitXavierWithRand is like InitXavier, but it uses r
+// as its source of randomness.
+// If r is nil, the global math/rand source is used.
+func (d *DenseLayer) InitXavierWithRand(r *rand.Rand) {
+	d.allocParams()
+	for i := range d.Biases.Var.Vector {
+		d.Biases.Var.Vector[i] = 0
+	}
+	bound := math.Sqrt(6 / float64(d.FanIn()+d.FanOut()))
+	for i := range d.Weights.Data.Vector {
+		d.Weights.Data.Vector[i] = bound * ((randFloat64(r) * 2) - 1)
+	}
+}
+
+// InitHe initializes the weights using the He scheme,
+// drawing each weight from a normal distribution with
+// a variance of 2/FanIn().
+// This is recommended for networks with ReLU-family
+// activations.
+// The biases are set to zero.
+//
+// This will create d.Weights and d.Biases if
+// they are nil.
+func (d *DenseLayer) InitHe() {
+	d.InitHeWithRand(nil)
+}
+
+// InitHeWithRand is like InitHe, but it uses r as its
+// source of randomness.
+// If r is nil, the global math/rand source is used.
+func (d *DenseLayer) InitHeWithRand(r *rand.Rand) {
+	d.allocParams()
+	for i := range d.Biases.Var.Vector {
+		d.Biases.Var.Vector[i] = 0
+	}
+	stddev := math.Sqrt(2 / float64(d.FanIn()))
+	for i := range d.Weights.Data.Vector {
+		d.Weights.Data.Vector[i] = stddev * randNormFloat64(r)
+	}
+}
+
+// InitOrthogonal initializes the weights to a random
+// orthogonal matrix scaled by gain, which helps
+// gradients flow through deep or recurrent networks.
+// If the weight matrix is not square, its rows (when
+// d.OutputCount <= d.InputCount) or its columns
+// (otherwise) are orthonormal before being scaled.
+// The biases are set to zero.
+//
+// This will create d.Weights and d.Biases if
+// they are nil.
+func (d *DenseLayer) InitOrthogonal(gain float64) {
+	d.InitOrthogonalWithRand(gain, nil)
+}
+
+// InitOrthogonalWithRand is like InitOrthogonal, but it
+// uses r as its source of randomness.
+// If r is nil, the global math/rand source is used.
+func (d *DenseLayer) InitOrthogonalWithRand(gain float64, r *rand.Rand) {
+	d.allocParams()
+	for i := range d.Biases.Var.Vector {
+		d.Biases.Var.Vector[i] = 0
+	}
+
+	// Orthonormalize random normal vectors along the
+	// larger dimension using modified Gram-Schmidt, which
+	// is equivalent to taking Q from a QR decomposition.
+	count, size := d.OutputCount, d.InputCount
+	if count > size {
+		count, size = size, count
+	}
+	basis := make([]linalg.Vector, count)
+	for i := range basis {
+		vec := make(linalg.Vector, size)
+		for j := range vec {
+			vec[j] = randNormFloat64(r)
+		}
+		for _, prev := range basis[:i] {
+			vec.Add(prev.Copy().Scale(-prev.Dot(vec)))
+		}
+		basis[i] = vec.Scale(1 / math.Sqrt(vec.Dot(vec)))
+	}
+
+	for row := 0; row < d.OutputCount; row++ {
+		for col := 0; col < d.InputCount; col++ {
+			var w float64
+			if d.OutputCount <= d.InputCount {
+				w = basis[row][col]
+			} else {
+				w = basis[col][row]
+			}
+			d.Weights.Data.Vector[row*d.InputCount+col] = gain * w
+		}
+	}
+}
+
+// InitBiasConstant sets every bias to v, leaving the
+// weights untouched.
+// Since the other initializers also set the biases, it
+// should be called after the weights are initialized.
+// A small positive v (e.g. 0.01) can reduce the number
+// of dead units at the start of training for layers
+// followed by ReLUs.
+//
+// This will create d.Weights and d.Biases if
+// they are nil.
+func (d *DenseLayer) InitBiasConstant(v float64) {
+	d.allocParams()
+	for i := range d.Biases.Var.Vector {
+		d.Biases.Var.Vector[i] = v
 	}
 }
 
 // Parameters returns a slice with two variables.
 // The first variable contains the weight matrix.
 // The second variable contains the bias vector.
+// If the layer is frozen, Parameters returns nil.
 func (d *DenseLayer) Parameters() []*autofunc.Variable {
 	if d.Weights == nil || d.Biases == nil {
 		panic(uninitPanicMessage)
 	}
+	if d.Frozen {
+		return nil
+	}
 	return []*autofunc.Variable{d.Weights.Data, d.Biases.Var}
 }
 
+// SetTrainable freezes or unfreezes the layer.
+//
+// A frozen layer reports no parameters, so gradienters,
+// optimizers, and regularizers which operate on the
+// parameters of a Learner (e.g. a Network) leave its
+// weights and biases unchanged.
+// This is useful for transfer learning, where some
+// layers of a pre-trained network should stay fixed.
+// The frozen state is preserved by serialization.
+//
+// Layers may be frozen or unfrozen during training.
+// The optimizers in this package keep their state for
+// the parameters which remain, so a layer which is
+// unfrozen starts with fresh optimizer state.
+// However, since a SingleRGradienter caches the
+// parameters of its Learner, layers should be frozen or
+// unfrozen before such a gradienter is first used.
+func (d *DenseLayer) SetTrainable(trainable bool) {
+	d.Frozen = !trainable
+}
+
+// Trainable returns whether or not the layer is
+// trainable, i.e. whether it is not frozen.
+func (d *DenseLayer) Trainable() bool {
+	return !d.Frozen
+}
+
+// Clone creates a deep copy of the layer, with its own
+// weights and biases.
+func (d *DenseLayer) Clone() Layer {
+	if d.Weights == nil || d.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	res := &DenseLayer{
+		InputCount:  d.InputCount,
+		OutputCount: d.OutputCount,
+		Frozen:      d.Frozen,
+	}
+	res.allocParams()
+	copy(res.Weights.Data.Vector, d.Weights.Data.Vector)
+	copy(res.Biases.Var.Vector, d.Biases.Var.Vector)
+	return res
+}
+
 func (d *DenseLayer) Apply(in autofunc.Result) autofunc.Result {
 	if d.Weights == nil || d.Biases == nil {
 		panic(uninitPanicMessage)
@@ -170,18 +393,33 @@ func (d *DenseLayer) BatchR(rv autofunc.RVector, v autofunc.RResult, n int) auto
 	return biasBatcher.BatchR(rv, d.Weights.BatchR(rv, v, n), n)
 }
 
+// InputSize returns the length of each input.
+func (d *DenseLayer) InputSize() int {
+	return d.InputCount
+}
+
+// OutputSize returns the length of each output.
+func (d *DenseLayer) OutputSize() int {
+	return d.OutputCount
+}
+
 func (d *DenseLayer) Serialize() ([]byte, error) {
 	if d.Weights == nil || d.Biases == nil {
 		panic(uninitPanicMessage)
 	}
 	weightCount := d.InputCount * d.OutputCount
 	biasCount := d.OutputCount
-	b := make([]byte, 0, 17+8*(weightCount+biasCount))
+	b := make([]byte, 0, 18+8*(weightCount+biasCount))
 	resBuf := bytes.NewBuffer(b)
 
 	resBuf.WriteByte(denseLayerDataVersion)
 	binary.Write(resBuf, denseLayerByteOrder, uint64(d.InputCount))
 	binary.Write(resBuf, denseLayerByteOrder, uint64(d.OutputCount))
+	if d.Frozen {
+		resBuf.WriteByte(1)
+	} else {
+		resBuf.WriteByte(0)
+	}
 	for _, w := range d.Weights.Data.Vector {
 		binary.Write(resBuf, denseLayerByteOrder, w)
 	}
@@ -195,3 +433,22 @@ func (d *DenseLayer) Serialize() ([]byte, error) {
 func (d *DenseLayer) SerializerType() string {
 	return serializerTypeDenseLayer
 }
+
+func (d *DenseLayer) allocParams() {
+	if d.Biases == nil {
+		d.Biases = &autofunc.LinAdd{
+			Var: &autofunc.Variable{
+				Vector: make(linalg.Vector, d.OutputCount),
+			},
+		}
+	}
+	if d.Weights == nil {
+		d.Weights = &autofunc.LinTran{
+			Rows: d.OutputCount,
+			Cols: d.InputCount,
+			Data: &autofunc.Variable{
+				Vector: make(linalg.Vector, d.OutputCount*d.InputCount),
+			},
+		}
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"encoding/json"
 	"math"
 	"math/rand"
+	"reflect"
+	"runtime"
 	"testing"
 
 	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/kahan"
 	"github.com/unixpickle/num-analysis/linalg"
 	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
 )
 
 func BenchmarkDenseLayerBackProp(b *testing.B) {
@@ -40,6 +44,48 @@ func BenchmarkDenseLayerBackProp(b *testing.B) {
 	}
 }
 
+func BenchmarkDenseLayer2048Serial(b *testing.B) {
+	benchmarkDenseLayer2048(b, 1)
+}
+
+func BenchmarkDenseLayer2048Parallel(b *testing.B) {
+	benchmarkDenseLayer2048(b, runtime.GOMAXPROCS(0))
+}
+
+// benchmarkDenseLayer2048 measures the time it takes
+// to compute the gradient of a big DenseLayer on a
+// batch of samples, spread across maxGos goroutines.
+func benchmarkDenseLayer2048(b *testing.B, maxGos int) {
+	rand.Seed(123)
+	layer := NewDenseLayer(2048, 2048)
+	inputs := make([]linalg.Vector, 32)
+	outputs := make([]linalg.Vector, len(inputs))
+	for i := range inputs {
+		inputs[i] = make(linalg.Vector, 2048)
+		outputs[i] = make(linalg.Vector, 2048)
+		for j := range inputs[i] {
+			inputs[i][j] = rand.Float64()*2 - 1
+			outputs[i][j] = rand.Float64()*2 - 1
+		}
+	}
+	samples := VectorSampleSet(inputs, outputs)
+	batchSize := len(inputs) / maxGos
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	gradienter := &BatchRGradienter{
+		Learner:       Network{layer}.BatchLearner(),
+		CostFunc:      MeanSquaredCost{},
+		MaxGoroutines: maxGos,
+		MaxBatchSize:  batchSize,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gradienter.Gradient(samples)
+	}
+}
+
 func BenchmarkDenseLayerSerialization(b *testing.B) {
 	dl := &DenseLayer{
 		InputCount:  128 * 8 * 8,
@@ -119,6 +165,146 @@ func TestDenseBackward(t *testing.T) {
 	}
 }
 
+func TestDenseBatchMatchesKahan(t *testing.T) {
+	layer := NewDenseLayer(300, 50)
+	n := 3
+	input := &autofunc.Variable{Vector: make(linalg.Vector, n*layer.InputCount)}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+	}
+	actual := layer.Batch(input, n).Output()
+
+	weights := layer.Weights.Data.Vector
+	biases := layer.Biases.Var.Vector
+	for i := 0; i < n; i++ {
+		sample := input.Vector[i*layer.InputCount : (i+1)*layer.InputCount]
+		for j := 0; j < layer.OutputCount; j++ {
+			summer := kahan.NewSummer64()
+			row := weights[j*layer.InputCount : (j+1)*layer.InputCount]
+			for k, w := range row {
+				summer.Add(w * sample[k])
+			}
+			summer.Add(biases[j])
+			expected := summer.Sum()
+			a := actual[i*layer.OutputCount+j]
+			if math.Abs(a-expected) > 1e-8 {
+				t.Errorf("sample %d output %d: expected %f but got %f", i, j,
+					expected, a)
+			}
+		}
+	}
+}
+
+func TestDenseInitXavier(t *testing.T) {
+	layer := &DenseLayer{InputCount: 300, OutputCount: 100}
+	if layer.FanIn() != 300 || layer.FanOut() != 100 {
+		t.Fatalf("expected fan-in 300 and fan-out 100 but got %d and %d",
+			layer.FanIn(), layer.FanOut())
+	}
+	layer.InitXavierWithRand(rand.New(rand.NewSource(1337)))
+	bound := math.Sqrt(6.0 / 400)
+	var sqSum float64
+	for _, w := range layer.Weights.Data.Vector {
+		if math.Abs(w) > bound {
+			t.Fatalf("weight %f exceeds bound %f", w, bound)
+		}
+		sqSum += w * w
+	}
+	variance := sqSum / float64(len(layer.Weights.Data.Vector))
+	expectedVariance := 2.0 / 400
+	if math.Abs(variance-expectedVariance) > expectedVariance/10 {
+		t.Errorf("expected variance %f but got %f", expectedVariance, variance)
+	}
+	for i, b := range layer.Biases.Var.Vector {
+		if b != 0 {
+			t.Errorf("bias %d should be 0 but got %f", i, b)
+		}
+	}
+
+	weights := layer.Weights.Data.Vector.Copy()
+	layer.InitXavierWithRand(rand.New(rand.NewSource(1337)))
+	for i, w := range layer.Weights.Data.Vector {
+		if w != weights[i] {
+			t.Fatalf("weight %d differs with the same source", i)
+		}
+	}
+}
+
+func TestDenseInitHe(t *testing.T) {
+	layer := &DenseLayer{InputCount: 200, OutputCount: 100}
+	layer.InitHeWithRand(rand.New(rand.NewSource(1337)))
+	weights := layer.Weights.Data.Vector.Copy()
+
+	var sqSum float64
+	for _, w := range weights {
+		sqSum += w * w
+	}
+	variance := sqSum / float64(len(weights))
+	expectedVariance := 2.0 / 200
+	if math.Abs(variance-expectedVariance) > expectedVariance/10 {
+		t.Errorf("expected variance %f but got %f", expectedVariance, variance)
+	}
+
+	layer.InitHeWithRand(rand.New(rand.NewSource(1337)))
+	for i, w := range layer.Weights.Data.Vector {
+		if w != weights[i] {
+			t.Fatalf("weight %d differs with the same source", i)
+		}
+	}
+}
+
+func TestDenseInitOrthogonal(t *testing.T) {
+	for _, size := range [][2]int{{5, 5}, {7, 3}, {3, 7}} {
+		layer := &DenseLayer{InputCount: size[0], OutputCount: size[1]}
+		layer.InitOrthogonalWithRand(1.5, rand.New(rand.NewSource(1337)))
+		w := layer.Weights.Data.Vector
+
+		// Compute W*W^T or W^T*W, whichever is smaller.
+		n, stride, step := layer.OutputCount, 1, layer.InputCount
+		if layer.OutputCount > layer.InputCount {
+			n, stride, step = layer.InputCount, layer.InputCount, 1
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				var dot float64
+				for k := 0; k < len(w)/n; k++ {
+					dot += w[i*step+k*stride] * w[j*step+k*stride]
+				}
+				expected := 0.0
+				if i == j {
+					expected = 1.5 * 1.5
+				}
+				if math.Abs(dot-expected) > 1e-8 {
+					t.Errorf("size %v: entry %d,%d should be %f but got %f", size, i, j,
+						expected, dot)
+				}
+			}
+		}
+		for i, b := range layer.Biases.Var.Vector {
+			if b != 0 {
+				t.Errorf("size %v: bias %d should be 0 but got %f", size, i, b)
+			}
+		}
+	}
+}
+
+func TestDenseInitBiasConstant(t *testing.T) {
+	layer := &DenseLayer{InputCount: 4, OutputCount: 3}
+	layer.InitHe()
+	weights := layer.Weights.Data.Vector.Copy()
+	layer.InitBiasConstant(0.1)
+	for i, b := range layer.Biases.Var.Vector {
+		if b != 0.1 {
+			t.Errorf("bias %d should be 0.1 but got %f", i, b)
+		}
+	}
+	for i, w := range layer.Weights.Data.Vector {
+		if w != weights[i] {
+			t.Fatalf("weight %d was modified", i)
+		}
+	}
+}
+
 func TestDenseSerialize(t *testing.T) {
 	network, _, _ := denseTestInfo()
 	layer := network[0].(*DenseLayer)
@@ -163,6 +349,21 @@ func TestDenseSerialize(t *testing.T) {
 
 }
 
+func TestDenseDeserializeVersion(t *testing.T) {
+	network, _, _ := denseTestInfo()
+	data, err := network[0].Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] = denseLayerDataVersion + 1
+	if _, err := DeserializeDenseLayer(data); err == nil {
+		t.Error("expected error for unknown version")
+	}
+	if _, err := DeserializeDenseLayer(nil); err == nil {
+		t.Error("expected error for empty data")
+	}
+}
+
 func denseTestInfo() (network Network, input *autofunc.Variable, grad linalg.Vector) {
 	denseLayer := &DenseLayer{
 		InputCount:  3,
@@ -179,3 +380,104 @@ func denseTestInfo() (network Network, input *autofunc.Variable, grad linalg.Vec
 
 	return
 }
+
+func TestNetworkRandomizeWithRand(t *testing.T) {
+	makeNet := func() Network {
+		return Network{
+			&DenseLayer{InputCount: 3, OutputCount: 4},
+			&Sigmoid{},
+			&ConvLayer{
+				FilterCount:  2,
+				FilterWidth:  2,
+				FilterHeight: 1,
+				Stride:       1,
+				InputWidth:   2,
+				InputHeight:  2,
+				InputDepth:   1,
+			},
+		}
+	}
+	net1, net2 := makeNet(), makeNet()
+	net1.RandomizeWithRand(rand.New(rand.NewSource(42)))
+	net2.RandomizeWithRand(rand.New(rand.NewSource(42)))
+	params1, params2 := net1.Parameters(), net2.Parameters()
+	for i, param := range params1 {
+		for j, x := range param.Vector {
+			if x != params2[i].Vector[j] {
+				t.Fatalf("parameter %d entry %d differs", i, j)
+			}
+		}
+	}
+}
+
+func TestDenseFrozen(t *testing.T) {
+	frozen := NewDenseLayer(3, 2)
+	trainable := NewDenseLayer(2, 2)
+	frozen.SetTrainable(false)
+	if frozen.Trainable() || !trainable.Trainable() {
+		t.Fatal("unexpected Trainable() results")
+	}
+	net := Network{frozen, &Sigmoid{}, trainable}
+	if params := net.Parameters(); len(params) != 2 ||
+		params[0] != trainable.Weights.Data || params[1] != trainable.Biases.Var {
+		t.Fatal("frozen parameters should be excluded")
+	}
+	if weights := net.Weights(); len(weights) != 1 {
+		t.Errorf("expected 1 weight variable but got %d", len(weights))
+	}
+
+	oldWeights := frozen.Weights.Data.Vector.Copy()
+	oldBiases := frozen.Biases.Var.Vector.Copy()
+	oldTrainable := trainable.Weights.Data.Vector.Copy()
+	samples := VectorSampleSet([]linalg.Vector{{1, 2, 3}, {-1, 0, 1}},
+		[]linalg.Vector{{0, 1}, {1, 0}})
+	gradienter := &BatchRGradienter{
+		Learner:  net.BatchLearner(),
+		CostFunc: MeanSquaredCost{},
+	}
+	sgd.SGD(gradienter, samples, 0.1, 3, 2)
+	if !reflect.DeepEqual(oldWeights, frozen.Weights.Data.Vector) ||
+		!reflect.DeepEqual(oldBiases, frozen.Biases.Var.Vector) {
+		t.Error("frozen layer was modified")
+	}
+	if reflect.DeepEqual(oldTrainable, trainable.Weights.Data.Vector) {
+		t.Error("trainable layer was not modified")
+	}
+
+	data, err := frozen.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DeserializeDenseLayer(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Trainable() {
+		t.Error("frozen flag was not preserved")
+	}
+	if clone := frozen.Clone().(*DenseLayer); clone.Trainable() {
+		t.Error("frozen flag was not cloned")
+	}
+}
+
+func TestDenseDeserializeVersion2(t *testing.T) {
+	network, _, _ := denseTestInfo()
+	layer := network[0].(*DenseLayer)
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldData := append([]byte{denseLayerDataVersion2}, data[1:17]...)
+	oldData = append(oldData, data[18:]...)
+	decoded, err := DeserializeDenseLayer(oldData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Trainable() {
+		t.Error("version 2 layers should be trainable")
+	}
+	if !reflect.DeepEqual(decoded.Weights.Data.Vector, layer.Weights.Data.Vector) ||
+		!reflect.DeepEqual(decoded.Biases.Var.Vector, layer.Biases.Var.Vector) {
+		t.Error("parameters were not preserved")
+	}
+}
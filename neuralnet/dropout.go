@@ -3,6 +3,7 @@ package neuralnet
 import (
 	"encoding/json"
 	"math/rand"
+	"sync"
 
 	"github.com/unixpickle/autofunc"
 	"github.com/unixpickle/num-analysis/linalg"
@@ -16,12 +17,18 @@ import (
 // where inputs are dropped stochastically, or in
 // usage mode, where inputs are scaled to output
 // their expected values.
+// With inverted dropout, the scaling is done to the
+// kept inputs in training mode instead, so that the
+// layer is the identity function in usage mode.
 //
 // Unlike normal autofunc.RFuncs, a DropoutLayer in
 // training mode may return different values each
 // time it is evaluated.
 // As a result, it will most likely fail traditional
 // autofunc tests which assume consistent functions.
+// However, each returned Result remembers its mask, so
+// back-propagation through a Result always uses the
+// same mask as the forward pass which produced it.
 type DropoutLayer struct {
 	// KeepProbability is the probability that an
 	// individual input is not dropped at each
@@ -31,6 +38,20 @@ type DropoutLayer struct {
 	// Training is true if inputs should be dropped
 	// stochastically rather than averaged.
 	Training bool
+
+	// Inverted is true if the inputs which are kept
+	// during training should be divided by
+	// KeepProbability, making the layer the identity
+	// function when Training is false.
+	Inverted bool
+
+	// Rand is used to generate dropout masks.
+	// If it is nil, the global math/rand source is used.
+	// Since a rand.Rand is not safe for concurrent use,
+	// calls to Rand are synchronized by the layer.
+	Rand *rand.Rand `json:"-"`
+
+	randLock sync.Mutex
 }
 
 func DeserializeDropoutLayer(d []byte) (*DropoutLayer, error) {
@@ -41,9 +62,26 @@ func DeserializeDropoutLayer(d []byte) (*DropoutLayer, error) {
 	return &res, nil
 }
 
+// SetTraining sets whether or not inputs should be
+// dropped stochastically.
+func (d *DropoutLayer) SetTraining(training bool) {
+	d.Training = training
+}
+
+// SetRand sets the source used to generate dropout
+// masks, which makes the masks reproducible.
+// If r is nil, the global math/rand source is used.
+func (d *DropoutLayer) SetRand(r *rand.Rand) {
+	d.randLock.Lock()
+	defer d.randLock.Unlock()
+	d.Rand = r
+}
+
 func (d *DropoutLayer) Apply(in autofunc.Result) autofunc.Result {
 	if d.Training {
 		return autofunc.Mul(in, d.dropoutMask(len(in.Output())))
+	} else if d.Inverted {
+		return in
 	} else {
 		return autofunc.Scale(in, d.KeepProbability)
 	}
@@ -54,6 +92,8 @@ func (d *DropoutLayer) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.
 		mask := d.dropoutMask(len(in.Output()))
 		maskVar := autofunc.NewRVariable(mask, v)
 		return autofunc.MulR(in, maskVar)
+	} else if d.Inverted {
+		return in
 	} else {
 		return autofunc.ScaleR(in, d.KeepProbability)
 	}
@@ -76,12 +116,18 @@ func (d *DropoutLayer) SerializerType() string {
 }
 
 func (d *DropoutLayer) dropoutMask(inLen int) *autofunc.Variable {
+	keepValue := 1.0
+	if d.Inverted {
+		keepValue = 1 / d.KeepProbability
+	}
+	d.randLock.Lock()
+	defer d.randLock.Unlock()
 	resVec := make(linalg.Vector, inLen)
 	for i := range resVec {
-		if rand.Float64() > d.KeepProbability {
+		if randFloat64(d.Rand) > d.KeepProbability {
 			resVec[i] = 0
 		} else {
-			resVec[i] = 1
+			resVec[i] = keepValue
 		}
 	}
 	return &autofunc.Variable{resVec}
@@ -0,0 +1,132 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestDropoutLayerInverted(t *testing.T) {
+	layer := &DropoutLayer{KeepProbability: 0.25, Training: true, Inverted: true}
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 10000)}
+	for i := range input.Vector {
+		input.Vector[i] = 1
+	}
+
+	output := layer.Apply(input).Output()
+	var sum float64
+	for i, x := range output {
+		if x != 0 && x != 4 {
+			t.Fatalf("output %d should be 0 or 4 but got %f", i, x)
+		}
+		sum += x
+	}
+	if mean := sum / float64(len(output)); math.Abs(mean-1) > 0.1 {
+		t.Errorf("expected mean output near 1 but got %f", mean)
+	}
+
+	upstream := make(linalg.Vector, len(output))
+	for i := range upstream {
+		upstream[i] = 1
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{input})
+	layer.Apply(input).PropagateGradient(upstream, grad)
+	for i, x := range grad[input] {
+		if x != 0 && x != 4 {
+			t.Fatalf("gradient %d should be 0 or 4 but got %f", i, x)
+		}
+	}
+
+	layer.Training = false
+	output = layer.Apply(input).Output()
+	for i, x := range output {
+		if x != 1 {
+			t.Fatalf("usage output %d should be 1 but got %f", i, x)
+		}
+	}
+}
+
+func TestDropoutLayerSetTraining(t *testing.T) {
+	layer := &DropoutLayer{KeepProbability: 0.5, Inverted: true}
+	layer.SetTraining(true)
+	if !layer.Training {
+		t.Error("expected training mode")
+	}
+	layer.SetTraining(false)
+	input := &autofunc.Variable{Vector: []float64{1, 2, 3}}
+	if out := layer.Apply(input).Output(); out.Copy().Scale(-1).Add(input.Vector).MaxAbs() != 0 {
+		t.Errorf("expected identity in usage mode but got %v", out)
+	}
+}
+
+func TestDropoutLayerRand(t *testing.T) {
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 100)}
+	for i := range input.Vector {
+		input.Vector[i] = 1
+	}
+	layer1 := &DropoutLayer{KeepProbability: 0.5, Training: true,
+		Rand: rand.New(rand.NewSource(42))}
+	layer2 := &DropoutLayer{KeepProbability: 0.5, Training: true,
+		Rand: rand.New(rand.NewSource(42))}
+	out1 := layer1.Apply(input).Output()
+	out2 := layer2.Apply(input).Output()
+	for i, x := range out1 {
+		if x != out2[i] {
+			t.Fatalf("output %d differs: %f vs %f", i, x, out2[i])
+		}
+	}
+}
+
+func TestDropoutLayerSetRand(t *testing.T) {
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 100)}
+	for i := range input.Vector {
+		input.Vector[i] = 1
+	}
+	layer := &DropoutLayer{KeepProbability: 0.5, Training: true}
+	layer.SetRand(rand.New(rand.NewSource(1337)))
+	out1 := layer.Apply(input).Output()
+	layer.SetRand(rand.New(rand.NewSource(1337)))
+	out2 := layer.Apply(input).Output()
+	for i, x := range out1 {
+		if x != out2[i] {
+			t.Fatalf("output %d differs: %f vs %f", i, x, out2[i])
+		}
+	}
+}
+
+func TestDropoutLayerBackwardMask(t *testing.T) {
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 100)}
+	upstream := make(linalg.Vector, len(input.Vector))
+	for i := range input.Vector {
+		input.Vector[i] = 1
+		upstream[i] = 1
+	}
+	rVec := autofunc.RVector{input: upstream}
+	layer := &DropoutLayer{KeepProbability: 0.5, Training: true, Inverted: true,
+		Rand: rand.New(rand.NewSource(1337))}
+
+	// With inputs and upstream gradients of 1, both the
+	// output and the gradient are exactly the mask.
+	result := layer.Apply(input)
+	grad := autofunc.NewGradient([]*autofunc.Variable{input})
+	result.PropagateGradient(upstream.Copy(), grad)
+	for i, x := range result.Output() {
+		if grad[input][i] != x {
+			t.Fatalf("gradient %d should be %f but got %f", i, x, grad[input][i])
+		}
+	}
+
+	rResult := layer.ApplyR(rVec, autofunc.NewRVariable(input, rVec))
+	rgrad := autofunc.NewRGradient([]*autofunc.Variable{input})
+	grad.Zero()
+	rResult.PropagateRGradient(upstream.Copy(), upstream.Copy(), rgrad, grad)
+	for i, x := range rResult.Output() {
+		if grad[input][i] != x || rgrad[input][i] != x {
+			t.Fatalf("gradients %d should be %f but got %f and %f", i, x,
+				grad[input][i], rgrad[input][i])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package neuralnet
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// elementwiseFunc evaluates a scalar function at x,
+// returning its value and its first derivative.
+type elementwiseFunc func(x float64) (y, dy float64)
+
+// elementwiseFuncR is like elementwiseFunc, but it
+// also returns the second derivative, which is needed
+// for back-propagating r-gradients.
+type elementwiseFuncR func(x float64) (y, dy, ddy float64)
+
+// applyElementwise applies f to every component of in.
+func applyElementwise(in autofunc.Result, f elementwiseFunc) autofunc.Result {
+	inVec := in.Output()
+	res := &elementwiseResult{
+		OutputVec: make(linalg.Vector, len(inVec)),
+		DerivVec:  make(linalg.Vector, len(inVec)),
+		Input:     in,
+	}
+	for i, x := range inVec {
+		res.OutputVec[i], res.DerivVec[i] = f(x)
+	}
+	return res
+}
+
+// applyElementwiseR applies f to every component of in.
+func applyElementwiseR(in autofunc.RResult, f elementwiseFuncR) autofunc.RResult {
+	inVec := in.Output()
+	inVecR := in.ROutput()
+	res := &elementwiseRResult{
+		OutputVec:  make(linalg.Vector, len(inVec)),
+		ROutputVec: make(linalg.Vector, len(inVec)),
+		DerivVec:   make(linalg.Vector, len(inVec)),
+		Deriv2Vec:  make(linalg.Vector, len(inVec)),
+		Input:      in,
+	}
+	for i, x := range inVec {
+		res.OutputVec[i], res.DerivVec[i], res.Deriv2Vec[i] = f(x)
+		res.ROutputVec[i] = res.DerivVec[i] * inVecR[i]
+	}
+	return res
+}
+
+type elementwiseResult struct {
+	OutputVec linalg.Vector
+	DerivVec  linalg.Vector
+	Input     autofunc.Result
+}
+
+func (e *elementwiseResult) Output() linalg.Vector {
+	return e.OutputVec
+}
+
+func (e *elementwiseResult) Constant(g autofunc.Gradient) bool {
+	return e.Input.Constant(g)
+}
+
+func (e *elementwiseResult) PropagateGradient(upstream linalg.Vector, grad autofunc.Gradient) {
+	if e.Input.Constant(grad) {
+		return
+	}
+	for i, d := range e.DerivVec {
+		upstream[i] *= d
+	}
+	e.Input.PropagateGradient(upstream, grad)
+}
+
+type elementwiseRResult struct {
+	OutputVec  linalg.Vector
+	ROutputVec linalg.Vector
+	DerivVec   linalg.Vector
+	Deriv2Vec  linalg.Vector
+	Input      autofunc.RResult
+}
+
+func (e *elementwiseRResult) Output() linalg.Vector {
+	return e.OutputVec
+}
+
+func (e *elementwiseRResult) ROutput() linalg.Vector {
+	return e.ROutputVec
+}
+
+func (e *elementwiseRResult) Constant(rg autofunc.RGradient, g autofunc.Gradient) bool {
+	return e.Input.Constant(rg, g)
+}
+
+func (e *elementwiseRResult) PropagateRGradient(upstream, upstreamR linalg.Vector,
+	rgrad autofunc.RGradient, grad autofunc.Gradient) {
+	if e.Input.Constant(rgrad, grad) {
+		return
+	}
+	inputR := e.Input.ROutput()
+	for i, d := range e.DerivVec {
+		upstreamR[i] = upstreamR[i]*d + upstream[i]*e.Deriv2Vec[i]*inputR[i]
+		upstream[i] *= d
+	}
+	e.Input.PropagateRGradient(upstream, upstreamR, rgrad, grad)
+}
+
+// logistic computes the logistic sigmoid of x.
+// For negative x, it uses exp(x)/(1+exp(x)) so that
+// math.Exp never overflows.
+func logistic(x float64) float64 {
+	if x < 0 {
+		e := math.Exp(x)
+		return e / (1 + e)
+	}
+	return 1 / (1 + math.Exp(-x))
+}
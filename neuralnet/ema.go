@@ -0,0 +1,90 @@
+package neuralnet
+
+import (
+	"encoding/json"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+const defaultEMADecay = 0.999
+
+// An EMA maintains an exponential moving average of the
+// parameters of a Network, which often generalizes
+// better than the parameters themselves.
+//
+// After each training step, call Update to blend the
+// current parameters into the average:
+//
+//	shadow = Decay*shadow + (1-Decay)*params
+//
+// To evaluate with the averaged parameters, call Swap,
+// and call it again afterwards to restore the trained
+// parameters.
+//
+// The Network's parameters are flattened with
+// ParamsVector, so the Network must keep the same
+// parameters for the lifetime of the EMA.
+type EMA struct {
+	// Decay determines how slowly the average changes.
+	// If it is 0, a reasonable default is used.
+	Decay float64
+
+	// Shadow stores the averaged parameters in the format
+	// of ParamsVector.
+	Shadow linalg.Vector
+}
+
+// NewEMA creates an EMA whose average starts at the
+// current parameters of n.
+func NewEMA(n Network, decay float64) *EMA {
+	return &EMA{Decay: decay, Shadow: n.ParamsVector()}
+}
+
+// DeserializeEMA deserializes an EMA.
+func DeserializeEMA(d []byte) (*EMA, error) {
+	var res EMA
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Update blends the current parameters of n into the
+// average.
+func (e *EMA) Update(n Network) {
+	params := n.ParamsVector()
+	if len(params) != len(e.Shadow) {
+		panic("parameter count does not match")
+	}
+	decay := e.decay()
+	e.Shadow.Scale(decay).Add(params.Scale(1 - decay))
+}
+
+// Swap exchanges the parameters of n with the averaged
+// parameters.
+func (e *EMA) Swap(n Network) {
+	params := n.ParamsVector()
+	if err := n.SetParamsVector(e.Shadow); err != nil {
+		panic(err)
+	}
+	e.Shadow = params
+}
+
+// Serialize serializes the EMA's decay and averaged
+// parameters, so they can be saved alongside a model.
+func (e *EMA) Serialize() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// SerializerType returns the unique ID used to serialize
+// an EMA with the serializer package.
+func (e *EMA) SerializerType() string {
+	return serializerTypeEMA
+}
+
+func (e *EMA) decay() float64 {
+	if e.Decay == 0 {
+		return defaultEMADecay
+	}
+	return e.Decay
+}
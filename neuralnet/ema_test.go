@@ -0,0 +1,51 @@
+package neuralnet
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/serializer"
+)
+
+func TestEMA(t *testing.T) {
+	net := Network{NewDenseLayer(3, 2)}
+	start := net.ParamsVector()
+	ema := NewEMA(net, 0.75)
+
+	for _, p := range net.Parameters() {
+		p.Vector.Scale(3)
+	}
+	ema.Update(net)
+	for i, x := range ema.Shadow {
+		if expected := start[i] * (0.75 + 0.25*3); math.Abs(x-expected) > 1e-8 {
+			t.Fatalf("shadow %d should be %f but got %f", i, expected, x)
+		}
+	}
+
+	averaged := ema.Shadow.Copy()
+	trained := net.ParamsVector()
+	ema.Swap(net)
+	if !reflect.DeepEqual(net.ParamsVector(), averaged) {
+		t.Error("swap did not load the averaged parameters")
+	}
+	ema.Swap(net)
+	if !reflect.DeepEqual(net.ParamsVector(), trained) {
+		t.Error("second swap did not restore the parameters")
+	}
+}
+
+func TestEMASerialize(t *testing.T) {
+	ema := NewEMA(Network{NewDenseLayer(3, 2)}, 0.9)
+	data, err := ema.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(ema.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, ema) {
+		t.Errorf("expected %#v but got %#v", ema, decoded)
+	}
+}
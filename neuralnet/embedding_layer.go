@@ -0,0 +1,225 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// An EmbeddingLayer maps integer token indices to dense
+// vectors by looking them up in an embedding matrix.
+//
+// Each component of the layer's input is a token index,
+// stored as a float64.
+// The layer's output is the concatenation of the
+// embeddings of each input token, so an input of n
+// tokens produces n*EmbeddingSize outputs.
+// Since every component is treated separately, the
+// layer works the same way on batches of inputs.
+//
+// Inputs are not differentiable, so no gradients are
+// propagated through an EmbeddingLayer to its input.
+// Gradients for the embedding matrix are only non-zero
+// in the rows of tokens which were used.
+type EmbeddingLayer struct {
+	VocabSize     int
+	EmbeddingSize int
+
+	// Embeddings stores the embedding matrix, with one
+	// row of EmbeddingSize components for each token.
+	Embeddings *autofunc.Variable
+}
+
+// NewEmbeddingLayer creates a randomized EmbeddingLayer.
+func NewEmbeddingLayer(vocabSize, embeddingSize int) *EmbeddingLayer {
+	res := &EmbeddingLayer{VocabSize: vocabSize, EmbeddingSize: embeddingSize}
+	res.Randomize()
+	return res
+}
+
+// DeserializeEmbeddingLayer deserializes an EmbeddingLayer.
+func DeserializeEmbeddingLayer(d []byte) (*EmbeddingLayer, error) {
+	var res EmbeddingLayer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Randomize sets every embedding to random values drawn
+// from a normal distribution.
+// This will allocate e.Embeddings if needed.
+func (e *EmbeddingLayer) Randomize() {
+	e.RandomizeWithRand(nil)
+}
+
+// RandomizeWithRand is like Randomize, but it uses r as
+// its source of randomness.
+// If r is nil, the global math/rand source is used.
+func (e *EmbeddingLayer) RandomizeWithRand(r *rand.Rand) {
+	if e.Embeddings == nil {
+		e.Embeddings = &autofunc.Variable{
+			Vector: make(linalg.Vector, e.VocabSize*e.EmbeddingSize),
+		}
+	}
+	for i := range e.Embeddings.Vector {
+		e.Embeddings.Vector[i] = randNormFloat64(r)
+	}
+}
+
+// Parameters returns a slice containing the embedding
+// matrix variable.
+func (e *EmbeddingLayer) Parameters() []*autofunc.Variable {
+	if e.Embeddings == nil {
+		panic(uninitPanicMessage)
+	}
+	return []*autofunc.Variable{e.Embeddings}
+}
+
+// Embed embeds a list of tokens, returning the
+// concatenation of their embeddings.
+func (e *EmbeddingLayer) Embed(tokens []int) autofunc.Result {
+	if e.Embeddings == nil {
+		panic(uninitPanicMessage)
+	}
+	return &embeddingResult{
+		OutputVec: e.lookup(e.Embeddings.Vector, tokens),
+		Tokens:    tokens,
+		Layer:     e,
+	}
+}
+
+// EmbedR is like Embed, but for RResults.
+func (e *EmbeddingLayer) EmbedR(rv autofunc.RVector, tokens []int) autofunc.RResult {
+	if e.Embeddings == nil {
+		panic(uninitPanicMessage)
+	}
+	res := &embeddingRResult{
+		OutputVec: e.lookup(e.Embeddings.Vector, tokens),
+		Tokens:    tokens,
+		Layer:     e,
+	}
+	if rVec, ok := rv[e.Embeddings]; ok {
+		res.ROutputVec = e.lookup(rVec, tokens)
+	} else {
+		res.ROutputVec = make(linalg.Vector, len(res.OutputVec))
+	}
+	return res
+}
+
+// Apply embeds the tokens in the input vector.
+func (e *EmbeddingLayer) Apply(in autofunc.Result) autofunc.Result {
+	return e.Embed(e.inputTokens(in.Output()))
+}
+
+// ApplyR is like Apply, but for RResults.
+func (e *EmbeddingLayer) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return e.EmbedR(rv, e.inputTokens(in.Output()))
+}
+
+// Batch applies the layer to inputs in batch.
+func (e *EmbeddingLayer) Batch(in autofunc.Result, n int) autofunc.Result {
+	return e.Apply(in)
+}
+
+// BatchR is like Batch, but for RResults.
+func (e *EmbeddingLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	return e.ApplyR(rv, in)
+}
+
+// Serialize serializes the layer.
+func (e *EmbeddingLayer) Serialize() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// SerializerType returns the unique ID used to serialize
+// this layer with the serializer package.
+func (e *EmbeddingLayer) SerializerType() string {
+	return serializerTypeEmbeddingLayer
+}
+
+func (e *EmbeddingLayer) inputTokens(in linalg.Vector) []int {
+	res := make([]int, len(in))
+	for i, x := range in {
+		if x != math.Floor(x) || x < 0 || x >= float64(e.VocabSize) {
+			panic("invalid token index")
+		}
+		res[i] = int(x)
+	}
+	return res
+}
+
+func (e *EmbeddingLayer) lookup(matrix linalg.Vector, tokens []int) linalg.Vector {
+	size := e.EmbeddingSize
+	res := make(linalg.Vector, len(tokens)*size)
+	for i, token := range tokens {
+		if token < 0 || token >= e.VocabSize {
+			panic("invalid token index")
+		}
+		copy(res[i*size:(i+1)*size], matrix[token*size:(token+1)*size])
+	}
+	return res
+}
+
+func (e *EmbeddingLayer) accumulate(grad, upstream linalg.Vector, tokens []int) {
+	size := e.EmbeddingSize
+	for i, token := range tokens {
+		grad[token*size : (token+1)*size].Add(upstream[i*size : (i+1)*size])
+	}
+}
+
+type embeddingResult struct {
+	OutputVec linalg.Vector
+	Tokens    []int
+	Layer     *EmbeddingLayer
+}
+
+func (e *embeddingResult) Output() linalg.Vector {
+	return e.OutputVec
+}
+
+func (e *embeddingResult) Constant(g autofunc.Gradient) bool {
+	_, ok := g[e.Layer.Embeddings]
+	return !ok
+}
+
+func (e *embeddingResult) PropagateGradient(upstream linalg.Vector, grad autofunc.Gradient) {
+	if gradVec, ok := grad[e.Layer.Embeddings]; ok {
+		e.Layer.accumulate(gradVec, upstream, e.Tokens)
+	}
+}
+
+type embeddingRResult struct {
+	OutputVec  linalg.Vector
+	ROutputVec linalg.Vector
+	Tokens     []int
+	Layer      *EmbeddingLayer
+}
+
+func (e *embeddingRResult) Output() linalg.Vector {
+	return e.OutputVec
+}
+
+func (e *embeddingRResult) ROutput() linalg.Vector {
+	return e.ROutputVec
+}
+
+func (e *embeddingRResult) Constant(rg autofunc.RGradient, g autofunc.Gradient) bool {
+	_, ok := g[e.Layer.Embeddings]
+	_, rok := rg[e.Layer.Embeddings]
+	return !ok && !rok
+}
+
+func (e *embeddingRResult) PropagateRGradient(upstream, upstreamR linalg.Vector,
+	rgrad autofunc.RGradient, grad autofunc.Gradient) {
+	if gradVec, ok := grad[e.Layer.Embeddings]; ok {
+		e.Layer.accumulate(gradVec, upstream, e.Tokens)
+	}
+	if rgradVec, ok := rgrad[e.Layer.Embeddings]; ok {
+		e.Layer.accumulate(rgradVec, upstreamR, e.Tokens)
+	}
+}
@@ -0,0 +1,75 @@
+package neuralnet
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func TestEmbeddingLayerOutput(t *testing.T) {
+	layer := &EmbeddingLayer{
+		VocabSize:     3,
+		EmbeddingSize: 2,
+		Embeddings:    &autofunc.Variable{Vector: []float64{1, 2, 3, 4, 5, 6}},
+	}
+	input := &autofunc.Variable{Vector: []float64{2, 0, 2}}
+	actual := layer.Apply(input).Output()
+	expected := linalg.Vector{5, 6, 1, 2, 5, 6}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func TestEmbeddingLayerGradients(t *testing.T) {
+	layer := NewEmbeddingLayer(5, 3)
+	input := &autofunc.Variable{Vector: []float64{4, 1, 1, 0}}
+	rVec := autofunc.RVector{
+		layer.Embeddings: make(linalg.Vector, len(layer.Embeddings.Vector)),
+	}
+	for i := range rVec[layer.Embeddings] {
+		rVec[layer.Embeddings][i] = rand.NormFloat64()
+	}
+	vars := layer.Parameters()
+	t.Run("Gradient", func(t *testing.T) {
+		checker := &functest.FuncChecker{F: layer, Vars: vars, Input: input}
+		checker.FullCheck(t)
+	})
+	t.Run("RGradient", func(t *testing.T) {
+		checker := &functest.RFuncChecker{F: layer, Vars: vars, Input: input, RV: rVec}
+		checker.FullCheck(t)
+	})
+
+	grad := autofunc.NewGradient(vars)
+	out := layer.Apply(input)
+	upstream := make(linalg.Vector, len(out.Output()))
+	for i := range upstream {
+		upstream[i] = 1
+	}
+	out.PropagateGradient(upstream, grad)
+	for token := 0; token < layer.VocabSize; token++ {
+		row := grad[layer.Embeddings][token*3 : (token+1)*3]
+		if (token == 2 || token == 3) != (row.MaxAbs() == 0) {
+			t.Errorf("unexpected gradient for token %d: %v", token, row)
+		}
+	}
+}
+
+func TestEmbeddingLayerSerialize(t *testing.T) {
+	layer := NewEmbeddingLayer(4, 3)
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, layer) {
+		t.Errorf("expected %#v but got %#v", layer, decoded)
+	}
+}
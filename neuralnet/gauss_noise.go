@@ -3,6 +3,7 @@ package neuralnet
 import (
 	"encoding/json"
 	"math/rand"
+	"sync"
 
 	"github.com/unixpickle/autofunc"
 	"github.com/unixpickle/num-analysis/linalg"
@@ -13,6 +14,9 @@ import (
 // deviation.
 // This is similar to DropoutLayer, except that it
 // modifies the inputs instead of dropping them.
+//
+// The noise does not depend on the input, so gradients
+// pass through the layer unchanged.
 type GaussNoiseLayer struct {
 	// Stddev is the standard devation of the noise
 	// added to the inputs.
@@ -20,6 +24,14 @@ type GaussNoiseLayer struct {
 
 	// Training is true if noise should be applied.
 	Training bool
+
+	// Rand is used to generate noise.
+	// If it is nil, the global math/rand source is used.
+	// Since a rand.Rand is not safe for concurrent use,
+	// calls to Rand are synchronized by the layer.
+	Rand *rand.Rand `json:"-"`
+
+	randLock sync.Mutex
 }
 
 func DeserializeGaussNoiseLayer(d []byte) (*GaussNoiseLayer, error) {
@@ -30,6 +42,23 @@ func DeserializeGaussNoiseLayer(d []byte) (*GaussNoiseLayer, error) {
 	return &res, nil
 }
 
+// SetTraining sets whether or not noise should be
+// applied.
+// When training is false, the layer is the identity, so
+// inference is deterministic.
+func (g *GaussNoiseLayer) SetTraining(training bool) {
+	g.Training = training
+}
+
+// SetRand sets the source used to generate noise, which
+// makes the noise reproducible.
+// If r is nil, the global math/rand source is used.
+func (g *GaussNoiseLayer) SetRand(r *rand.Rand) {
+	g.randLock.Lock()
+	defer g.randLock.Unlock()
+	g.Rand = r
+}
+
 func (g *GaussNoiseLayer) Apply(in autofunc.Result) autofunc.Result {
 	if g.Training {
 		return autofunc.Add(in, g.noise(len(in.Output())))
@@ -63,17 +92,21 @@ func (g *GaussNoiseLayer) Serialize() ([]byte, error) {
 }
 
 func (g *GaussNoiseLayer) noise(size int) autofunc.Result {
+	g.randLock.Lock()
+	defer g.randLock.Unlock()
 	vec := make(linalg.Vector, size)
 	for i := range vec {
-		vec[i] = rand.NormFloat64() * g.Stddev
+		vec[i] = randNormFloat64(g.Rand) * g.Stddev
 	}
 	return &autofunc.Variable{Vector: vec}
 }
 
 func (g *GaussNoiseLayer) noiseR(size int) autofunc.RResult {
+	g.randLock.Lock()
+	defer g.randLock.Unlock()
 	vec := make(linalg.Vector, size)
 	for i := range vec {
-		vec[i] = rand.NormFloat64() * g.Stddev
+		vec[i] = randNormFloat64(g.Rand) * g.Stddev
 	}
 	return &autofunc.RVariable{
 		Variable:   &autofunc.Variable{Vector: vec},
@@ -0,0 +1,80 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func TestGaussNoiseLayerTraining(t *testing.T) {
+	layer := &GaussNoiseLayer{Stddev: 0.5}
+	layer.SetTraining(true)
+	layer.SetRand(rand.New(rand.NewSource(1337)))
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 10000)}
+	for i := range input.Vector {
+		input.Vector[i] = 2
+	}
+
+	output := layer.Apply(input)
+	var sum, sqSum float64
+	for _, x := range output.Output() {
+		sum += x - 2
+		sqSum += (x - 2) * (x - 2)
+	}
+	n := float64(len(input.Vector))
+	if mean := sum / n; math.Abs(mean) > 0.05 {
+		t.Errorf("expected mean noise near 0 but got %f", mean)
+	}
+	if stddev := math.Sqrt(sqSum / n); math.Abs(stddev-0.5) > 0.05 {
+		t.Errorf("expected noise stddev near 0.5 but got %f", stddev)
+	}
+
+	upstream := make(linalg.Vector, len(input.Vector))
+	for i := range upstream {
+		upstream[i] = float64(i)
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{input})
+	output.PropagateGradient(upstream.Copy(), grad)
+	if !reflect.DeepEqual(grad[input], upstream) {
+		t.Error("gradient was not passed through unchanged")
+	}
+
+	layer.SetTraining(false)
+	if out := layer.Apply(input).Output(); !reflect.DeepEqual(out, input.Vector) {
+		t.Error("layer should be the identity when not training")
+	}
+}
+
+func TestGaussNoiseLayerRand(t *testing.T) {
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 50)}
+	layer1 := &GaussNoiseLayer{Stddev: 1, Training: true}
+	layer2 := &GaussNoiseLayer{Stddev: 1, Training: true}
+	layer1.SetRand(rand.New(rand.NewSource(42)))
+	layer2.SetRand(rand.New(rand.NewSource(42)))
+	out1 := layer1.Apply(input).Output()
+	out2 := layer2.Apply(input).Output()
+	if !reflect.DeepEqual(out1, out2) {
+		t.Error("layers with the same seed produced different noise")
+	}
+}
+
+func TestGaussNoiseLayerSerialize(t *testing.T) {
+	layer := &GaussNoiseLayer{Stddev: 0.3, Training: true}
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := decoded.(*GaussNoiseLayer)
+	if res.Stddev != layer.Stddev || res.Training != layer.Training {
+		t.Errorf("expected %v but got %v", layer, res)
+	}
+}
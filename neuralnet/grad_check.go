@@ -0,0 +1,71 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+// gradCheckSeed seeds the weights which GradCheck uses
+// to combine a layer's outputs into a scalar cost.
+const gradCheckSeed = 1337
+
+// GradCheck compares the analytic gradient of a layer
+// to a numerical estimate obtained by central finite
+// differences with the given epsilon.
+//
+// The outputs of the layer are combined into a scalar
+// cost using a fixed set of pseudo-random weights.
+// The partial derivatives of this cost are checked for
+// the input and for every parameter of the layer (if
+// it is an sgd.Learner).
+//
+// GradCheck returns the maximum error across all of the
+// partials.
+// Errors are relative to the magnitude of the partials,
+// except when both the analytic and numeric partials
+// have magnitudes below 1, in which case errors are
+// absolute.
+//
+// The layer must be deterministic (e.g. DropoutLayers
+// should not be in training mode).
+func GradCheck(layer Layer, input linalg.Vector, epsilon float64) float64 {
+	inVar := &autofunc.Variable{Vector: input.Copy()}
+	vars := []*autofunc.Variable{inVar}
+	if l, ok := layer.(sgd.Learner); ok {
+		vars = append(vars, l.Parameters()...)
+	}
+
+	output := layer.Apply(inVar)
+	weights := make(linalg.Vector, len(output.Output()))
+	gen := rand.New(rand.NewSource(gradCheckSeed))
+	for i := range weights {
+		weights[i] = gen.NormFloat64()
+	}
+	cost := func() float64 {
+		return layer.Apply(inVar).Output().Dot(weights)
+	}
+
+	grad := autofunc.NewGradient(vars)
+	output.PropagateGradient(weights.Copy(), grad)
+
+	var maxErr float64
+	for _, variable := range vars {
+		for i, analytic := range grad[variable] {
+			old := variable.Vector[i]
+			variable.Vector[i] = old + epsilon
+			plus := cost()
+			variable.Vector[i] = old - epsilon
+			minus := cost()
+			variable.Vector[i] = old
+			numeric := (plus - minus) / (2 * epsilon)
+
+			scale := math.Max(1, math.Max(math.Abs(analytic), math.Abs(numeric)))
+			maxErr = math.Max(maxErr, math.Abs(analytic-numeric)/scale)
+		}
+	}
+	return maxErr
+}
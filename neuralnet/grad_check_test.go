@@ -0,0 +1,51 @@
+package neuralnet
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestGradCheck(t *testing.T) {
+	layers := []Layer{
+		Network{NewDenseLayer(6, 4), &Sigmoid{}, &SoftmaxLayer{}},
+		NewConvLayer(3, 2, 1, 2, 2, 2, 1),
+		&AvgPoolingLayer{XSpan: 2, YSpan: 2, InputWidth: 3, InputHeight: 2, InputDepth: 1},
+	}
+	for i, layer := range layers {
+		input := make(linalg.Vector, 6)
+		for j := range input {
+			input[j] = rand.NormFloat64()
+		}
+		if err := GradCheck(layer, input, 1e-5); err > 1e-5 {
+			t.Errorf("layer %d: error %e is too large", i, err)
+		}
+	}
+}
+
+func TestGradCheckBroken(t *testing.T) {
+	input := linalg.Vector{1, -2, 3}
+	if err := GradCheck(brokenGradLayer{}, input, 1e-5); err < 0.1 {
+		t.Errorf("expected large error but got %e", err)
+	}
+}
+
+// brokenGradLayer doubles its input but only reports
+// the gradient of the identity function.
+type brokenGradLayer struct {
+	Sigmoid
+}
+
+func (b brokenGradLayer) Apply(in autofunc.Result) autofunc.Result {
+	return &brokenGradResult{in}
+}
+
+type brokenGradResult struct {
+	autofunc.Result
+}
+
+func (b *brokenGradResult) Output() linalg.Vector {
+	return b.Result.Output().Copy().Scale(2)
+}
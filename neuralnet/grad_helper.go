@@ -59,13 +59,13 @@ func (g *GradHelper) RGradient(rv autofunc.RVector, s sgd.SampleSet) (autofunc.G
 
 func (g *GradHelper) batch(rv autofunc.RVector, s sgd.SampleSet) (grad autofunc.Gradient,
 	rgrad autofunc.RGradient) {
-	g.gradCache.variables = g.Learner.Parameters()
 	if g.lastGradResult != nil {
 		g.gradCache.Free(g.lastGradResult)
 	}
 	if g.lastRGradResult != nil {
 		g.gradCache.FreeR(g.lastRGradResult)
 	}
+	g.gradCache.SetVariables(g.Learner.Parameters())
 	batchSize := g.batchSize()
 	maxGos := g.goroutineCount()
 	if s.Len() < batchSize || maxGos < 2 {
@@ -173,6 +173,28 @@ type gradientCache struct {
 	rGradients []autofunc.RGradient
 }
 
+// SetVariables sets the variables for which gradients
+// are allocated, discarding cached gradients if the
+// variables have changed (e.g. because a layer was
+// frozen).
+func (g *gradientCache) SetVariables(vars []*autofunc.Variable) {
+	if len(vars) == len(g.variables) {
+		same := true
+		for i, v := range vars {
+			if g.variables[i] != v {
+				same = false
+				break
+			}
+		}
+		if same {
+			return
+		}
+	}
+	g.variables = vars
+	g.gradients = nil
+	g.rGradients = nil
+}
+
 func (g *gradientCache) Alloc() autofunc.Gradient {
 	if len(g.gradients) == 0 {
 		res := autofunc.NewGradient(g.variables)
@@ -0,0 +1,68 @@
+package neuralnet
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/sgd"
+)
+
+// ClipGradientNorm scales down the gradient g in place
+// so that its global L2 norm (computed over every
+// variable in g) does not exceed maxNorm.
+// It returns the norm of g before it was clipped.
+//
+// The same behavior is available as a Gradienter via
+// sgd.GradientClipper.
+func ClipGradientNorm(g autofunc.Gradient, maxNorm float64) float64 {
+	var sqNorm float64
+	for _, vec := range g {
+		sqNorm += vec.Dot(vec)
+	}
+	norm := math.Sqrt(sqNorm)
+	if norm > maxNorm {
+		g.Scale(maxNorm / norm)
+	}
+	return norm
+}
+
+// LayerGradientClipper is a Gradienter which clips the
+// gradient of each layer in a Network separately, so
+// that the L2 norm of each layer's parameter gradients
+// (e.g. both its weights and biases) does not exceed
+// Threshold.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type LayerGradientClipper struct {
+	Gradienter sgd.Gradienter
+	Network    Network
+	Threshold  float64
+}
+
+func (l *LayerGradientClipper) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return l.Transform(l.Gradienter.Gradient(s))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (l *LayerGradientClipper) WrappedGradienter() sgd.Gradienter {
+	return l.Gradienter
+}
+
+func (l *LayerGradientClipper) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	for _, layer := range l.Network {
+		learner, ok := layer.(sgd.Learner)
+		if !ok {
+			continue
+		}
+		layerGrad := autofunc.Gradient{}
+		for _, param := range learner.Parameters() {
+			if vec, ok := grad[param]; ok {
+				layerGrad[param] = vec
+			}
+		}
+		ClipGradientNorm(layerGrad, l.Threshold)
+	}
+	return grad
+}
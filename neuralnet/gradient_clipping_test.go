@@ -0,0 +1,45 @@
+package neuralnet
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+)
+
+func TestClipGradientNorm(t *testing.T) {
+	net := optimizerTestNetwork()
+	grad := randomOptimizerGradient(net)
+	var sqNorm float64
+	for _, vec := range grad {
+		sqNorm += vec.Dot(vec)
+	}
+	norm := math.Sqrt(sqNorm)
+
+	expected := grad.Copy()
+	expected.Scale(0.5 / norm)
+	if actual := ClipGradientNorm(grad, 0.5); math.Abs(actual-norm) > 1e-8 {
+		t.Errorf("expected norm %f but got %f", norm, actual)
+	}
+	checkOptimizerGradients(t, net, grad, expected)
+
+	expected = grad.Copy()
+	ClipGradientNorm(grad, 1)
+	checkOptimizerGradients(t, net, grad, expected)
+}
+
+func TestLayerGradientClipper(t *testing.T) {
+	net := optimizerTestNetwork()
+	grad := randomOptimizerGradient(net)
+	clipper := &LayerGradientClipper{Network: net, Threshold: 0.5}
+	clipper.Transform(grad)
+	for i, layer := range []*DenseLayer{net[0].(*DenseLayer), net[2].(*DenseLayer)} {
+		layerGrad := autofunc.Gradient{}
+		for _, param := range layer.Parameters() {
+			layerGrad[param] = grad[param]
+		}
+		if norm := ClipGradientNorm(layerGrad, math.Inf(1)); math.Abs(norm-0.5) > 1e-8 {
+			t.Errorf("layer %d: expected norm 0.5 but got %f", i, norm)
+		}
+	}
+}
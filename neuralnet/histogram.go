@@ -0,0 +1,133 @@
+package neuralnet
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// histogramBarWidth is the length of the bar which
+// Histogram.String draws for the fullest bin.
+const histogramBarWidth = 40
+
+// A Histogram counts values in equally sized bins
+// spanning the range [Min, Max].
+// This can be used to watch the distributions of
+// weights or activations change during training.
+//
+// Infinite values, which appear when training
+// diverges, do not fit in any bin, so they are counted
+// separately in NegInf and PosInf.
+type Histogram struct {
+	Min    float64
+	Max    float64
+	Counts []int
+
+	NegInf int
+	PosInf int
+}
+
+// NewHistogram buckets the values into the given
+// number of bins, which span from the smallest finite
+// value to the largest one.
+// NaN values are ignored, and infinite values are
+// counted in NegInf and PosInf rather than in a bin.
+func NewHistogram(values linalg.Vector, bins int) *Histogram {
+	if bins < 1 {
+		panic("bin count must be positive")
+	}
+	res := &Histogram{
+		Min:    math.Inf(1),
+		Max:    math.Inf(-1),
+		Counts: make([]int, bins),
+	}
+	for _, x := range values {
+		if math.IsInf(x, -1) {
+			res.NegInf++
+		} else if math.IsInf(x, 1) {
+			res.PosInf++
+		} else if !math.IsNaN(x) {
+			res.Min = math.Min(res.Min, x)
+			res.Max = math.Max(res.Max, x)
+		}
+	}
+	if res.Min > res.Max {
+		res.Min, res.Max = 0, 0
+		return res
+	}
+	for _, x := range values {
+		if !math.IsNaN(x) && !math.IsInf(x, 0) {
+			res.Counts[res.bin(x)]++
+		}
+	}
+	return res
+}
+
+// BinWidth returns the width of each bin.
+func (h *Histogram) BinWidth() float64 {
+	return (h.Max - h.Min) / float64(len(h.Counts))
+}
+
+// String formats the histogram with one line per bin,
+// giving the bin's range, its count, and a bar scaled
+// relative to the fullest bin.
+// If there are infinite values, a final line gives
+// their counts.
+func (h *Histogram) String() string {
+	var maxCount int
+	for _, c := range h.Counts {
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+	var buf bytes.Buffer
+	for i, c := range h.Counts {
+		start := h.Min + float64(i)*h.BinWidth()
+		var bar int
+		if maxCount > 0 {
+			bar = c * histogramBarWidth / maxCount
+		}
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "[%10.4g, %10.4g] %6d %s", start, start+h.BinWidth(), c,
+			strings.Repeat("#", bar))
+	}
+	if h.NegInf > 0 || h.PosInf > 0 {
+		fmt.Fprintf(&buf, "\n-Inf: %d, +Inf: %d", h.NegInf, h.PosInf)
+	}
+	return buf.String()
+}
+
+func (h *Histogram) bin(x float64) int {
+	if h.Max == h.Min {
+		return 0
+	}
+	idx := int((x - h.Min) / h.BinWidth())
+	if idx >= len(h.Counts) {
+		// The maximum value belongs in the last bin.
+		idx = len(h.Counts) - 1
+	}
+	return idx
+}
+
+// WeightHistogram returns a Histogram of the layer's
+// weights.
+func (d *DenseLayer) WeightHistogram(bins int) *Histogram {
+	if d.Weights == nil {
+		panic(uninitPanicMessage)
+	}
+	return NewHistogram(d.Weights.Data.Vector, bins)
+}
+
+// BiasHistogram returns a Histogram of the layer's
+// biases.
+func (d *DenseLayer) BiasHistogram(bins int) *Histogram {
+	if d.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	return NewHistogram(d.Biases.Var.Vector, bins)
+}
@@ -0,0 +1,66 @@
+package neuralnet
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestNewHistogram(t *testing.T) {
+	values := linalg.Vector{-1, 3, 0.5, 0, 2.9, math.NaN(), 1, -0.5}
+	h := NewHistogram(values, 4)
+	if h.Min != -1 || h.Max != 3 {
+		t.Errorf("expected range [-1, 3] but got [%f, %f]", h.Min, h.Max)
+	}
+	if expected := []int{2, 2, 1, 2}; !reflect.DeepEqual(h.Counts, expected) {
+		t.Errorf("expected counts %v but got %v", expected, h.Counts)
+	}
+	if lines := strings.Split(h.String(), "\n"); len(lines) != 4 {
+		t.Errorf("expected 4 lines but got %d", len(lines))
+	}
+
+	h = NewHistogram(linalg.Vector{2, 2, 2}, 3)
+	if expected := []int{3, 0, 0}; !reflect.DeepEqual(h.Counts, expected) {
+		t.Errorf("expected counts %v but got %v", expected, h.Counts)
+	}
+
+	h = NewHistogram(linalg.Vector{1, 2, math.Inf(1), math.Inf(-1), math.Inf(1)}, 4)
+	if h.Min != 1 || h.Max != 2 {
+		t.Errorf("expected range [1, 2] but got [%f, %f]", h.Min, h.Max)
+	}
+	if expected := []int{1, 0, 0, 1}; !reflect.DeepEqual(h.Counts, expected) {
+		t.Errorf("expected counts %v but got %v", expected, h.Counts)
+	}
+	if h.NegInf != 1 || h.PosInf != 2 {
+		t.Errorf("expected 1 -Inf and 2 +Inf but got %d and %d", h.NegInf, h.PosInf)
+	}
+	if lines := strings.Split(h.String(), "\n"); len(lines) != 5 {
+		t.Errorf("expected 5 lines but got %d", len(lines))
+	}
+
+	h = NewHistogram(linalg.Vector{math.Inf(1)}, 2)
+	if h.Min != 0 || h.Max != 0 || h.PosInf != 1 {
+		t.Errorf("unexpected histogram for one infinite value: %+v", h)
+	}
+}
+
+func TestDenseLayerHistograms(t *testing.T) {
+	layer := NewDenseLayer(5, 3)
+	var total int
+	for _, c := range layer.WeightHistogram(7).Counts {
+		total += c
+	}
+	if total != 15 {
+		t.Errorf("expected 15 weights but got %d", total)
+	}
+	total = 0
+	for _, c := range layer.BiasHistogram(2).Counts {
+		total += c
+	}
+	if total != 3 {
+		t.Errorf("expected 3 biases but got %d", total)
+	}
+}
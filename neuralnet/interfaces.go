@@ -2,6 +2,7 @@ package neuralnet
 
 import (
 	"errors"
+	"math/rand"
 
 	"github.com/unixpickle/autofunc"
 	"github.com/unixpickle/serializer"
@@ -20,16 +21,68 @@ var uninitPanicMessage = errors.New("layer has uninitialized parameters")
 // a Layer are only valid so long as the Layer is
 // not modified (e.g. by SetCache, by changing
 // a struct field, etc.).
+// Likewise, Results may refer to the vectors of their
+// inputs rather than copying them, so an input vector
+// must not be modified until its Results are no longer
+// needed.
+// For instance, re-using the vector of an input
+// autofunc.Variable between a call to Apply and a call
+// to PropagateGradient will produce incorrect
+// gradients.
+// Use linalg.Vector.Copy (or Network.ApplyCopy) to give
+// a Layer its own copy of an input which is stored in a
+// re-used buffer.
 //
 // Layers must support concurrent calls to their
 // autofunc.RFunc methods.
 // However, serialization methods needn't be safe
 // for concurrency.
+// Evaluating a Layer allocates fresh Results rather
+// than writing to buffers in the Layer, so a single
+// Network can serve predictions from many goroutines,
+// provided that its parameters are not being modified
+// (e.g. by training) at the same time.
+// Layers with internal state, like DropoutLayers and
+// BatchNormLayers in training mode, synchronize access
+// to that state themselves.
+//
+// Layers with trainable parameters should implement
+// sgd.Learner, so that optimizers and regularizers can
+// access those parameters without knowing the type of
+// the layer.
+// The gradient of each parameter can then be found in
+// an autofunc.Gradient, keyed by the parameter's
+// *autofunc.Variable.
+// Layers never store gradients themselves:
+// back-propagation adds into whichever autofunc.Gradient
+// it is given, which can be cleared between training
+// steps with its Zero method.
+// Layers without parameters, such as pooling layers,
+// needn't implement sgd.Learner at all.
 type Layer interface {
 	serializer.Serializer
 	autofunc.RFunc
 }
 
+// A SizedLayer is a Layer whose input and output sizes
+// are fixed by its configuration, such as a DenseLayer
+// or a ConvLayer.
+// Element-wise layers like activation functions work
+// with inputs of any size, so they are not SizedLayers.
+//
+// NewNetwork and NetworkBuilder use these sizes to
+// check that adjacent layers fit together.
+type SizedLayer interface {
+	Layer
+
+	// InputSize returns the length of one input sample.
+	InputSize() int
+
+	// OutputSize returns the length of one output
+	// sample.
+	OutputSize() int
+}
+
 // A Randomizer is anything which can be reset to
 // a random state.
 // For instance, some layers of a neural network
@@ -38,6 +91,52 @@ type Randomizer interface {
 	Randomize()
 }
 
+// A RandRandomizer is a Randomizer which can draw its
+// random numbers from a specific source, making the
+// randomization reproducible.
+type RandRandomizer interface {
+	Randomizer
+
+	// RandomizeWithRand is like Randomize, but it uses
+	// r rather than the global math/rand source.
+	// If r is nil, the global source is used.
+	RandomizeWithRand(r *rand.Rand)
+}
+
+// A Cloner is a Layer which can produce a deep copy of
+// itself.
+// The copy must not share any parameters or other
+// mutable state with the original.
+type Cloner interface {
+	Layer
+	Clone() Layer
+}
+
+// A TrainingLayer is a Layer which behaves differently
+// while it is being trained, such as a DropoutLayer.
+//
+// Network.SetTraining switches every TrainingLayer in a
+// Network at once.
+type TrainingLayer interface {
+	Layer
+
+	// SetTraining switches the layer between training
+	// mode and usage (inference) mode.
+	SetTraining(training bool)
+}
+
+// A GradienterWrapper is a Gradienter which transforms
+// the gradients of another Gradienter, such as an
+// optimizer or a regularizer.
+//
+// Trainer checkpoints use WrappedGradienter to find the
+// state of every Gradienter in a chain, so wrappers
+// outside of this package should implement it too.
+type GradienterWrapper interface {
+	sgd.Gradienter
+	WrappedGradienter() sgd.Gradienter
+}
+
 // A LearnBatcher is a Learner that can be evaluated
 // in batch.
 type BatchLearner interface {
@@ -0,0 +1,176 @@
+package neuralnet
+
+import (
+	"encoding/json"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+const defaultLayerNormEpsilon = 1e-5
+
+// LayerNormLayer implements layer normalization, where
+// the components of each input are normalized to have a
+// mean of 0 and a variance of 1, and are then scaled
+// and translated by learnable parameters.
+//
+// Unlike a BatchNormLayer, a LayerNormLayer computes
+// its statistics separately for every sample, so it
+// behaves the same way during training and usage and
+// does not depend on the other samples in a batch.
+type LayerNormLayer struct {
+	InputCount int
+
+	// Epsilon is added to variances to prevent division
+	// by zero.
+	// If it is 0, a reasonable default is used.
+	Epsilon float64
+
+	Gains  *autofunc.Variable
+	Biases *autofunc.Variable
+}
+
+// NewLayerNormLayer creates a LayerNormLayer with unit
+// gains and zero biases.
+func NewLayerNormLayer(inCount int) *LayerNormLayer {
+	res := &LayerNormLayer{
+		InputCount: inCount,
+		Gains:      &autofunc.Variable{Vector: make(linalg.Vector, inCount)},
+		Biases:     &autofunc.Variable{Vector: make(linalg.Vector, inCount)},
+	}
+	for i := range res.Gains.Vector {
+		res.Gains.Vector[i] = 1
+	}
+	return res
+}
+
+// DeserializeLayerNormLayer deserializes a LayerNormLayer.
+func DeserializeLayerNormLayer(d []byte) (*LayerNormLayer, error) {
+	var res LayerNormLayer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Parameters returns a slice containing the gain and
+// bias variables.
+func (l *LayerNormLayer) Parameters() []*autofunc.Variable {
+	if l.Gains == nil || l.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	return []*autofunc.Variable{l.Gains, l.Biases}
+}
+
+// Apply applies the layer to a single input.
+func (l *LayerNormLayer) Apply(in autofunc.Result) autofunc.Result {
+	return l.Batch(in, 1)
+}
+
+// ApplyR is like Apply, but for RResults.
+func (l *LayerNormLayer) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return l.BatchR(rv, in, 1)
+}
+
+// Batch applies the layer to inputs in batch.
+func (l *LayerNormLayer) Batch(in autofunc.Result, n int) autofunc.Result {
+	l.checkInput(len(in.Output()), n)
+
+	// The batch is treated as a column-major matrix with
+	// one column per sample, so that per-sample statistics
+	// are products with a row of averaging weights, and
+	// spreading them back out is a product with a column
+	// of ones.
+	averager, ones := l.statVars()
+	spread := func(stat autofunc.Result) autofunc.Result {
+		return autofunc.MatMulVecs(ones, l.InputCount, 1, stat)
+	}
+	return autofunc.Pool(in, func(in autofunc.Result) autofunc.Result {
+		mean := autofunc.MatMulVecs(averager, 1, l.InputCount, in)
+		centered := autofunc.Sub(in, spread(mean))
+		return autofunc.Pool(centered, func(centered autofunc.Result) autofunc.Result {
+			variance := autofunc.MatMulVecs(averager, 1, l.InputCount,
+				autofunc.Square(centered))
+			invStd := autofunc.Pow(autofunc.AddScaler(variance, l.epsilon()), -0.5)
+			normalized := autofunc.Mul(centered, spread(invStd))
+			return autofunc.Add(autofunc.Mul(normalized, autofunc.Repeat(l.Gains, n)),
+				autofunc.Repeat(l.Biases, n))
+		})
+	})
+}
+
+// BatchR is like Batch, but for RResults.
+func (l *LayerNormLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	l.checkInput(len(in.Output()), n)
+	averagerVar, onesVar := l.statVars()
+	averager := autofunc.NewRVariable(averagerVar, rv)
+	ones := autofunc.NewRVariable(onesVar, rv)
+	gains := autofunc.NewRVariable(l.Gains, rv)
+	biases := autofunc.NewRVariable(l.Biases, rv)
+	spread := func(stat autofunc.RResult) autofunc.RResult {
+		return autofunc.MatMulVecsR(ones, l.InputCount, 1, stat)
+	}
+	return autofunc.PoolR(in, func(in autofunc.RResult) autofunc.RResult {
+		mean := autofunc.MatMulVecsR(averager, 1, l.InputCount, in)
+		centered := autofunc.SubR(in, spread(mean))
+		return autofunc.PoolR(centered, func(centered autofunc.RResult) autofunc.RResult {
+			variance := autofunc.MatMulVecsR(averager, 1, l.InputCount,
+				autofunc.SquareR(centered))
+			invStd := autofunc.PowR(autofunc.AddScalerR(variance, l.epsilon()), -0.5)
+			normalized := autofunc.MulR(centered, spread(invStd))
+			return autofunc.AddR(autofunc.MulR(normalized, autofunc.RepeatR(gains, n)),
+				autofunc.RepeatR(biases, n))
+		})
+	})
+}
+
+// InputSize returns the length of each input.
+func (l *LayerNormLayer) InputSize() int {
+	return l.InputCount
+}
+
+// OutputSize returns the length of each output.
+func (l *LayerNormLayer) OutputSize() int {
+	return l.InputCount
+}
+
+// Serialize serializes the layer.
+func (l *LayerNormLayer) Serialize() ([]byte, error) {
+	return json.Marshal(l)
+}
+
+// SerializerType returns the unique ID used to serialize
+// this layer with the serializer package.
+func (l *LayerNormLayer) SerializerType() string {
+	return serializerTypeLayerNormLayer
+}
+
+func (l *LayerNormLayer) checkInput(inLen, n int) {
+	if l.Gains == nil || l.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	if inLen != n*l.InputCount {
+		panic("invalid input size")
+	}
+}
+
+// statVars returns constant variables for averaging the
+// components of a sample and for spreading a statistic
+// across the components of a sample.
+func (l *LayerNormLayer) statVars() (averager, ones *autofunc.Variable) {
+	averager = &autofunc.Variable{Vector: make(linalg.Vector, l.InputCount)}
+	ones = &autofunc.Variable{Vector: make(linalg.Vector, l.InputCount)}
+	for i := range averager.Vector {
+		averager.Vector[i] = 1 / float64(l.InputCount)
+		ones.Vector[i] = 1
+	}
+	return
+}
+
+func (l *LayerNormLayer) epsilon() float64 {
+	if l.Epsilon == 0 {
+		return defaultLayerNormEpsilon
+	}
+	return l.Epsilon
+}
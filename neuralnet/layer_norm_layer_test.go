@@ -0,0 +1,90 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+type layerNormTestFunc struct {
+	Layer *LayerNormLayer
+	N     int
+}
+
+func (l layerNormTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return l.Layer.Batch(in, l.N)
+}
+
+func (l layerNormTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return l.Layer.BatchR(v, in, l.N)
+}
+
+func TestLayerNormLayerOutput(t *testing.T) {
+	layer := NewLayerNormLayer(3)
+	copy(layer.Gains.Vector, []float64{2, -1, 0.5})
+	copy(layer.Biases.Vector, []float64{0.5, 1, 0})
+
+	input := &autofunc.Variable{Vector: []float64{1, 2, 3, 4, 4, 10}}
+	output := layer.Batch(input, 2).Output()
+
+	invStd1 := 1 / math.Sqrt(2.0/3+defaultLayerNormEpsilon)
+	invStd2 := 1 / math.Sqrt(8+defaultLayerNormEpsilon)
+	expected := []float64{
+		-2*invStd1 + 0.5, 1, 0.5 * invStd1,
+		-4*invStd2 + 0.5, 2*invStd2 + 1, 0.5 * 4 * invStd2,
+	}
+	for i, x := range expected {
+		if math.Abs(output[i]-x) > 1e-5 {
+			t.Errorf("output %d: expected %f but got %f", i, x, output[i])
+		}
+	}
+
+	single := layer.Apply(&autofunc.Variable{Vector: input.Vector[3:]}).Output()
+	for i, x := range single {
+		if math.Abs(x-output[i+3]) > 1e-8 {
+			t.Errorf("single output %d: expected %f but got %f", i, output[i+3], x)
+		}
+	}
+}
+
+func TestLayerNormLayerGradients(t *testing.T) {
+	layer := NewLayerNormLayer(4)
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 12)}
+	params := append(layer.Parameters(), input)
+	rVec := autofunc.RVector{}
+	for _, p := range params {
+		rVec[p] = make(linalg.Vector, len(p.Vector))
+		for i := range p.Vector {
+			p.Vector[i] = rand.NormFloat64()
+			rVec[p][i] = rand.NormFloat64()
+		}
+	}
+	f := layerNormTestFunc{Layer: layer, N: 3}
+	checker := &functest.RFuncChecker{F: f, Vars: params, Input: input, RV: rVec}
+	checker.FullCheck(t)
+}
+
+func TestLayerNormLayerSerialize(t *testing.T) {
+	layer := NewLayerNormLayer(3)
+	layer.Epsilon = 1e-3
+	copy(layer.Gains.Vector, []float64{2, -1, 0.5})
+	copy(layer.Biases.Vector, []float64{0.5, 1, 0})
+
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, layer) {
+		t.Errorf("expected %#v but got %#v", layer, decoded)
+	}
+}
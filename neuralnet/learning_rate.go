@@ -0,0 +1,216 @@
+package neuralnet
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/sgd"
+)
+
+// A LearningRateSchedule determines the learning rate
+// to use at each step of training.
+// Steps are numbered starting at 0.
+type LearningRateSchedule interface {
+	Rate(step int) float64
+}
+
+// StepDecay is a LearningRateSchedule which multiplies
+// the learning rate by Factor every Interval steps.
+// If Interval is 0, it is treated as 1.
+type StepDecay struct {
+	Initial  float64
+	Factor   float64
+	Interval int
+}
+
+func (s *StepDecay) Rate(step int) float64 {
+	interval := s.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	return s.Initial * math.Pow(s.Factor, float64(step/interval))
+}
+
+// ExponentialDecay is a LearningRateSchedule which
+// multiplies the learning rate by Factor at every step.
+type ExponentialDecay struct {
+	Initial float64
+	Factor  float64
+}
+
+func (e *ExponentialDecay) Rate(step int) float64 {
+	return e.Initial * math.Pow(e.Factor, float64(step))
+}
+
+// CosineAnnealing is a LearningRateSchedule which
+// decreases the learning rate from MaxRate to MinRate
+// along a half cosine wave, and then restarts at
+// MaxRate (i.e. SGD with warm restarts).
+//
+// The first cycle lasts for Period steps.
+// Each subsequent cycle is PeriodFactor times longer
+// than the previous one.
+// If PeriodFactor is 0, it is treated as 1, so that
+// every cycle has the same length.
+//
+// Rate panics if Period is not positive or if
+// PeriodFactor is less than 1, since the cycles would
+// never reach later steps.
+type CosineAnnealing struct {
+	MaxRate float64
+	MinRate float64
+
+	Period       int
+	PeriodFactor float64
+}
+
+func (c *CosineAnnealing) Rate(step int) float64 {
+	t := float64(step)
+	period := float64(c.Period)
+	factor := c.PeriodFactor
+	if factor == 0 {
+		factor = 1
+	}
+	if c.Period <= 0 {
+		panic("cosine annealing period must be positive")
+	} else if factor < 1 {
+		panic("cosine annealing period factor must be at least 1")
+	}
+	if factor == 1 {
+		t = math.Mod(t, period)
+	} else {
+		for t >= period {
+			t -= period
+			period *= factor
+		}
+	}
+	return c.MinRate + (c.MaxRate-c.MinRate)*(1+math.Cos(math.Pi*t/period))/2
+}
+
+// LinearDecay is a LearningRateSchedule which moves
+// the learning rate linearly from Initial to Final over
+// Steps steps, and then stays at Final.
+type LinearDecay struct {
+	Initial float64
+	Final   float64
+	Steps   int
+}
+
+func (l *LinearDecay) Rate(step int) float64 {
+	if step >= l.Steps {
+		return l.Final
+	}
+	frac := float64(step) / float64(l.Steps)
+	return l.Initial + (l.Final-l.Initial)*frac
+}
+
+// WarmupSchedule is a LearningRateSchedule which
+// increases the learning rate linearly from near zero to
+// Peak over the first Steps steps, and then follows
+// another schedule.
+//
+// At step i of the warmup, the learning rate is
+// Peak*(i+1)/Steps, so the warmup ends at Peak rather
+// than starting at zero.
+// After the warmup, the steps of Schedule are counted
+// from 0, so Schedule should typically start at Peak,
+// e.g. a LinearDecay or CosineAnnealing with an initial
+// or maximum rate of Peak.
+// If Schedule is nil, the rate stays at Peak.
+type WarmupSchedule struct {
+	Steps    int
+	Peak     float64
+	Schedule LearningRateSchedule
+}
+
+func (w *WarmupSchedule) Rate(step int) float64 {
+	if step < w.Steps {
+		return w.Peak * float64(step+1) / float64(w.Steps)
+	} else if w.Schedule == nil {
+		return w.Peak
+	}
+	return w.Schedule.Rate(step - w.Steps)
+}
+
+// A CyclicalPolicy specifies how the amplitude of a
+// CyclicalRate changes from one cycle to the next.
+type CyclicalPolicy int
+
+const (
+	// Triangular keeps the same amplitude for every
+	// cycle.
+	Triangular CyclicalPolicy = iota
+
+	// Triangular2 halves the amplitude after each
+	// cycle.
+	Triangular2
+)
+
+// CyclicalRate is a LearningRateSchedule which moves
+// the learning rate linearly from BaseRate up to
+// MaxRate and back down again, as described in
+// "Cyclical Learning Rates for Training Neural
+// Networks" (Smith, 2015).
+//
+// HalfCycle is the number of steps it takes to go from
+// BaseRate to MaxRate, so each cycle lasts for
+// 2*HalfCycle steps.
+// Rate panics if HalfCycle is not positive.
+type CyclicalRate struct {
+	BaseRate  float64
+	MaxRate   float64
+	HalfCycle int
+	Policy    CyclicalPolicy
+}
+
+func (c *CyclicalRate) Rate(step int) float64 {
+	if c.HalfCycle <= 0 {
+		panic("cyclical rate half cycle must be positive")
+	}
+	cycle := step / (2 * c.HalfCycle)
+	x := math.Abs(float64(step-cycle*2*c.HalfCycle)/float64(c.HalfCycle) - 1)
+	amplitude := c.MaxRate - c.BaseRate
+	if c.Policy == Triangular2 {
+		amplitude /= math.Pow(2, float64(cycle))
+	}
+	return c.BaseRate + amplitude*(1-x)
+}
+
+// ScheduledGradienter is a Gradienter which scales the
+// gradients of another Gradienter by the learning rate
+// from a LearningRateSchedule.
+// It should be used with a step size of 1, e.g. in
+// sgd.SGD.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type ScheduledGradienter struct {
+	Gradienter sgd.Gradienter
+	Schedule   LearningRateSchedule
+
+	// Step is the index of the next step, which is
+	// incremented every time Transform is called.
+	Step int
+}
+
+func (s *ScheduledGradienter) Gradient(set sgd.SampleSet) autofunc.Gradient {
+	return s.Transform(s.Gradienter.Gradient(set))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (s *ScheduledGradienter) WrappedGradienter() sgd.Gradienter {
+	return s.Gradienter
+}
+
+// CurrentRate returns the learning rate which the next
+// call to Transform will use.
+func (s *ScheduledGradienter) CurrentRate() float64 {
+	return s.Schedule.Rate(s.Step)
+}
+
+func (s *ScheduledGradienter) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	grad.Scale(s.Schedule.Rate(s.Step))
+	s.Step++
+	return grad
+}
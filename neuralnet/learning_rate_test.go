@@ -0,0 +1,110 @@
+package neuralnet
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStepDecay(t *testing.T) {
+	schedule := &StepDecay{Initial: 0.1, Factor: 0.5, Interval: 3}
+	expected := []float64{0.1, 0.1, 0.1, 0.05, 0.05, 0.05, 0.025}
+	testLearningRateSchedule(t, schedule, expected)
+
+	schedule = &StepDecay{Initial: 0.1, Factor: 0.5}
+	expected = []float64{0.1, 0.05, 0.025}
+	testLearningRateSchedule(t, schedule, expected)
+}
+
+func TestExponentialDecay(t *testing.T) {
+	schedule := &ExponentialDecay{Initial: 2, Factor: 0.5}
+	expected := []float64{2, 1, 0.5, 0.25}
+	testLearningRateSchedule(t, schedule, expected)
+}
+
+func TestCosineAnnealing(t *testing.T) {
+	schedule := &CosineAnnealing{MaxRate: 1, MinRate: 0, Period: 4}
+	expected := []float64{1, 0.5 + math.Sqrt2/4, 0.5, 0.5 - math.Sqrt2/4, 1, 0.5 + math.Sqrt2/4}
+	testLearningRateSchedule(t, schedule, expected)
+
+	schedule = &CosineAnnealing{MaxRate: 1, MinRate: 0.5, Period: 2, PeriodFactor: 2}
+	expected = []float64{1, 0.75, 1, 0.5 + 0.25*(1+math.Sqrt2/2), 0.75,
+		0.5 + 0.25*(1-math.Sqrt2/2), 1}
+	testLearningRateSchedule(t, schedule, expected)
+}
+
+func TestLinearDecay(t *testing.T) {
+	schedule := &LinearDecay{Initial: 1, Final: 0.2, Steps: 4}
+	expected := []float64{1, 0.8, 0.6, 0.4, 0.2, 0.2}
+	testLearningRateSchedule(t, schedule, expected)
+}
+
+func TestWarmupSchedule(t *testing.T) {
+	schedule := &WarmupSchedule{
+		Steps:    4,
+		Peak:     2,
+		Schedule: &LinearDecay{Initial: 2, Final: 1, Steps: 2},
+	}
+	expected := []float64{0.5, 1, 1.5, 2, 2, 1.5, 1, 1}
+	testLearningRateSchedule(t, schedule, expected)
+
+	schedule.Schedule = nil
+	expected = []float64{0.5, 1, 1.5, 2, 2, 2}
+	testLearningRateSchedule(t, schedule, expected)
+}
+
+func TestCyclicalRate(t *testing.T) {
+	schedule := &CyclicalRate{BaseRate: 0.1, MaxRate: 0.5, HalfCycle: 2}
+	expected := []float64{0.1, 0.3, 0.5, 0.3, 0.1, 0.3, 0.5, 0.3, 0.1}
+	testLearningRateSchedule(t, schedule, expected)
+
+	schedule.Policy = Triangular2
+	expected = []float64{0.1, 0.3, 0.5, 0.3, 0.1, 0.2, 0.3, 0.2, 0.1, 0.15, 0.2}
+	testLearningRateSchedule(t, schedule, expected)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for zero half cycle")
+		}
+	}()
+	(&CyclicalRate{BaseRate: 0.1, MaxRate: 0.5}).Rate(3)
+}
+
+func TestCosineAnnealingInvalid(t *testing.T) {
+	schedules := []*CosineAnnealing{
+		{MaxRate: 1, Period: 10, PeriodFactor: 0.5},
+		{MaxRate: 1, Period: 0, PeriodFactor: 2},
+		{MaxRate: 1, Period: 0},
+	}
+	for i, schedule := range schedules {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("schedule %d: expected panic", i)
+				}
+			}()
+			schedule.Rate(100)
+		}()
+	}
+}
+
+func TestScheduledGradienter(t *testing.T) {
+	net := optimizerTestNetwork()
+	g := &ScheduledGradienter{Schedule: &ExponentialDecay{Initial: 1, Factor: 0.5}}
+	for _, scale := range []float64{1, 0.5, 0.25} {
+		grad := randomOptimizerGradient(net)
+		expected := grad.Copy()
+		expected.Scale(scale)
+		if rate := g.CurrentRate(); math.Abs(rate-scale) > 1e-8 {
+			t.Errorf("expected current rate %f but got %f", scale, rate)
+		}
+		checkOptimizerGradients(t, net, g.Transform(grad), expected)
+	}
+}
+
+func testLearningRateSchedule(t *testing.T, s LearningRateSchedule, expected []float64) {
+	for i, x := range expected {
+		if actual := s.Rate(i); math.Abs(actual-x) > 1e-8 {
+			t.Errorf("step %d: expected %f but got %f", i, x, actual)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package neuralnet
+
+import (
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+const (
+	defaultLineSearchInitialStep   = 1
+	defaultLineSearchShrink        = 0.5
+	defaultLineSearchSufficient    = 1e-4
+	defaultLineSearchMaxIterations = 20
+)
+
+// A LineSearch picks step sizes by backtracking: it
+// tries a step along the negative gradient, and shrinks
+// the step until the cost decreases sufficiently.
+//
+// A step of size t is accepted once it satisfies the
+// Armijo condition, where Sufficient is c:
+//
+//	cost(x - t*g) <= cost(x) - c*t*|g|^2
+//
+// This removes the need to tune a learning rate for
+// small, full-batch problems, at the cost of evaluating
+// the cost function several times per step.
+type LineSearch struct {
+	// InitialStep is the first step size to try.
+	// If it is 0, 1 is used.
+	InitialStep float64
+
+	// Shrink is the factor by which the step size is
+	// multiplied after each rejected step.
+	// If it is 0, 0.5 is used.
+	Shrink float64
+
+	// Sufficient is the fraction of the decrease
+	// predicted by the gradient which a step must
+	// achieve.
+	// If it is 0, a reasonable default is used.
+	Sufficient float64
+
+	// MaxIterations is the maximum number of step sizes
+	// to try.
+	// If it is 0, a reasonable default is used.
+	MaxIterations int
+}
+
+// Step applies a step to the parameters in grad,
+// choosing its size by backtracking on the total cost
+// of the Network on the samples.
+//
+// It returns the step size which was applied.
+// If no step size decreased the cost sufficiently
+// within MaxIterations tries, the parameters are left
+// unchanged and Step returns 0.
+func (l *LineSearch) Step(n Network, c CostFunc, s sgd.SampleSet,
+	grad autofunc.Gradient) float64 {
+	vars := make([]*autofunc.Variable, 0, len(grad))
+	backup := make([]linalg.Vector, 0, len(grad))
+	var sqNorm float64
+	for variable, g := range grad {
+		vars = append(vars, variable)
+		backup = append(backup, variable.Vector.Copy())
+		sqNorm += g.Dot(g)
+	}
+	restore := func() {
+		for i, variable := range vars {
+			copy(variable.Vector, backup[i])
+		}
+	}
+
+	batcher := n.BatchLearner()
+	initialCost := TotalCostBatcher(c, batcher, s, 0)
+	step := l.initialStep()
+	for i := 0; i < l.maxIterations(); i++ {
+		grad.AddToVars(-step)
+		cost := TotalCostBatcher(c, batcher, s, 0)
+		if cost <= initialCost-l.sufficient()*step*sqNorm {
+			return step
+		}
+		restore()
+		step *= l.shrink()
+	}
+	return 0
+}
+
+func (l *LineSearch) initialStep() float64 {
+	if l.InitialStep == 0 {
+		return defaultLineSearchInitialStep
+	}
+	return l.InitialStep
+}
+
+func (l *LineSearch) shrink() float64 {
+	if l.Shrink == 0 {
+		return defaultLineSearchShrink
+	}
+	return l.Shrink
+}
+
+func (l *LineSearch) sufficient() float64 {
+	if l.Sufficient == 0 {
+		return defaultLineSearchSufficient
+	}
+	return l.Sufficient
+}
+
+func (l *LineSearch) maxIterations() int {
+	if l.MaxIterations == 0 {
+		return defaultLineSearchMaxIterations
+	}
+	return l.MaxIterations
+}
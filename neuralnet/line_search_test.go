@@ -0,0 +1,59 @@
+package neuralnet
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestLineSearchStep(t *testing.T) {
+	rand.Seed(123)
+	var inputs, outputs []linalg.Vector
+	for i := 0; i < 20; i++ {
+		in := linalg.Vector{rand.NormFloat64(), rand.NormFloat64()}
+		inputs = append(inputs, in)
+		outputs = append(outputs, linalg.Vector{3*in[0] - in[1] + 1})
+	}
+	samples := VectorSampleSet(inputs, outputs)
+	net := Network{NewDenseLayer(2, 1)}
+	gradienter := &BatchRGradienter{
+		Learner:  net.BatchLearner(),
+		CostFunc: MeanSquaredCost{},
+	}
+
+	search := &LineSearch{}
+	lastCost := TotalCost(MeanSquaredCost{}, net, samples)
+	for i := 0; i < 10; i++ {
+		step := search.Step(net, MeanSquaredCost{}, samples, gradienter.Gradient(samples))
+		if step <= 0 || step > 1 {
+			t.Fatalf("step %d: invalid step size %f", i, step)
+		}
+		cost := TotalCost(MeanSquaredCost{}, net, samples)
+		if cost >= lastCost {
+			t.Fatalf("step %d: cost went from %f to %f", i, lastCost, cost)
+		}
+		lastCost = cost
+	}
+}
+
+func TestLineSearchNoDecrease(t *testing.T) {
+	samples := VectorSampleSet([]linalg.Vector{{1}}, []linalg.Vector{{2}})
+	net := Network{NewDenseLayer(1, 1)}
+	grad := (&BatchRGradienter{
+		Learner:  net.BatchLearner(),
+		CostFunc: MeanSquaredCost{},
+	}).Gradient(samples)
+
+	// Stepping along the gradient itself can only
+	// increase the cost.
+	grad.Scale(-1)
+	params := net.ParamsVector()
+	search := &LineSearch{MaxIterations: 5}
+	if step := search.Step(net, MeanSquaredCost{}, samples, grad); step != 0 {
+		t.Errorf("expected step size 0 but got %f", step)
+	}
+	if net.ParamsVector().Scale(-1).Add(params).MaxAbs() != 0 {
+		t.Error("parameters were modified")
+	}
+}
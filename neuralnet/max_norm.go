@@ -0,0 +1,58 @@
+package neuralnet
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/sgd"
+)
+
+// MaxNormConstraint rescales each row of the weight
+// matrix (i.e. the incoming weights of each neuron) so
+// that its L2 norm does not exceed maxNorm.
+// Rows which are already small enough, as well as the
+// biases, are left untouched.
+func (d *DenseLayer) MaxNormConstraint(maxNorm float64) {
+	if d.Weights == nil {
+		panic(uninitPanicMessage)
+	}
+	weights := d.Weights.Data.Vector
+	for row := 0; row < d.OutputCount; row++ {
+		rowVec := weights[row*d.InputCount : (row+1)*d.InputCount]
+		if norm := math.Sqrt(rowVec.Dot(rowVec)); norm > maxNorm {
+			rowVec.Scale(maxNorm / norm)
+		}
+	}
+}
+
+// A MaxNormConstrainer is a Gradienter which enforces
+// MaxNormConstraint on a list of DenseLayers.
+//
+// Since SGD applies each step before requesting the
+// next gradient, the constraint is enforced before
+// every gradient computation, and hence after every
+// step but the last.
+// Call Constrain once training finishes to enforce it
+// after the final step as well.
+type MaxNormConstrainer struct {
+	Gradienter sgd.Gradienter
+	Layers     []*DenseLayer
+	MaxNorm    float64
+}
+
+func (m *MaxNormConstrainer) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	m.Constrain()
+	return m.Gradienter.Gradient(s)
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (m *MaxNormConstrainer) WrappedGradienter() sgd.Gradienter {
+	return m.Gradienter
+}
+
+// Constrain applies MaxNormConstraint to every layer.
+func (m *MaxNormConstrainer) Constrain() {
+	for _, layer := range m.Layers {
+		layer.MaxNormConstraint(m.MaxNorm)
+	}
+}
@@ -0,0 +1,55 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+func TestDenseMaxNormConstraint(t *testing.T) {
+	layer := NewDenseLayer(3, 2)
+	copy(layer.Weights.Data.Vector, []float64{3, 0, 4, 0.1, 0.2, -0.2})
+	biases := layer.Biases.Var.Vector.Copy()
+	layer.MaxNormConstraint(1)
+
+	expected := []float64{0.6, 0, 0.8, 0.1, 0.2, -0.2}
+	for i, x := range expected {
+		if math.Abs(layer.Weights.Data.Vector[i]-x) > 1e-8 {
+			t.Errorf("weight %d should be %f but got %f", i, x, layer.Weights.Data.Vector[i])
+		}
+	}
+	if !reflect.DeepEqual(layer.Biases.Var.Vector, biases) {
+		t.Error("biases were modified")
+	}
+}
+
+func TestMaxNormConstrainer(t *testing.T) {
+	rand.Seed(123)
+	var inputs, outputs []linalg.Vector
+	for i := 0; i < 20; i++ {
+		in := linalg.Vector{rand.NormFloat64(), rand.NormFloat64()}
+		inputs = append(inputs, in)
+		outputs = append(outputs, linalg.Vector{10*in[0] - 10*in[1]})
+	}
+	samples := VectorSampleSet(inputs, outputs)
+	layer := NewDenseLayer(2, 1)
+	net := Network{layer}
+	constrainer := &MaxNormConstrainer{
+		Gradienter: &BatchRGradienter{
+			Learner:  net.BatchLearner(),
+			CostFunc: MeanSquaredCost{},
+		},
+		Layers:  []*DenseLayer{layer},
+		MaxNorm: 2,
+	}
+	sgd.SGD(constrainer, samples, 0.01, 20, 5)
+	constrainer.Constrain()
+	w := layer.Weights.Data.Vector
+	if norm := math.Sqrt(w.Dot(w)); norm > 2+1e-8 {
+		t.Errorf("weight norm %f exceeds 2", norm)
+	}
+}
@@ -13,6 +13,9 @@ import (
 // of an input tensor by returning the maximum value
 // from each of many small two-dimensional regions
 // in each depth layer of the input tensor.
+//
+// By default, the regions do not overlap, but a
+// stride may be set to make them overlap.
 type MaxPoolingLayer struct {
 	// XSpan indicates how many consecutive
 	// horizontal inputs correspond to a pool.
@@ -33,6 +36,16 @@ type MaxPoolingLayer struct {
 	// InputDepth indicates the depth of the
 	// layer's input tensor.
 	InputDepth int
+
+	// XStride is the horizontal distance between
+	// the starts of consecutive pools.
+	// If it is 0, XSpan is used.
+	XStride int
+
+	// YStride is the vertical distance between
+	// the starts of consecutive pools.
+	// If it is 0, YSpan is used.
+	YStride int
 }
 
 // DeserializeMaxPoolingLayer deserializes a MaxPoolingLayer.
@@ -46,20 +59,12 @@ func DeserializeMaxPoolingLayer(d []byte) (*MaxPoolingLayer, error) {
 
 // OutputWidth returns the output tensor width.
 func (m *MaxPoolingLayer) OutputWidth() int {
-	w := m.InputWidth / m.XSpan
-	if (m.InputWidth % m.XSpan) != 0 {
-		w++
-	}
-	return w
+	return poolOutputSize(m.InputWidth, m.XSpan, m.xStride())
 }
 
 // OutputHeight returns the output tensor height.
 func (m *MaxPoolingLayer) OutputHeight() int {
-	h := m.InputHeight / m.YSpan
-	if (m.InputHeight % m.YSpan) != 0 {
-		h++
-	}
-	return h
+	return poolOutputSize(m.InputHeight, m.YSpan, m.yStride())
 }
 
 // Apply applies the layer to an input, which is treated
@@ -121,6 +126,16 @@ func (m *MaxPoolingLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
 	return res
 }
 
+// InputSize returns the length of each input.
+func (m *MaxPoolingLayer) InputSize() int {
+	return m.InputWidth * m.InputHeight * m.InputDepth
+}
+
+// OutputSize returns the length of each output.
+func (m *MaxPoolingLayer) OutputSize() int {
+	return m.OutputWidth() * m.OutputHeight() * m.InputDepth
+}
+
 // Serialize serializes the layer.
 func (m *MaxPoolingLayer) Serialize() ([]byte, error) {
 	return json.Marshal(m)
@@ -135,13 +150,13 @@ func (m *MaxPoolingLayer) SerializerType() string {
 func (m *MaxPoolingLayer) evaluate(in *tensor.Float64, out *tensor.Float64) poolChoiceMap {
 	choices := newPoolChoiceMap(m.OutputWidth(), m.OutputHeight(), m.InputDepth)
 	for y := 0; y < out.Height; y++ {
-		poolY := y * m.YSpan
+		poolY := y * m.yStride()
 		maxY := poolY + m.YSpan - 1
 		if maxY >= in.Height {
 			maxY = in.Height - 1
 		}
 		for x := 0; x < out.Width; x++ {
-			poolX := x * m.XSpan
+			poolX := x * m.xStride()
 			maxX := poolX + m.XSpan - 1
 			if maxX >= in.Width {
 				maxX = in.Width - 1
@@ -156,6 +171,20 @@ func (m *MaxPoolingLayer) evaluate(in *tensor.Float64, out *tensor.Float64) pool
 	return choices
 }
 
+func (m *MaxPoolingLayer) xStride() int {
+	if m.XStride == 0 {
+		return m.XSpan
+	}
+	return m.XStride
+}
+
+func (m *MaxPoolingLayer) yStride() int {
+	if m.YStride == 0 {
+		return m.YSpan
+	}
+	return m.YStride
+}
+
 func (m *MaxPoolingLayer) inputTensor(inVec linalg.Vector) *tensor.Float64 {
 	return &tensor.Float64{
 		Width:  m.InputWidth,
@@ -248,6 +277,27 @@ func (m *maxPoolingRResult) PropagateRGradient(upstream, upstreamR linalg.Vector
 	m.Input.PropagateRGradient(downstream, downstreamR, rgrad, grad)
 }
 
+// poolOutputSize computes the number of pools along an
+// axis, including a partial pool at the end if the pools
+// do not evenly cover the input.
+//
+// If the stride is larger than the span, there are gaps
+// between the pools, and only pools which start inside
+// the input are counted.
+func poolOutputSize(inSize, span, stride int) int {
+	if inSize <= span {
+		return 1
+	}
+	if stride > span {
+		return (inSize-1)/stride + 1
+	}
+	res := (inSize - span) / stride
+	if (inSize-span)%stride != 0 {
+		res++
+	}
+	return res + 1
+}
+
 func maxInput(t *tensor.Float64, x1, x2, y1, y2, z int) (value float64, bestX, bestY int) {
 	value = math.Inf(-1)
 	for x := x1; x <= x2; x++ {
@@ -294,7 +344,8 @@ func (p poolChoiceMap) BackPropagate(downstream *tensor.Float64, upstream *tenso
 		for x, list1 := range list {
 			for z, point := range list1 {
 				val := downstream.Get(x, y, z)
-				upstream.Set(point[0], point[1], z, val)
+				oldVal := upstream.Get(point[0], point[1], z)
+				upstream.Set(point[0], point[1], z, oldVal+val)
 			}
 		}
 	}
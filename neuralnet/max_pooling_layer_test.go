@@ -13,9 +13,9 @@ import (
 
 func TestMaxPoolingDimensions(t *testing.T) {
 	layers := []*MaxPoolingLayer{
-		{3, 3, 9, 9, 5},
-		{2, 2, 9, 9, 7},
-		{4, 10, 30, 51, 2},
+		{3, 3, 9, 9, 5, 0, 0},
+		{2, 2, 9, 9, 7, 0, 0},
+		{4, 10, 30, 51, 2, 0, 0},
 	}
 	outSizes := [][3]int{
 		{3, 3, 5},
@@ -33,8 +33,53 @@ func TestMaxPoolingDimensions(t *testing.T) {
 	}
 }
 
+func TestMaxPoolingStrideDimensions(t *testing.T) {
+	layers := []*MaxPoolingLayer{
+		{3, 3, 9, 9, 5, 2, 2},
+		{3, 2, 10, 9, 7, 2, 1},
+		{4, 10, 30, 51, 2, 3, 5},
+	}
+	outSizes := [][2]int{
+		{4, 4},
+		{5, 8},
+		{10, 10},
+	}
+	for i, layer := range layers {
+		expOutSize := outSizes[i]
+		if layer.OutputWidth() != expOutSize[0] ||
+			layer.OutputHeight() != expOutSize[1] {
+			t.Errorf("test %d gave downstream size %dX%d (expected %dX%d)",
+				i, layer.OutputWidth(), layer.OutputHeight(),
+				expOutSize[0], expOutSize[1])
+		}
+	}
+}
+
+func TestMaxPoolingSparseStride(t *testing.T) {
+	layer := &MaxPoolingLayer{XSpan: 1, YSpan: 1, XStride: 5, InputWidth: 10,
+		InputHeight: 1, InputDepth: 1}
+	input := &autofunc.Variable{Vector: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+	expected := []float64{1, 6}
+	actual := layer.Apply(input).Output()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d outputs but got %d", len(expected), len(actual))
+	}
+	for i, x := range expected {
+		if actual[i] != x {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+
+	layer = &MaxPoolingLayer{XSpan: 2, YSpan: 2, XStride: 3, YStride: 4, InputWidth: 8,
+		InputHeight: 9, InputDepth: 1}
+	if layer.OutputWidth() != 3 || layer.OutputHeight() != 3 {
+		t.Errorf("expected 3X3 output but got %dX%d", layer.OutputWidth(),
+			layer.OutputHeight())
+	}
+}
+
 func TestMaxPoolingForward(t *testing.T) {
-	layer := &MaxPoolingLayer{3, 3, 10, 11, 2}
+	layer := &MaxPoolingLayer{3, 3, 10, 11, 2, 0, 0}
 
 	input := []float64{
 		0.5305, 0.7935, 0.3718, 0.4026, 0.8246, 0.6875, 0.6069, 0.0399, 0.4759, 0.3548, 0.8465, 0.0479, 0.4841, 0.1277, 0.2060, 0.6833, 0.0844, 0.0793, 0.1564, 0.2891,
@@ -69,7 +114,7 @@ func TestMaxPoolingForward(t *testing.T) {
 }
 
 func TestMaxPoolingBackward(t *testing.T) {
-	layer := &MaxPoolingLayer{3, 3, 10, 11, 2}
+	layer := &MaxPoolingLayer{3, 3, 10, 11, 2, 0, 0}
 
 	input := []float64{
 		0.5305, 0.7935, 0.3718, 0.4026, 0.8246, 0.6875, 0.6069, 0.0399, 0.4759, 0.3548, 0.8465, 0.0479, 0.4841, 0.1277, 0.2060, 0.6833, 0.0844, 0.0793, 0.1564, 0.2891,
@@ -183,8 +228,33 @@ func TestMaxPoolingBatchR(t *testing.T) {
 		n, []*autofunc.Variable{batchRes})
 }
 
+func TestMaxPoolingStrideGradients(t *testing.T) {
+	layer := &MaxPoolingLayer{
+		XSpan:       3,
+		YSpan:       2,
+		InputWidth:  8,
+		InputHeight: 7,
+		InputDepth:  2,
+		XStride:     2,
+		YStride:     1,
+	}
+	inputVar := &autofunc.Variable{Vector: make(linalg.Vector, 8*7*2)}
+	rVector := autofunc.RVector{inputVar: make(linalg.Vector, len(inputVar.Vector))}
+	for i := range inputVar.Vector {
+		inputVar.Vector[i] = rand.Float64()*2 - 1
+		rVector[inputVar][i] = rand.Float64()*2 - 1
+	}
+	funcTest := &functest.RFuncChecker{
+		F:     layer,
+		Vars:  []*autofunc.Variable{inputVar},
+		Input: inputVar,
+		RV:    rVector,
+	}
+	funcTest.FullCheck(t)
+}
+
 func TestMaxPoolingSerialize(t *testing.T) {
-	layer := &MaxPoolingLayer{3, 3, 10, 11, 2}
+	layer := &MaxPoolingLayer{3, 3, 10, 11, 2, 0, 0}
 	encoded, err := layer.Serialize()
 	if err != nil {
 		t.Fatal(err)
@@ -201,7 +271,7 @@ func TestMaxPoolingSerialize(t *testing.T) {
 }
 
 func TestMaxPoolingRProp(t *testing.T) {
-	layer := &MaxPoolingLayer{3, 3, 10, 11, 2}
+	layer := &MaxPoolingLayer{3, 3, 10, 11, 2, 0, 0}
 	input := make(linalg.Vector, 10*11*2)
 	inputR := make(linalg.Vector, len(input))
 	for i := range input {
@@ -0,0 +1,101 @@
+package neuralnet
+
+import (
+	"encoding/json"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+// MomentumOptimizer implements SGD with momentum, with
+// optional support for Nesterov accelerated gradient.
+//
+// It is like sgd.Momentum, except that its velocity is
+// stored per parameter of a Learner, so that it can be
+// serialized to resume training.
+// If Momentum is 0, gradients are left unchanged, which
+// is equivalent to plain SGD.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type MomentumOptimizer struct {
+	Gradienter sgd.Gradienter `json:"-"`
+
+	// Learner determines the order of the parameters
+	// in the velocity.
+	// It should not change once training starts.
+	Learner sgd.Learner `json:"-"`
+
+	// Momentum is the amount by which the velocity is
+	// scaled before each new gradient is added to it.
+	Momentum float64
+
+	// Nesterov indicates that each step should use the
+	// gradient plus the scaled velocity, rather than
+	// the velocity itself.
+	Nesterov bool
+
+	// Velocity stores the velocity for each of the
+	// Learner's parameters.
+	// It is set automatically after the first batch.
+	Velocity []linalg.Vector
+
+	params optimizerParams
+}
+
+// DeserializeMomentumOptimizer deserializes a
+// MomentumOptimizer.
+// The Gradienter and Learner must be set before it can
+// be used again.
+func DeserializeMomentumOptimizer(d []byte) (*MomentumOptimizer, error) {
+	var res MomentumOptimizer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (m *MomentumOptimizer) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return m.Transform(m.Gradienter.Gradient(s))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (m *MomentumOptimizer) WrappedGradienter() sgd.Gradienter {
+	return m.Gradienter
+}
+
+func (m *MomentumOptimizer) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	m.params.update(m.Learner, 0, &m.Velocity)
+	for i, vec := range learnerGradient(m.Learner, grad) {
+		velocity := m.Velocity[i]
+		for j, x := range vec {
+			velocity[j] = m.Momentum*velocity[j] + x
+			if m.Nesterov {
+				vec[j] = x + m.Momentum*velocity[j]
+			} else {
+				vec[j] = velocity[j]
+			}
+		}
+	}
+	return grad
+}
+
+// Reset clears the velocity, as if no batches had been
+// seen yet.
+func (m *MomentumOptimizer) Reset() {
+	m.Velocity = nil
+}
+
+// Serialize serializes the optimizer's hyper-parameters
+// and velocity.
+func (m *MomentumOptimizer) Serialize() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a MomentumOptimizer with the serializer package.
+func (m *MomentumOptimizer) SerializerType() string {
+	return serializerTypeMomentumOptimizer
+}
@@ -0,0 +1,71 @@
+package neuralnet
+
+import (
+	"testing"
+
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+func TestMomentumOptimizerTransform(t *testing.T) {
+	net := optimizerTestNetwork()
+	actual := &MomentumOptimizer{Learner: net, Momentum: 0.9}
+	expected := &sgd.Momentum{Momentum: 0.9}
+	testOptimizerTransform(t, net, actual, expected)
+}
+
+func TestMomentumOptimizerZero(t *testing.T) {
+	net := optimizerTestNetwork()
+	for _, nesterov := range []bool{false, true} {
+		opt := &MomentumOptimizer{Learner: net, Nesterov: nesterov}
+		for i := 0; i < 3; i++ {
+			grad := randomOptimizerGradient(net)
+			expected := grad.Copy()
+			checkOptimizerGradients(t, net, opt.Transform(grad), expected)
+		}
+	}
+}
+
+func TestMomentumOptimizerNesterov(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &MomentumOptimizer{Learner: net, Momentum: 0.5, Nesterov: true}
+	grad1 := randomOptimizerGradient(net)
+	grad2 := randomOptimizerGradient(net)
+
+	expected1 := grad1.Copy()
+	expected1.Scale(1.5)
+	checkOptimizerGradients(t, net, opt.Transform(grad1.Copy()), expected1)
+
+	// The velocity is now grad1, so it becomes
+	// 0.5*grad1 + grad2, and the step is
+	// grad2 + 0.5*(0.5*grad1 + grad2).
+	expected2 := grad1.Copy()
+	expected2.Scale(0.25)
+	scaled2 := grad2.Copy()
+	scaled2.Scale(1.5)
+	expected2.Add(scaled2)
+	checkOptimizerGradients(t, net, opt.Transform(grad2), expected2)
+}
+
+func TestMomentumOptimizerReset(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &MomentumOptimizer{Learner: net, Momentum: 0.9}
+	opt.Transform(randomOptimizerGradient(net))
+	opt.Reset()
+	grad := randomOptimizerGradient(net)
+	expected := grad.Copy()
+	checkOptimizerGradients(t, net, opt.Transform(grad), expected)
+}
+
+func TestMomentumOptimizerResume(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &MomentumOptimizer{Learner: net, Momentum: 0.8, Nesterov: true}
+	testOptimizerResume(t, net, opt, func(s serializer.Serializer) sgd.Transformer {
+		restored := s.(*MomentumOptimizer)
+		if !restored.Nesterov {
+			t.Error("expected Nesterov flag to be restored")
+		}
+		restored.Learner = net
+		return restored
+	})
+}
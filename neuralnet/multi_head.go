@@ -0,0 +1,280 @@
+package neuralnet
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+// A MultiHeadNetwork feeds its input through a shared
+// trunk, and then feeds the trunk's output to several
+// heads.
+// Since the trunk's output is pooled, the gradients
+// from every head are summed into the trunk.
+//
+// As a Layer, a MultiHeadNetwork concatenates the
+// outputs of its heads for each sample, so it can be
+// trained like any other Layer by using a MultiHeadCost
+// with targets which are likewise concatenated.
+// ApplyHeads gives the output of each head separately.
+type MultiHeadNetwork struct {
+	Trunk Network
+	Heads []Network
+}
+
+// DeserializeMultiHeadNetwork deserializes a
+// MultiHeadNetwork.
+func DeserializeMultiHeadNetwork(d []byte) (*MultiHeadNetwork, error) {
+	var trunk, heads Network
+	if err := serializer.DeserializeAny(d, &trunk, &heads); err != nil {
+		return nil, err
+	}
+	res := &MultiHeadNetwork{Trunk: trunk}
+	for _, head := range heads {
+		net, ok := head.(Network)
+		if !ok {
+			return nil, errors.New("multi-head network: head is not a Network")
+		}
+		res.Heads = append(res.Heads, net)
+	}
+	return res, nil
+}
+
+// ApplyHeads applies the network to a batch of n inputs
+// and returns the batch of outputs from each head.
+//
+// The heads share the trunk's output, but each result
+// back-propagates through the trunk separately, so
+// training should go through Batch with a
+// MultiHeadCost, which only back-propagates through the
+// trunk once.
+func (m *MultiHeadNetwork) ApplyHeads(in autofunc.Result, n int) []autofunc.Result {
+	trunkOut := m.Trunk.BatchLearner().Batch(in, n)
+	res := make([]autofunc.Result, len(m.Heads))
+	for i, head := range m.Heads {
+		res[i] = head.BatchLearner().Batch(trunkOut, n)
+	}
+	return res
+}
+
+// Apply applies the network to a single input.
+func (m *MultiHeadNetwork) Apply(in autofunc.Result) autofunc.Result {
+	return m.Batch(in, 1)
+}
+
+// ApplyR is like Apply, but for RResults.
+func (m *MultiHeadNetwork) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return m.BatchR(rv, in, 1)
+}
+
+// Batch applies the network in batch, concatenating the
+// heads' outputs for each sample.
+func (m *MultiHeadNetwork) Batch(in autofunc.Result, n int) autofunc.Result {
+	return autofunc.Pool(m.Trunk.BatchLearner().Batch(in, n),
+		func(trunkOut autofunc.Result) autofunc.Result {
+			outs := make([]autofunc.Result, len(m.Heads))
+			for i, head := range m.Heads {
+				outs[i] = head.BatchLearner().Batch(trunkOut, n)
+			}
+			return interleaveBatches(outs, n)
+		})
+}
+
+// BatchR is like Batch, but for RResults.
+func (m *MultiHeadNetwork) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	return autofunc.PoolR(m.Trunk.BatchLearner().BatchR(rv, in, n),
+		func(trunkOut autofunc.RResult) autofunc.RResult {
+			outs := make([]autofunc.RResult, len(m.Heads))
+			for i, head := range m.Heads {
+				outs[i] = head.BatchLearner().BatchR(rv, trunkOut, n)
+			}
+			return interleaveBatchesR(outs, n)
+		})
+}
+
+// Randomize randomizes the trunk and every head.
+func (m *MultiHeadNetwork) Randomize() {
+	m.RandomizeWithRand(nil)
+}
+
+// RandomizeWithRand is like Randomize, but it uses r as
+// its source of randomness.
+func (m *MultiHeadNetwork) RandomizeWithRand(r *rand.Rand) {
+	m.Trunk.RandomizeWithRand(r)
+	for _, head := range m.Heads {
+		head.RandomizeWithRand(r)
+	}
+}
+
+// Parameters returns the parameters of the trunk,
+// followed by the parameters of each head.
+func (m *MultiHeadNetwork) Parameters() []*autofunc.Variable {
+	res := m.Trunk.Parameters()
+	for _, head := range m.Heads {
+		res = append(res, head.Parameters()...)
+	}
+	return res
+}
+
+// SerializerType returns the unique ID used to serialize
+// a MultiHeadNetwork with the serializer package.
+func (m *MultiHeadNetwork) SerializerType() string {
+	return serializerTypeMultiHeadNetwork
+}
+
+// Serialize serializes the network.
+func (m *MultiHeadNetwork) Serialize() ([]byte, error) {
+	heads := make(Network, len(m.Heads))
+	for i, head := range m.Heads {
+		heads[i] = head
+	}
+	return serializer.SerializeAny(m.Trunk, heads)
+}
+
+// A MultiHeadCost is a CostFunc for the output of a
+// MultiHeadNetwork, which applies a separate CostFunc
+// to the output of each head and sums the results.
+//
+// The expected output of each sample is the
+// concatenation of the expected outputs for each head.
+// Each CostFunc is given the batch of outputs for its
+// head, so costs which handle batches themselves, such
+// as SoftmaxCECost with an OutputSize, work as usual.
+type MultiHeadCost struct {
+	// Costs contains one CostFunc for each head.
+	Costs []CostFunc
+
+	// HeadSizes contains the output size of each head.
+	HeadSizes []int
+
+	// Weights, if non-nil, scales the cost of each head.
+	Weights []float64
+}
+
+// Cost computes the total cost of every head.
+func (m *MultiHeadCost) Cost(expected linalg.Vector, actual autofunc.Result) autofunc.Result {
+	n := m.batchSize(len(expected), len(actual.Output()))
+	return autofunc.Pool(actual, func(actual autofunc.Result) autofunc.Result {
+		var res autofunc.Result
+		var offset int
+		for i, cost := range m.Costs {
+			size := m.HeadSizes[i]
+			headOut := gatherHead(actual, offset, size, n)
+			c := cost.Cost(gatherHeadVec(expected, offset, size, n), headOut)
+			if m.Weights != nil {
+				c = autofunc.Scale(c, m.Weights[i])
+			}
+			if res == nil {
+				res = c
+			} else {
+				res = autofunc.Add(res, c)
+			}
+			offset += size
+		}
+		return res
+	})
+}
+
+// CostR is like Cost, but for RResults.
+func (m *MultiHeadCost) CostR(v autofunc.RVector, expected linalg.Vector,
+	actual autofunc.RResult) autofunc.RResult {
+	n := m.batchSize(len(expected), len(actual.Output()))
+	return autofunc.PoolR(actual, func(actual autofunc.RResult) autofunc.RResult {
+		var res autofunc.RResult
+		var offset int
+		for i, cost := range m.Costs {
+			size := m.HeadSizes[i]
+			headOut := gatherHeadR(actual, offset, size, n)
+			c := cost.CostR(v, gatherHeadVec(expected, offset, size, n), headOut)
+			if m.Weights != nil {
+				c = autofunc.ScaleR(c, m.Weights[i])
+			}
+			if res == nil {
+				res = c
+			} else {
+				res = autofunc.AddR(res, c)
+			}
+			offset += size
+		}
+		return res
+	})
+}
+
+func (m *MultiHeadCost) batchSize(expectedLen, actualLen int) int {
+	if len(m.HeadSizes) != len(m.Costs) {
+		panic("head size count must match cost count")
+	} else if m.Weights != nil && len(m.Weights) != len(m.Costs) {
+		panic("weight count must match cost count")
+	} else if len(m.Costs) == 0 {
+		panic("no heads")
+	}
+	var total int
+	for _, size := range m.HeadSizes {
+		total += size
+	}
+	if expectedLen != actualLen || actualLen%total != 0 {
+		panic("invalid input size")
+	}
+	return actualLen / total
+}
+
+// interleaveBatches combines batches of n outputs from
+// several heads into a batch of n outputs, each of which
+// concatenates the heads' outputs for one sample.
+func interleaveBatches(outs []autofunc.Result, n int) autofunc.Result {
+	var parts []autofunc.Result
+	for i := 0; i < n; i++ {
+		for _, out := range outs {
+			size := len(out.Output()) / n
+			parts = append(parts, autofunc.Slice(out, i*size, (i+1)*size))
+		}
+	}
+	return autofunc.Concat(parts...)
+}
+
+func interleaveBatchesR(outs []autofunc.RResult, n int) autofunc.RResult {
+	var parts []autofunc.RResult
+	for i := 0; i < n; i++ {
+		for _, out := range outs {
+			size := len(out.Output()) / n
+			parts = append(parts, autofunc.SliceR(out, i*size, (i+1)*size))
+		}
+	}
+	return autofunc.ConcatR(parts...)
+}
+
+// gatherHead extracts the batch of outputs for one head
+// from a batch of n concatenated outputs.
+func gatherHead(out autofunc.Result, offset, size, n int) autofunc.Result {
+	stride := len(out.Output()) / n
+	parts := make([]autofunc.Result, n)
+	for i := range parts {
+		start := i*stride + offset
+		parts[i] = autofunc.Slice(out, start, start+size)
+	}
+	return autofunc.Concat(parts...)
+}
+
+func gatherHeadR(out autofunc.RResult, offset, size, n int) autofunc.RResult {
+	stride := len(out.Output()) / n
+	parts := make([]autofunc.RResult, n)
+	for i := range parts {
+		start := i*stride + offset
+		parts[i] = autofunc.SliceR(out, start, start+size)
+	}
+	return autofunc.ConcatR(parts...)
+}
+
+func gatherHeadVec(vec linalg.Vector, offset, size, n int) linalg.Vector {
+	stride := len(vec) / n
+	res := make(linalg.Vector, 0, size*n)
+	for i := 0; i < n; i++ {
+		start := i*stride + offset
+		res = append(res, vec[start:start+size]...)
+	}
+	return res
+}
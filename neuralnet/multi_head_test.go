@@ -0,0 +1,160 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+type multiHeadTestFunc struct {
+	Net  *MultiHeadNetwork
+	Cost *MultiHeadCost
+
+	Expected linalg.Vector
+	N        int
+}
+
+func (m multiHeadTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	out := m.Net.Batch(in, m.N)
+	if m.Cost == nil {
+		return out
+	}
+	return m.Cost.Cost(m.Expected, out)
+}
+
+func (m multiHeadTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	out := m.Net.BatchR(v, in, m.N)
+	if m.Cost == nil {
+		return out
+	}
+	return m.Cost.CostR(v, m.Expected, out)
+}
+
+func multiHeadTestNetwork() *MultiHeadNetwork {
+	return &MultiHeadNetwork{
+		Trunk: Network{NewDenseLayer(3, 4), &HyperbolicTangent{}},
+		Heads: []Network{
+			{NewDenseLayer(4, 2)},
+			{NewDenseLayer(4, 3), &Sigmoid{}},
+		},
+	}
+}
+
+func TestMultiHeadNetworkOutput(t *testing.T) {
+	net := multiHeadTestNetwork()
+	inputs := []linalg.Vector{{1, -0.5, 2}, {0.3, 0.2, -1}}
+	joined := &autofunc.Variable{Vector: append(inputs[0].Copy(), inputs[1]...)}
+	var expected linalg.Vector
+	for _, in := range inputs {
+		trunkOut := net.Trunk.Apply(&autofunc.Variable{Vector: in})
+		for _, head := range net.Heads {
+			expected = append(expected, head.Apply(trunkOut).Output()...)
+		}
+	}
+	actual := net.Batch(joined, 2).Output()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d outputs but got %d", len(expected), len(actual))
+	}
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d: expected %f but got %f", i, x, actual[i])
+		}
+	}
+
+	offsets, sizes := []int{0, 2}, []int{2, 3}
+	for i, head := range net.ApplyHeads(joined, 2) {
+		for j, x := range head.Output() {
+			idx := (j/sizes[i])*5 + offsets[i] + j%sizes[i]
+			if math.Abs(actual[idx]-x) > 1e-8 {
+				t.Errorf("head %d output %d: expected %f but got %f", i, j, actual[idx], x)
+			}
+		}
+	}
+}
+
+func TestMultiHeadNetworkGradients(t *testing.T) {
+	net := multiHeadTestNetwork()
+	cost := &MultiHeadCost{
+		Costs:     []CostFunc{MeanSquaredCost{}, CrossEntropyCost{}},
+		HeadSizes: []int{2, 3},
+		Weights:   []float64{0.5, 2},
+	}
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 6)}
+	expected := linalg.Vector{1, -1, 0, 1, 0, 0.5, 2, 1, 0, 0}
+	params := append(net.Parameters(), input)
+	rVec := autofunc.RVector{}
+	for _, p := range params {
+		rVec[p] = make(linalg.Vector, len(p.Vector))
+		for i := range rVec[p] {
+			rVec[p][i] = rand.NormFloat64()
+		}
+	}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+	}
+	for _, c := range []*MultiHeadCost{nil, cost} {
+		checker := &functest.RFuncChecker{
+			F:     multiHeadTestFunc{Net: net, Cost: c, Expected: expected, N: 2},
+			Vars:  params,
+			Input: input,
+			RV:    rVec,
+		}
+		checker.FullCheck(t)
+	}
+}
+
+func TestMultiHeadCostSum(t *testing.T) {
+	net := multiHeadTestNetwork()
+	input := &autofunc.Variable{Vector: linalg.Vector{1, -0.5, 2, 0.3, 0.2, -1}}
+	expected := linalg.Vector{1, -1, 0, 1, 0, 0.5, 2, 1, 0, 0}
+	headExpected := []linalg.Vector{{1, -1, 0.5, 2}, {0, 1, 0, 1, 0, 0}}
+	costs := []CostFunc{MeanSquaredCost{}, CrossEntropyCost{}}
+	cost := &MultiHeadCost{Costs: costs, HeadSizes: []int{2, 3}}
+
+	grad := autofunc.NewGradient(net.Trunk.Parameters())
+	out := cost.Cost(expected, net.Batch(input, 2))
+	out.PropagateGradient(linalg.Vector{1}, grad)
+
+	expGrad := autofunc.NewGradient(net.Trunk.Parameters())
+	var expCost float64
+	for i, headOut := range net.ApplyHeads(input, 2) {
+		headCost := costs[i].Cost(headExpected[i], headOut)
+		expCost += headCost.Output()[0]
+		headCost.PropagateGradient(linalg.Vector{1}, expGrad)
+	}
+	if math.Abs(out.Output()[0]-expCost) > 1e-8 {
+		t.Errorf("expected cost %f but got %f", expCost, out.Output()[0])
+	}
+	for variable, g := range expGrad {
+		if diff := g.Copy().Scale(-1).Add(grad[variable]).MaxAbs(); diff > 1e-8 {
+			t.Errorf("trunk gradient differs by %f", diff)
+		}
+	}
+}
+
+func TestMultiHeadNetworkSerialize(t *testing.T) {
+	net := multiHeadTestNetwork()
+	data, err := net.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(net.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newNet, ok := decoded.(*MultiHeadNetwork)
+	if !ok {
+		t.Fatalf("expected *MultiHeadNetwork but got %T", decoded)
+	}
+	input := &autofunc.Variable{Vector: []float64{1, -2, 0.5}}
+	expected := net.Apply(input).Output()
+	actual := newNet.Apply(input).Output()
+	if actual.Copy().Scale(-1).Add(expected).MaxAbs() != 0 {
+		t.Errorf("expected output %v but got %v", expected, actual)
+	}
+}
@@ -7,6 +7,15 @@
 // itself a Layer. All Layers can be serialized, so
 // saving a neural network to a file is easy.
 //
+// Every Layer takes and returns flat vectors.
+// Layers which operate on tensors, such as ConvLayer
+// and MaxPoolingLayer, store them in row-major order
+// with the depth components of each location adjacent
+// to one another, so the entry at (x, y, z) is at index
+// (x+y*width)*depth+z.
+// As a result, tensor layers can feed directly into
+// DenseLayers without any flattening or reshaping.
+//
 // To train a network, you can use the sgd package at
 // https://github.com/unixpickle/sgd or the Hessian Free
 // package at https://github.com/unixpickle/hessfree.
@@ -44,8 +53,11 @@ package neuralnet
 
 import (
 	"errors"
+	"fmt"
+	"math/rand"
 
 	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
 	"github.com/unixpickle/serializer"
 	"github.com/unixpickle/sgd"
 )
@@ -57,6 +69,64 @@ import (
 // and Parameters() methods.
 type Network []Layer
 
+// NewNetwork creates a Network from a list of layers.
+//
+// For every pair of adjacent layers whose input and
+// output sizes are known (e.g. SizedLayers, or nested
+// Networks of them), NewNetwork verifies that the output
+// size of the first layer matches the input size of
+// the second, returning an error if it does not.
+// Layers with unknown sizes, such as activation
+// functions, are assumed to preserve the size of
+// their input, except for layers which are known to
+// change it (e.g. an EmbeddingLayer), after which the
+// size is unknown until the next layer whose sizes
+// are known.
+// It also verifies that the wrapped layers of every
+// ResidualLayer and StochasticDepthLayer preserve the
+// size of their input, and that every TiedDenseLayer is
+// tied to a DenseLayer.
+func NewNetwork(layers ...Layer) (Network, error) {
+	lastSize := -1
+	for i, layer := range layers {
+		var residual Network
+		switch layer := layer.(type) {
+		case *ResidualLayer:
+			residual = layer.Network
+		case *StochasticDepthLayer:
+			residual = layer.Network
+		}
+		if residual != nil {
+			inSize, outSize, ok := layerSizes(residual)
+			if ok && inSize != outSize {
+				return nil, fmt.Errorf("residual layer %d maps %d inputs to %d outputs",
+					i, inSize, outSize)
+			}
+		}
+		if tied, ok := layer.(*TiedDenseLayer); ok && tied.Tied == nil {
+			return nil, fmt.Errorf("layer %d is a TiedDenseLayer which is not tied", i)
+		}
+		if concat, ok := layer.(*ConcatLayer); ok {
+			if err := checkConcatSizes(concat); err != nil {
+				return nil, fmt.Errorf("concat layer %d: %s", i, err)
+			}
+		}
+		inSize, outSize, ok := layerSizes(layer)
+		if !ok {
+			if !preservesSize(layer) {
+				lastSize = -1
+			}
+			continue
+		}
+		if lastSize >= 0 && inSize != lastSize {
+			return nil, fmt.Errorf("layer %d expects %d inputs but gets %d",
+				i, inSize, lastSize)
+		}
+		lastSize = outSize
+	}
+	return Network(layers), nil
+}
+
 func DeserializeNetwork(data []byte) (Network, error) {
 	var res Network
 
@@ -73,6 +143,10 @@ func DeserializeNetwork(data []byte) (Network, error) {
 		}
 	}
 
+	if err := tieLayers(res); err != nil {
+		return nil, err
+	}
+
 	return res, nil
 }
 
@@ -86,6 +160,142 @@ func (n Network) Randomize() {
 	}
 }
 
+// SetTraining calls SetTraining on every TrainingLayer
+// in n, including the layers of nested Networks,
+// ResidualLayers, StochasticDepthLayers, ConcatLayers,
+// and MultiHeadNetworks.
+func (n Network) SetTraining(training bool) {
+	for _, layer := range n {
+		switch layer := layer.(type) {
+		case *ResidualLayer:
+			layer.Network.SetTraining(training)
+		case *StochasticDepthLayer:
+			layer.Network.SetTraining(training)
+		case *ConcatLayer:
+			Network(layer.Layers).SetTraining(training)
+		case *MultiHeadNetwork:
+			layer.Trunk.SetTraining(training)
+			for _, head := range layer.Heads {
+				head.SetTraining(training)
+			}
+		}
+		if t, ok := layer.(TrainingLayer); ok {
+			t.SetTraining(training)
+		}
+	}
+}
+
+// RandomizeWithRand is like Randomize, but it passes r
+// to every layer that implements RandRandomizer.
+// Layers which only implement Randomizer use the
+// global math/rand source.
+func (n Network) RandomizeWithRand(r *rand.Rand) {
+	for _, layer := range n {
+		if rr, ok := layer.(RandRandomizer); ok {
+			rr.RandomizeWithRand(r)
+		} else if rr, ok := layer.(Randomizer); ok {
+			rr.Randomize()
+		}
+	}
+}
+
+// Clone creates a deep copy of n, in which no layer
+// shares parameters or other state with the original.
+// TiedDenseLayers in the copy are tied to the copies of
+// their DenseLayers.
+//
+// Layers which implement Cloner are copied with their
+// Clone method, and other layers are copied by
+// serializing and deserializing them.
+func (n Network) Clone() (Network, error) {
+	res := make(Network, len(n))
+	for i, layer := range n {
+		switch layer := layer.(type) {
+		case Cloner:
+			res[i] = layer.Clone()
+		case Network:
+			sub, err := layer.Clone()
+			if err != nil {
+				return nil, err
+			}
+			res[i] = sub
+		default:
+			copied, err := serializer.Copy(layer)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = copied.(Layer)
+		}
+	}
+	if err := tieLayers(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Predict applies n to a single input and returns a
+// copy of the output.
+// If the input size of n can be determined (see
+// NewNetwork), Predict returns an error for inputs of
+// the wrong length instead of panicking.
+//
+// Predict may be called from many goroutines at once;
+// see Layer for details.
+func (n Network) Predict(input linalg.Vector) (linalg.Vector, error) {
+	outputs, err := n.PredictBatch([]linalg.Vector{input})
+	if err != nil {
+		return nil, err
+	}
+	return outputs[0], nil
+}
+
+// PredictBatch is like Predict, but it applies n to
+// many inputs at once as a single batch.
+func (n Network) PredictBatch(inputs []linalg.Vector) ([]linalg.Vector, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	inSize, known := inputSize(n)
+	var joined linalg.Vector
+	for i, in := range inputs {
+		if known && len(in) != inSize {
+			return nil, fmt.Errorf("input %d has length %d but expected %d",
+				i, len(in), inSize)
+		} else if len(in) != len(inputs[0]) {
+			return nil, fmt.Errorf("input %d has length %d but input 0 has %d",
+				i, len(in), len(inputs[0]))
+		}
+		joined = append(joined, in...)
+	}
+	batcher := n.BatchLearner()
+	output := batcher.Batch(&autofunc.Variable{Vector: joined}, len(inputs)).Output()
+	outSize := len(output) / len(inputs)
+	res := make([]linalg.Vector, len(inputs))
+	for i := range res {
+		res[i] = output[i*outSize : (i+1)*outSize].Copy()
+	}
+	return res, nil
+}
+
+// InputGradient computes the gradient of a scalar with
+// respect to the input of n, given the gradient of that
+// scalar with respect to the output (outGrad).
+// For example, outGrad might be the gradient of a cost
+// function, in which case the result could be used to
+// craft adversarial examples or saliency maps.
+//
+// The parameters of n are treated as constants.
+func (n Network) InputGradient(input, outGrad linalg.Vector) linalg.Vector {
+	inVar := &autofunc.Variable{Vector: input}
+	output := n.Apply(inVar)
+	if len(output.Output()) != len(outGrad) {
+		panic("invalid output gradient size")
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{inVar})
+	output.PropagateGradient(outGrad.Copy(), grad)
+	return grad[inVar]
+}
+
 // Parameters concatenates the parameters of
 // every Learner in n.
 func (n Network) Parameters() []*autofunc.Variable {
@@ -98,6 +308,80 @@ func (n Network) Parameters() []*autofunc.Variable {
 	return res
 }
 
+// ParamsVector concatenates the values of every
+// parameter in n, in the order given by Parameters.
+// The result is a copy, so modifying it does not affect
+// the Network.
+func (n Network) ParamsVector() linalg.Vector {
+	var res linalg.Vector
+	for _, param := range n.Parameters() {
+		res = append(res, param.Vector...)
+	}
+	return res
+}
+
+// SetParamsVector copies a vector created by
+// ParamsVector back into the parameters of n.
+// It returns an error if the vector's length does not
+// match the total number of parameters.
+func (n Network) SetParamsVector(vec linalg.Vector) error {
+	params := n.Parameters()
+	var total int
+	for _, param := range params {
+		total += len(param.Vector)
+	}
+	if len(vec) != total {
+		return fmt.Errorf("parameter vector has length %d but expected %d", len(vec), total)
+	}
+	for _, param := range params {
+		copy(param.Vector, vec)
+		vec = vec[len(param.Vector):]
+	}
+	return nil
+}
+
+// Weights returns the weight variables of the layers in
+// n, excluding biases and other parameters which are
+// not usually regularized.
+// It includes the weights of DenseLayers which are not
+// frozen and the filters of ConvLayers, as well as the
+// weights of any nested Networks, ResidualLayers,
+// StochasticDepthLayers, ConcatLayers, or
+// MultiHeadNetworks.
+func (n Network) Weights() []*autofunc.Variable {
+	var res []*autofunc.Variable
+	for _, layer := range n {
+		switch layer := layer.(type) {
+		case *DenseLayer:
+			if layer.Weights == nil {
+				panic(uninitPanicMessage)
+			}
+			if !layer.Frozen {
+				res = append(res, layer.Weights.Data)
+			}
+		case *ConvLayer:
+			if layer.FilterVar == nil {
+				panic(uninitPanicMessage)
+			}
+			res = append(res, layer.FilterVar)
+		case *ResidualLayer:
+			res = append(res, layer.Network.Weights()...)
+		case *StochasticDepthLayer:
+			res = append(res, layer.Network.Weights()...)
+		case *MultiHeadNetwork:
+			res = append(res, layer.Trunk.Weights()...)
+			for _, head := range layer.Heads {
+				res = append(res, head.Weights()...)
+			}
+		case *ConcatLayer:
+			res = append(res, Network(layer.Layers).Weights()...)
+		case Network:
+			res = append(res, layer.Weights()...)
+		}
+	}
+	return res
+}
+
 func (n Network) Apply(in autofunc.Result) autofunc.Result {
 	for _, layer := range n {
 		in = layer.Apply(in)
@@ -105,6 +389,34 @@ func (n Network) Apply(in autofunc.Result) autofunc.Result {
 	return in
 }
 
+// ApplyCopy is like Apply, but it applies n to a copy
+// of the input vector rather than the vector itself.
+// The input may then be re-used (e.g. as a buffer for
+// the next sample) while the Result is still needed,
+// such as before the Result's gradient is propagated.
+func (n Network) ApplyCopy(in linalg.Vector) autofunc.Result {
+	return n.Apply(&autofunc.Variable{Vector: in.Copy()})
+}
+
+// LayerOutputs applies n to an input and returns the
+// output of every layer, in order, so that the last
+// Result is the output of n.
+//
+// Since activation functions are layers of their own,
+// this exposes pre-activation values (e.g. the output
+// of a DenseLayer followed by a Sigmoid), which can be
+// inspected or used in differentiable penalties.
+// Like all Results, the vectors of the Results must
+// not be modified.
+func (n Network) LayerOutputs(in autofunc.Result) []autofunc.Result {
+	res := make([]autofunc.Result, len(n))
+	for i, layer := range n {
+		in = layer.Apply(in)
+		res[i] = in
+	}
+	return res
+}
+
 func (n Network) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
 	for _, layer := range n {
 		in = layer.ApplyR(v, in)
@@ -198,3 +510,147 @@ func (n *networkBatchLearner) BatchR(v autofunc.RVector, in autofunc.RResult,
 func (n *networkBatchLearner) Parameters() []*autofunc.Variable {
 	return n.Network.Parameters()
 }
+
+// checkConcatSizes reports an error if the branches of
+// a ConcatLayer disagree with its InputSizes or, when
+// InputSizes is nil, with each other.
+func checkConcatSizes(l *ConcatLayer) error {
+	if l.InputSizes != nil && len(l.InputSizes) != len(l.Layers) {
+		return fmt.Errorf("%d input sizes for %d layers", len(l.InputSizes), len(l.Layers))
+	}
+	firstIn := -1
+	for i, sub := range l.Layers {
+		subIn, _, ok := layerSizes(sub)
+		if !ok {
+			continue
+		}
+		if l.InputSizes != nil {
+			if subIn != l.InputSizes[i] {
+				return fmt.Errorf("branch %d expects %d inputs but gets %d",
+					i, subIn, l.InputSizes[i])
+			}
+		} else if firstIn < 0 {
+			firstIn = subIn
+		} else if subIn != firstIn {
+			return fmt.Errorf("branch %d expects %d inputs but another branch expects %d",
+				i, subIn, firstIn)
+		}
+	}
+	return nil
+}
+
+// layerSizes returns the input and output sizes of a
+// layer, if they can be determined from its fields.
+func layerSizes(l Layer) (inSize, outSize int, ok bool) {
+	if t, ok := l.(*TiedDenseLayer); ok && t.Tied == nil {
+		return 0, 0, false
+	}
+	if s, ok := l.(SizedLayer); ok {
+		return s.InputSize(), s.OutputSize(), true
+	}
+	switch l := l.(type) {
+	case *ResidualLayer:
+		inSize, _, ok := layerSizes(l.Network)
+		return inSize, inSize, ok
+	case *StochasticDepthLayer:
+		inSize, _, ok := layerSizes(l.Network)
+		return inSize, inSize, ok
+	case *ConcatLayer:
+		if l.InputSizes != nil && len(l.InputSizes) != len(l.Layers) {
+			return 0, 0, false
+		}
+		for i, sub := range l.Layers {
+			subIn, subOut, subOk := layerSizes(sub)
+			if !subOk {
+				return 0, 0, false
+			}
+			if l.InputSizes != nil {
+				if l.InputSizes[i] != subIn {
+					return 0, 0, false
+				}
+				inSize += subIn
+			} else if i > 0 && subIn != inSize {
+				return 0, 0, false
+			} else {
+				inSize = subIn
+			}
+			outSize += subOut
+		}
+		return inSize, outSize, len(l.Layers) > 0
+	case *MultiHeadNetwork:
+		for i, head := range l.Heads {
+			headIn, headOut, headOk := layerSizes(head)
+			if !headOk || (i > 0 && headIn != inSize) {
+				return 0, 0, false
+			}
+			inSize = headIn
+			outSize += headOut
+		}
+		if len(l.Heads) == 0 {
+			return 0, 0, false
+		}
+		if trunkIn, trunkOut, trunkOk := layerSizes(l.Trunk); trunkOk {
+			if trunkOut != inSize {
+				return 0, 0, false
+			}
+			inSize = trunkIn
+		} else if !preservesSize(l.Trunk) {
+			return 0, 0, false
+		}
+		return inSize, outSize, true
+	case Network:
+		var inSize, outSize int
+		var found bool
+		for _, sub := range l {
+			subIn, subOut, subOk := layerSizes(sub)
+			if !subOk {
+				if !preservesSize(sub) {
+					return 0, 0, false
+				}
+				continue
+			}
+			if !found {
+				inSize = subIn
+				found = true
+			}
+			outSize = subOut
+		}
+		return inSize, outSize, found
+	}
+	return 0, 0, false
+}
+
+// inputSize returns the input size of a layer, if it
+// can be determined, even if its output size cannot.
+func inputSize(l Layer) (int, bool) {
+	if inSize, _, ok := layerSizes(l); ok {
+		return inSize, true
+	}
+	if n, ok := l.(Network); ok {
+		for _, sub := range n {
+			if inSize, ok := inputSize(sub); ok {
+				return inSize, true
+			} else if !preservesSize(sub) {
+				return 0, false
+			}
+		}
+	}
+	return 0, false
+}
+
+// preservesSize reports whether a layer whose sizes
+// cannot be determined may be assumed to preserve the
+// size of its input.
+func preservesSize(l Layer) bool {
+	switch l := l.(type) {
+	case *EmbeddingLayer, *MultiHeadNetwork, *ConcatLayer, *TiedDenseLayer:
+		return false
+	case Network:
+		for _, sub := range l {
+			if _, _, ok := layerSizes(sub); ok || !preservesSize(sub) {
+				return false
+			}
+		}
+	}
+	return true
+}
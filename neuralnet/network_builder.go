@@ -0,0 +1,100 @@
+package neuralnet
+
+import "fmt"
+
+// A NetworkBuilder constructs a Network layer by layer,
+// keeping track of the current output size so that the
+// input size of each DenseLayer can be inferred.
+// The output size becomes unknown after a layer which
+// changes it without a known output size (see
+// NewNetwork), such as an EmbeddingLayer.
+//
+// Its methods return the builder itself so that calls
+// can be chained:
+//
+//	network, err := neuralnet.NewNetworkBuilder(784).
+//	    Dense(300, &neuralnet.HyperbolicTangent{}).
+//	    Dropout(0.5).
+//	    Dense(10, nil).
+//	    Softmax().
+//	    Build()
+//
+// Errors are deferred until Build is called.
+type NetworkBuilder struct {
+	network Network
+	size    int
+	err     error
+}
+
+// NewNetworkBuilder creates a NetworkBuilder for a
+// network with the given input size.
+func NewNetworkBuilder(inputSize int) *NetworkBuilder {
+	return &NetworkBuilder{size: inputSize}
+}
+
+// Dense adds a randomized DenseLayer with the given
+// output size, followed by an activation function.
+// If activation is nil, no activation is added.
+func (n *NetworkBuilder) Dense(outputSize int, activation Layer) *NetworkBuilder {
+	if n.size < 0 {
+		n.setErr(fmt.Errorf("input size of layer %d is unknown", len(n.network)))
+		return n
+	}
+	n.Layer(NewDenseLayer(n.size, outputSize))
+	if activation != nil {
+		n.Layer(activation)
+	}
+	return n
+}
+
+// Dropout adds a DropoutLayer in training mode with the
+// given keep probability.
+func (n *NetworkBuilder) Dropout(keepProb float64) *NetworkBuilder {
+	if keepProb <= 0 || keepProb > 1 {
+		n.setErr(fmt.Errorf("invalid keep probability: %f", keepProb))
+	}
+	return n.Layer(&DropoutLayer{KeepProbability: keepProb, Training: true})
+}
+
+// Softmax adds a SoftmaxLayer.
+func (n *NetworkBuilder) Softmax() *NetworkBuilder {
+	return n.Layer(&SoftmaxLayer{})
+}
+
+// Layer adds an arbitrary layer.
+// If the layer's sizes are known (see NewNetwork), its
+// input size must match the current output size.
+func (n *NetworkBuilder) Layer(l Layer) *NetworkBuilder {
+	if inSize, outSize, ok := layerSizes(l); ok {
+		if n.size >= 0 && inSize != n.size {
+			n.setErr(fmt.Errorf("layer %d expects %d inputs but gets %d",
+				len(n.network), inSize, n.size))
+		}
+		n.size = outSize
+	} else if !preservesSize(l) {
+		n.size = -1
+	}
+	n.network = append(n.network, l)
+	return n
+}
+
+// OutputSize returns the output size of the layers that
+// have been added so far, or -1 if it is unknown.
+func (n *NetworkBuilder) OutputSize() int {
+	return n.size
+}
+
+// Build returns the resulting Network, or the first
+// error encountered while building it.
+func (n *NetworkBuilder) Build() (Network, error) {
+	if n.err != nil {
+		return nil, n.err
+	}
+	return n.network, nil
+}
+
+func (n *NetworkBuilder) setErr(err error) {
+	if n.err == nil {
+		n.err = err
+	}
+}
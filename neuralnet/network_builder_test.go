@@ -0,0 +1,52 @@
+package neuralnet
+
+import (
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestNetworkBuilder(t *testing.T) {
+	net, err := NewNetworkBuilder(5).
+		Dense(4, &HyperbolicTangent{}).
+		Dropout(0.5).
+		Dense(3, nil).
+		Softmax().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(net) != 5 {
+		t.Fatalf("expected 5 layers but got %d", len(net))
+	}
+	first := net[0].(*DenseLayer)
+	second := net[3].(*DenseLayer)
+	if first.InputCount != 5 || first.OutputCount != 4 {
+		t.Errorf("bad first layer dimensions: %d->%d", first.InputCount,
+			first.OutputCount)
+	}
+	if second.InputCount != 4 || second.OutputCount != 3 {
+		t.Errorf("bad second layer dimensions: %d->%d", second.InputCount,
+			second.OutputCount)
+	}
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 5)}
+	if out := net.Apply(input).Output(); len(out) != 3 {
+		t.Errorf("expected 3 outputs but got %d", len(out))
+	}
+}
+
+func TestNetworkBuilderErrors(t *testing.T) {
+	_, err := NewNetworkBuilder(5).Dense(4, nil).Layer(NewDenseLayer(3, 2)).Build()
+	if err == nil {
+		t.Error("expected error for mismatched layer")
+	}
+	_, err = NewNetworkBuilder(5).Dropout(0).Build()
+	if err == nil {
+		t.Error("expected error for invalid keep probability")
+	}
+	_, err = NewNetworkBuilder(3).Layer(NewEmbeddingLayer(10, 4)).Dense(2, nil).Build()
+	if err == nil {
+		t.Error("expected error for unknown input size")
+	}
+}
@@ -0,0 +1,54 @@
+package neuralnet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// networkFileMagic is the header which begins every
+// file written by SaveNetwork.
+var networkFileMagic = []byte("weakainn")
+
+// SaveNetwork serializes a Network and writes it to a
+// file, overwriting any existing file.
+//
+// The file begins with a header identifying it as a
+// network file and specifying the FormatVersion which
+// wrote it, so that LoadNetwork can reject files it
+// cannot decode.
+func SaveNetwork(path string, n Network) error {
+	data, err := n.Serialize()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.Write(networkFileMagic)
+	buf.WriteByte(FormatVersion)
+	buf.Write(data)
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// LoadNetwork reads a Network from a file that was
+// created with SaveNetwork.
+// Files written with older format versions are
+// supported, but files from newer versions are not.
+func LoadNetwork(path string) (Network, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, networkFileMagic) {
+		return nil, errors.New("load network: not a network file")
+	}
+	data = data[len(networkFileMagic):]
+	if len(data) == 0 {
+		return nil, errors.New("load network: missing version")
+	}
+	if data[0] == 0 || data[0] > FormatVersion {
+		return nil, fmt.Errorf("load network: unsupported version %d (newest is %d)",
+			data[0], FormatVersion)
+	}
+	return DeserializeNetwork(data[1:])
+}
@@ -0,0 +1,68 @@
+package neuralnet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestSaveNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network_file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "net")
+
+	net := Network{NewDenseLayer(3, 4), &Sigmoid{}, NewDenseLayer(4, 2)}
+	if err := SaveNetwork(path, net); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadNetwork(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(net) {
+		t.Fatalf("expected %d layers but got %d", len(net), len(loaded))
+	}
+	input := &autofunc.Variable{Vector: linalg.Vector{1, -0.5, 2}}
+	expected := net.Apply(input).Output()
+	actual := loaded.Apply(input).Output()
+	if actual.Copy().Scale(-1).Add(expected).MaxAbs() != 0 {
+		t.Errorf("expected output %v but got %v", expected, actual)
+	}
+}
+
+func TestLoadNetworkErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network_file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := Network{&Sigmoid{}}.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"raw":     data,
+		"version": append(append([]byte("weakainn"), FormatVersion+1), data...),
+		"empty":   []byte("weakainn"),
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadNetwork(path); err == nil {
+			t.Errorf("file %s: expected an error", name)
+		}
+	}
+	if _, err := LoadNetwork(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -1,9 +1,16 @@
 package neuralnet
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
 	"testing"
 
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
 	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
 )
 
 func TestNetworkSerialize(t *testing.T) {
@@ -43,3 +50,402 @@ func TestNetworkSerialize(t *testing.T) {
 		t.Fatalf("expected Sigmoid but got %T", decodedNet[1])
 	}
 }
+
+func TestNewNetwork(t *testing.T) {
+	_, err := NewNetwork(
+		&DenseLayer{InputCount: 3, OutputCount: 2},
+		&Sigmoid{},
+		&DenseLayer{InputCount: 2, OutputCount: 4},
+		Network{&HyperbolicTangent{}, &DenseLayer{InputCount: 4, OutputCount: 1}},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = NewNetwork(
+		&ConvLayer{
+			FilterCount:  2,
+			FilterWidth:  2,
+			FilterHeight: 2,
+			Stride:       1,
+			InputWidth:   4,
+			InputHeight:  4,
+			InputDepth:   1,
+		},
+		&Sigmoid{},
+		&DenseLayer{InputCount: 16, OutputCount: 2},
+	)
+	if err == nil {
+		t.Error("expected error for mismatched sizes")
+	}
+
+	embedding := &EmbeddingLayer{VocabSize: 10, EmbeddingSize: 4}
+	valid := [][]Layer{
+		{&DenseLayer{InputCount: 3, OutputCount: 3}, embedding,
+			&DenseLayer{InputCount: 12, OutputCount: 2}},
+		{Network{embedding, &Sigmoid{}}, &DenseLayer{InputCount: 12, OutputCount: 2}},
+		{&DenseLayer{InputCount: 3, OutputCount: 5}, &MultiHeadNetwork{
+			Heads: []Network{
+				{&DenseLayer{InputCount: 5, OutputCount: 2}},
+				{&DenseLayer{InputCount: 5, OutputCount: 1}},
+			},
+		}, &DenseLayer{InputCount: 3, OutputCount: 1}},
+	}
+	for i, layers := range valid {
+		if _, err := NewNetwork(layers...); err != nil {
+			t.Errorf("network %d: %s", i, err)
+		}
+	}
+
+	_, err = NewNetwork(
+		&DenseLayer{InputCount: 3, OutputCount: 5},
+		&MultiHeadNetwork{
+			Heads: []Network{
+				{&DenseLayer{InputCount: 5, OutputCount: 2}},
+				{&DenseLayer{InputCount: 5, OutputCount: 1}},
+			},
+		},
+		&DenseLayer{InputCount: 5, OutputCount: 1},
+	)
+	if err == nil {
+		t.Error("expected error for mismatched multi-head sizes")
+	}
+}
+
+// networkBatchTest pairs a Network with its BatchLearner
+// so that the two can be compared by testBatcher.
+type networkBatchTest struct {
+	Network
+	BatchLearner
+}
+
+func TestNetworkSetTraining(t *testing.T) {
+	dropout := &DropoutLayer{KeepProbability: 0.5}
+	noise := &GaussNoiseLayer{Stddev: 1}
+	norm := NewBatchNormLayer(2)
+	depth := &StochasticDepthLayer{Network: Network{&BatchNormLayer{}}}
+	concatDropout := &DropoutLayer{KeepProbability: 0.5}
+	headNoise := &GaussNoiseLayer{Stddev: 1}
+	net := Network{
+		dropout,
+		Network{noise},
+		&ResidualLayer{Network: Network{norm}},
+		depth,
+		&ConcatLayer{Layers: []Layer{concatDropout}},
+		&MultiHeadNetwork{Heads: []Network{{headNoise}}},
+	}
+	for _, training := range []bool{true, false} {
+		net.SetTraining(training)
+		modes := []bool{
+			dropout.Training, noise.Training, norm.Training, depth.Training,
+			depth.Network[0].(*BatchNormLayer).Training, concatDropout.Training,
+			headNoise.Training,
+		}
+		for i, mode := range modes {
+			if mode != training {
+				t.Errorf("layer %d: expected training %v", i, training)
+			}
+		}
+	}
+}
+
+func TestNetworkBatchLearner(t *testing.T) {
+	net := Network{
+		NewDenseLayer(4, 5),
+		&HyperbolicTangent{},
+		&DropoutLayer{KeepProbability: 1},
+		NewDenseLayer(5, 3),
+	}
+	learner := networkBatchTest{net, net.BatchLearner()}
+
+	n := 4
+	input := &autofunc.Variable{Vector: make(linalg.Vector, n*4)}
+	inputR := make(linalg.Vector, len(input.Vector))
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+		inputR[i] = rand.NormFloat64()
+	}
+	rv := autofunc.RVector{input: inputR}
+	params := append(net.Parameters(), input)
+
+	testBatcher(t, learner, input, n, params)
+	testRBatcher(t, rv, learner, autofunc.NewRVariable(input, rv), n, params)
+}
+
+func TestLayerParameters(t *testing.T) {
+	learners := map[Layer]int{
+		NewDenseLayer(3, 2):                                   2,
+		NewConvLayer(4, 4, 1, 2, 2, 3, 1):                     2,
+		NewBatchNormLayer(3):                                  2,
+		NewLayerNormLayer(3):                                  2,
+		&ResidualLayer{Network: Network{NewDenseLayer(3, 3)}}: 2,
+	}
+	for layer, count := range learners {
+		l, ok := layer.(sgd.Learner)
+		if !ok {
+			t.Errorf("%T should be an sgd.Learner", layer)
+			continue
+		}
+		if params := l.Parameters(); len(params) != count {
+			t.Errorf("%T: expected %d parameters but got %d", layer, count, len(params))
+		}
+	}
+	for _, layer := range []Layer{&MaxPoolingLayer{}, &AvgPoolingLayer{}, &Sigmoid{}} {
+		if _, ok := layer.(sgd.Learner); ok {
+			t.Errorf("%T should not be an sgd.Learner", layer)
+		}
+	}
+
+	var net Network
+	var expected int
+	for layer := range learners {
+		net = append(net, layer)
+		expected += len(layer.(sgd.Learner).Parameters())
+	}
+	net = append(net, &Sigmoid{})
+	if actual := len(net.Parameters()); actual != expected {
+		t.Errorf("expected %d network parameters but got %d", expected, actual)
+	}
+}
+
+func TestSizedLayers(t *testing.T) {
+	conv := NewConvLayer(5, 4, 2, 2, 3, 3, 1)
+	sizes := map[SizedLayer][2]int{
+		NewDenseLayer(3, 2):  {3, 2},
+		conv:                 {40, conv.OutputWidth() * conv.OutputHeight() * 3},
+		NewBatchNormLayer(4): {4, 4},
+		NewLayerNormLayer(5): {5, 5},
+		&MaxPoolingLayer{XSpan: 2, YSpan: 2, InputWidth: 4, InputHeight: 4,
+			InputDepth: 3}: {48, 12},
+		&AvgPoolingLayer{XSpan: 2, YSpan: 2, InputWidth: 4, InputHeight: 4,
+			InputDepth: 3}: {48, 12},
+		&BorderLayer{InputWidth: 2, InputHeight: 3, InputDepth: 2, LeftBorder: 1,
+			TopBorder: 2}: {12, 30},
+	}
+	for layer, size := range sizes {
+		if in, out := layer.InputSize(), layer.OutputSize(); in != size[0] || out != size[1] {
+			t.Errorf("%T: expected sizes %v but got [%d %d]", layer, size, in, out)
+		}
+	}
+	if _, ok := Layer(&Sigmoid{}).(SizedLayer); ok {
+		t.Error("Sigmoid should not be a SizedLayer")
+	}
+}
+
+func TestNetworkClone(t *testing.T) {
+	net := Network{
+		NewDenseLayer(3, 4),
+		&Sigmoid{},
+		Network{NewConvLayer(2, 2, 1, 1, 1, 2, 1), NewBatchNormLayer(8)},
+		NewDenseLayer(8, 2),
+	}
+	clone, err := net.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &autofunc.Variable{Vector: linalg.Vector{1, -2, 0.5}}
+	expected := net.Apply(input).Output()
+	actual := clone.Apply(input).Output()
+	if actual.Copy().Scale(-1).Add(expected).MaxAbs() > 1e-8 {
+		t.Fatalf("expected output %v but got %v", expected, actual)
+	}
+
+	origParams := net.Parameters()
+	cloneParams := clone.Parameters()
+	if len(origParams) != len(cloneParams) {
+		t.Fatalf("expected %d parameters but got %d", len(origParams), len(cloneParams))
+	}
+	for _, p := range cloneParams {
+		for i := range p.Vector {
+			p.Vector[i] = rand.NormFloat64()
+		}
+	}
+	actual = net.Apply(input).Output()
+	if actual.Copy().Scale(-1).Add(expected).MaxAbs() != 0 {
+		t.Error("modifying the clone modified the original")
+	}
+	for i, p := range origParams {
+		if p == cloneParams[i] {
+			t.Errorf("parameter %d is shared", i)
+		}
+	}
+}
+
+func TestNetworkPredict(t *testing.T) {
+	net := Network{NewDenseLayer(3, 4), &Sigmoid{}, NewDenseLayer(4, 2)}
+	inputs := []linalg.Vector{{1, -2, 0.5}, {0, 3, -1}}
+	outputs, err := net.PredictBatch(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, in := range inputs {
+		expected := net.Apply(&autofunc.Variable{Vector: in}).Output()
+		actual, err := net.Predict(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, out := range []linalg.Vector{actual, outputs[i]} {
+			if len(out) != 2 || out.Copy().Scale(-1).Add(expected).MaxAbs() > 1e-8 {
+				t.Errorf("input %d: expected %v but got %v", i, expected, out)
+			}
+		}
+	}
+
+	if _, err := net.Predict(linalg.Vector{1, 2}); err == nil {
+		t.Error("expected error for short input")
+	}
+	if _, err := (Network{&Sigmoid{}}).PredictBatch(inputs[:1]); err != nil {
+		t.Error(err)
+	}
+	if _, err := (Network{&Sigmoid{}}).PredictBatch([]linalg.Vector{{1}, {1, 2}}); err == nil {
+		t.Error("expected error for mismatched inputs")
+	}
+
+	embedNet := Network{NewEmbeddingLayer(10, 4), NewDenseLayer(12, 2)}
+	tokens := linalg.Vector{1, 2, 3}
+	expected := embedNet.Apply(&autofunc.Variable{Vector: tokens}).Output()
+	if actual, err := embedNet.Predict(tokens); err != nil {
+		t.Error(err)
+	} else if actual.Copy().Scale(-1).Add(expected).MaxAbs() > 1e-8 {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func TestNetworkPredictConcurrent(t *testing.T) {
+	net := Network{NewDenseLayer(3, 4), &Sigmoid{}, NewBatchNormLayer(4), NewDenseLayer(4, 2)}
+	inputs := make([]linalg.Vector, 8)
+	expected := make([]linalg.Vector, len(inputs))
+	for i := range inputs {
+		inputs[i] = linalg.Vector{rand.NormFloat64(), rand.NormFloat64(), rand.NormFloat64()}
+		expected[i] = net.Apply(&autofunc.Variable{Vector: inputs[i]}).Output()
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, len(inputs))
+	for i := range inputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				out, err := net.Predict(inputs[i])
+				if err != nil || out.Copy().Scale(-1).Add(expected[i]).MaxAbs() > 1e-8 {
+					errs <- fmt.Sprintf("input %d: expected %v but got %v (%v)", i,
+						expected[i], out, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestNetworkApplyCopy(t *testing.T) {
+	net := Network{NewDenseLayer(3, 2), &Sigmoid{}}
+	buffer := linalg.Vector{1, -2, 0.5}
+	expected := autofunc.NewGradient(net.Parameters())
+	net.Apply(&autofunc.Variable{Vector: buffer.Copy()}).PropagateGradient(
+		linalg.Vector{1, -1}, expected)
+
+	actual := autofunc.NewGradient(net.Parameters())
+	result := net.ApplyCopy(buffer)
+	buffer[0], buffer[1], buffer[2] = 3, 3, 3
+	result.PropagateGradient(linalg.Vector{1, -1}, actual)
+	for _, p := range net.Parameters() {
+		if actual[p].Copy().Scale(-1).Add(expected[p]).MaxAbs() > 1e-8 {
+			t.Errorf("expected gradient %v but got %v", expected[p], actual[p])
+		}
+	}
+}
+
+func TestNetworkLayerOutputs(t *testing.T) {
+	dense := NewDenseLayer(3, 2)
+	net := Network{dense, &Sigmoid{}}
+	input := &autofunc.Variable{Vector: linalg.Vector{1, -2, 0.5}}
+	outputs := net.LayerOutputs(input)
+	if len(outputs) != 2 {
+		t.Fatalf("expected 2 outputs but got %d", len(outputs))
+	}
+	sums := dense.Apply(input).Output()
+	if outputs[0].Output().Copy().Scale(-1).Add(sums).MaxAbs() > 1e-8 {
+		t.Errorf("expected sums %v but got %v", sums, outputs[0].Output())
+	}
+	final := net.Apply(input).Output()
+	if outputs[1].Output().Copy().Scale(-1).Add(final).MaxAbs() > 1e-8 {
+		t.Errorf("expected output %v but got %v", final, outputs[1].Output())
+	}
+}
+
+func TestNetworkParamsVector(t *testing.T) {
+	net := Network{NewDenseLayer(3, 4), &Sigmoid{}, NewBatchNormLayer(4), NewDenseLayer(4, 2)}
+	vec := net.ParamsVector()
+	if len(vec) != 3*4+4+4+4+4*2+2 {
+		t.Fatalf("unexpected length %d", len(vec))
+	}
+	if vec[0] != net[0].(*DenseLayer).Weights.Data.Vector[0] {
+		t.Error("unexpected parameter order")
+	}
+
+	changed := vec.Copy().Scale(2)
+	if err := net.SetParamsVector(changed); err != nil {
+		t.Fatal(err)
+	}
+	if diff := net.ParamsVector().Copy().Scale(-1).Add(changed).MaxAbs(); diff != 0 {
+		t.Error("parameters were not restored")
+	}
+	changed[0] = 1337
+	if net.ParamsVector()[0] == 1337 {
+		t.Error("parameters share memory with the given vector")
+	}
+	if err := net.SetParamsVector(changed[1:]); err == nil {
+		t.Error("expected error for short vector")
+	}
+}
+
+func TestNetworkInputGradient(t *testing.T) {
+	net := Network{NewDenseLayer(3, 4), &Sigmoid{}, NewDenseLayer(4, 2)}
+	input := linalg.Vector{1, -2, 0.5}
+	outGrad := linalg.Vector{0.3, -1}
+	actual := net.InputGradient(input, outGrad)
+
+	const epsilon = 1e-5
+	for i := range input {
+		eval := func(x float64) float64 {
+			in := input.Copy()
+			in[i] = x
+			return net.Apply(&autofunc.Variable{Vector: in}).Output().Dot(outGrad)
+		}
+		expected := (eval(input[i]+epsilon) - eval(input[i]-epsilon)) / (2 * epsilon)
+		if math.Abs(actual[i]-expected) > 1e-5 {
+			t.Errorf("partial %d should be %f but got %f", i, expected, actual[i])
+		}
+	}
+}
+
+func TestNetworkConvToDense(t *testing.T) {
+	conv := NewConvLayer(4, 4, 1, 2, 2, 3, 2)
+	dense := NewDenseLayer(conv.OutputWidth()*conv.OutputHeight()*conv.OutputDepth(), 2)
+	net, err := NewNetwork(conv, &ReLU{}, &MaxPoolingLayer{
+		XSpan:       2,
+		YSpan:       1,
+		InputWidth:  conv.OutputWidth(),
+		InputHeight: conv.OutputHeight(),
+		InputDepth:  conv.OutputDepth(),
+	}, NewDenseLayer(6, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 16)}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+	}
+	if out := net.Apply(input).Output(); len(out) != 2 {
+		t.Errorf("expected 2 outputs but got %d", len(out))
+	}
+	if _, err := NewNetwork(conv, dense); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,115 @@
+package neuralnet
+
+import (
+	"math"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// A NormalizeMode specifies how FitNormalizer should
+// normalize each feature.
+type NormalizeMode int
+
+const (
+	// Standardize gives each feature a mean of 0 and
+	// a standard deviation of 1.
+	Standardize NormalizeMode = iota
+
+	// MinMaxScale maps each feature onto the range
+	// [0, 1].
+	MinMaxScale
+)
+
+// FeatureStats computes the mean and standard
+// deviation of each component of a list of vectors.
+func FeatureStats(vecs []linalg.Vector) (mean, stddev linalg.Vector) {
+	if len(vecs) == 0 {
+		return nil, nil
+	}
+	mean = make(linalg.Vector, len(vecs[0]))
+	stddev = make(linalg.Vector, len(vecs[0]))
+	for _, v := range vecs {
+		mean.Add(v)
+	}
+	mean.Scale(1 / float64(len(vecs)))
+	for _, v := range vecs {
+		for i, x := range v {
+			diff := x - mean[i]
+			stddev[i] += diff * diff
+		}
+	}
+	for i, x := range stddev {
+		stddev[i] = math.Sqrt(x / float64(len(vecs)))
+	}
+	return
+}
+
+// FitNormalizer creates a VecRescaleLayer which
+// normalizes each component of the given vectors
+// according to the mode.
+//
+// The layer should be fit on training data only, and
+// can then be applied to other data with Rescale, or
+// placed at the start of a Network so that it is
+// serialized along with the model.
+// Features which are constant in vecs are shifted but
+// not scaled.
+func FitNormalizer(vecs []linalg.Vector, mode NormalizeMode) *VecRescaleLayer {
+	if len(vecs) == 0 {
+		panic("cannot fit normalizer to no data")
+	}
+	var offset, spread linalg.Vector
+	switch mode {
+	case Standardize:
+		offset, spread = FeatureStats(vecs)
+	case MinMaxScale:
+		offset = vecs[0].Copy()
+		spread = vecs[0].Copy()
+		for _, v := range vecs[1:] {
+			for i, x := range v {
+				offset[i] = math.Min(offset[i], x)
+				spread[i] = math.Max(spread[i], x)
+			}
+		}
+		for i, min := range offset {
+			spread[i] -= min
+		}
+	default:
+		panic("unknown normalize mode")
+	}
+
+	res := &VecRescaleLayer{
+		Biases: offset.Scale(-1),
+		Scales: make(linalg.Vector, len(spread)),
+	}
+	for i, x := range spread {
+		if x == 0 {
+			res.Scales[i] = 1
+		} else {
+			res.Scales[i] = 1 / x
+		}
+	}
+	return res
+}
+
+// Rescale applies the layer's transformation to a
+// vector, returning a new vector.
+func (v *VecRescaleLayer) Rescale(vec linalg.Vector) linalg.Vector {
+	res := make(linalg.Vector, len(vec))
+	for i, x := range vec {
+		res[i] = (x + v.Biases[i]) * v.Scales[i]
+	}
+	return res
+}
+
+// Inverse undoes the layer's transformation on a vector,
+// returning a new vector.
+// This is useful for mapping a network's predictions
+// back to the original scale of normalized targets.
+func (v *VecRescaleLayer) Inverse(vec linalg.Vector) linalg.Vector {
+	res := make(linalg.Vector, len(vec))
+	for i, x := range vec {
+		res[i] = x/v.Scales[i] - v.Biases[i]
+	}
+	return res
+}
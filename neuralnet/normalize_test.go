@@ -0,0 +1,57 @@
+package neuralnet
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestFitNormalizerStandardize(t *testing.T) {
+	vecs := []linalg.Vector{{1, 5, 3}, {3, 5, -1}, {5, 5, 1}}
+	layer := FitNormalizer(vecs, Standardize)
+	var normalized []linalg.Vector
+	for _, v := range vecs {
+		normalized = append(normalized, layer.Rescale(v))
+	}
+	mean, stddev := FeatureStats(normalized)
+	expectedStd := linalg.Vector{1, 0, 1}
+	for i := range mean {
+		if math.Abs(mean[i]) > 1e-8 {
+			t.Errorf("feature %d: expected mean 0 but got %f", i, mean[i])
+		}
+		if math.Abs(stddev[i]-expectedStd[i]) > 1e-8 {
+			t.Errorf("feature %d: expected stddev %f but got %f", i,
+				expectedStd[i], stddev[i])
+		}
+	}
+	testNormalizerInverse(t, layer, vecs)
+}
+
+func TestFitNormalizerMinMax(t *testing.T) {
+	vecs := []linalg.Vector{{1, -2}, {3, 2}, {2, 0}}
+	layer := FitNormalizer(vecs, MinMaxScale)
+	expected := []linalg.Vector{{0, 0}, {1, 1}, {0.5, 0.5}}
+	for i, v := range vecs {
+		actual := layer.Rescale(v)
+		for j, x := range expected[i] {
+			if math.Abs(actual[j]-x) > 1e-8 {
+				t.Errorf("vector %d entry %d: expected %f but got %f", i, j, x, actual[j])
+			}
+		}
+	}
+	testNormalizerInverse(t, layer, vecs)
+}
+
+func testNormalizerInverse(t *testing.T, layer *VecRescaleLayer, vecs []linalg.Vector) {
+	for i, v := range vecs {
+		asLayer := layer.Apply(&autofunc.Variable{Vector: v}).Output()
+		actual := layer.Inverse(asLayer)
+		for j, x := range v {
+			if math.Abs(actual[j]-x) > 1e-8 {
+				t.Errorf("vector %d entry %d: expected %f but got %f", i, j, x, actual[j])
+			}
+		}
+	}
+}
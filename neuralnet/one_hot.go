@@ -0,0 +1,38 @@
+package neuralnet
+
+import "github.com/unixpickle/num-analysis/linalg"
+
+// OneHot creates a vector of numClasses components in
+// which the component at index label is 1 and all the
+// other components are 0.
+func OneHot(label, numClasses int) linalg.Vector {
+	if label < 0 || label >= numClasses {
+		panic("label out of range")
+	}
+	res := make(linalg.Vector, numClasses)
+	res[label] = 1
+	return res
+}
+
+// OneHotBatch applies OneHot to each label in a list.
+func OneHotBatch(labels []int, numClasses int) []linalg.Vector {
+	res := make([]linalg.Vector, len(labels))
+	for i, label := range labels {
+		res[i] = OneHot(label, numClasses)
+	}
+	return res
+}
+
+// ArgMax returns the index of the greatest component of
+// a vector, such as a classifier's prediction.
+// Ties are broken in favor of the lowest index.
+// It returns -1 for an empty vector.
+func ArgMax(v linalg.Vector) int {
+	res := -1
+	for i, x := range v {
+		if res < 0 || x > v[res] {
+			res = i
+		}
+	}
+	return res
+}
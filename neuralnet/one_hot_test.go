@@ -0,0 +1,39 @@
+package neuralnet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestOneHotBatch(t *testing.T) {
+	actual := OneHotBatch([]int{2, 0, 1}, 3)
+	expected := []linalg.Vector{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+	for i, vec := range actual {
+		if label := ArgMax(vec); label != ArgMax(expected[i]) {
+			t.Errorf("vector %d: ArgMax gave %d", i, label)
+		}
+	}
+}
+
+func TestArgMax(t *testing.T) {
+	tests := []struct {
+		Vec      linalg.Vector
+		Expected int
+	}{
+		{linalg.Vector{}, -1},
+		{linalg.Vector{-3}, 0},
+		{linalg.Vector{0.1, 0.7, 0.2}, 1},
+		{linalg.Vector{-1, -2, -0.5}, 2},
+		{linalg.Vector{0.5, 0.5, 0.1}, 0},
+	}
+	for i, test := range tests {
+		if actual := ArgMax(test.Vec); actual != test.Expected {
+			t.Errorf("test %d: expected %d but got %d", i, test.Expected, actual)
+		}
+	}
+}
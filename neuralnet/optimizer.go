@@ -0,0 +1,107 @@
+package neuralnet
+
+import (
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+// learnerGradient returns the gradient vectors for each
+// of the learner's parameters, in order.
+// Parameters which are missing from the gradient have
+// nil entries in the result.
+//
+// Optimizers use this to store their state as slices
+// indexed by parameter, since slices (unlike gradients
+// keyed by variables) can be serialized.
+func learnerGradient(l sgd.Learner, g autofunc.Gradient) []linalg.Vector {
+	params := l.Parameters()
+	res := make([]linalg.Vector, len(params))
+	for i, p := range params {
+		res[i] = g[p]
+	}
+	return res
+}
+
+// optimizerParams records which parameter of a Learner
+// each entry of an optimizer's state belongs to, so that
+// the state can follow the parameters when they change,
+// e.g. because a layer is frozen with SetTrainable.
+type optimizerParams struct {
+	vars []*autofunc.Variable
+}
+
+// update makes every state match the learner's current
+// parameters.
+//
+// Nil states are filled with vectors of fill values.
+// If the parameters changed since the last update, the
+// entries of parameters which remain are kept, the
+// entries of removed parameters are dropped, and new
+// parameters get vectors of fill values.
+//
+// It panics if a state does not match the parameters
+// otherwise, e.g. because it was deserialized for a
+// different Learner.
+func (o *optimizerParams) update(l sgd.Learner, fill float64, states ...*[]linalg.Vector) {
+	params := l.Parameters()
+	if o.vars != nil && !sameVariables(o.vars, params) {
+		indices := map[*autofunc.Variable]int{}
+		for i, v := range o.vars {
+			indices[v] = i
+		}
+		for _, state := range states {
+			if len(*state) != len(o.vars) {
+				continue
+			}
+			remapped := make([]linalg.Vector, len(params))
+			for i, p := range params {
+				if j, ok := indices[p]; ok {
+					remapped[i] = (*state)[j]
+				} else {
+					remapped[i] = filledVector(len(p.Vector), fill)
+				}
+			}
+			*state = remapped
+		}
+	}
+	o.vars = params
+
+	for _, state := range states {
+		if *state == nil {
+			*state = make([]linalg.Vector, len(params))
+			for i, p := range params {
+				(*state)[i] = filledVector(len(p.Vector), fill)
+			}
+			continue
+		}
+		if len(*state) != len(params) {
+			panic("optimizer state does not match parameters")
+		}
+		for i, p := range params {
+			if len((*state)[i]) != len(p.Vector) {
+				panic("optimizer state does not match parameters")
+			}
+		}
+	}
+}
+
+func sameVariables(v1, v2 []*autofunc.Variable) bool {
+	if len(v1) != len(v2) {
+		return false
+	}
+	for i, v := range v1 {
+		if v != v2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func filledVector(size int, fill float64) linalg.Vector {
+	res := make(linalg.Vector, size)
+	for i := range res {
+		res[i] = fill
+	}
+	return res
+}
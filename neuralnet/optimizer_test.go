@@ -0,0 +1,155 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+func optimizerTestNetwork() Network {
+	net := Network{
+		&DenseLayer{InputCount: 3, OutputCount: 4},
+		&Sigmoid{},
+		&DenseLayer{InputCount: 4, OutputCount: 2},
+	}
+	net.Randomize()
+	return net
+}
+
+func randomOptimizerGradient(l sgd.Learner) autofunc.Gradient {
+	grad := autofunc.NewGradient(l.Parameters())
+	for _, vec := range grad {
+		for i := range vec {
+			vec[i] = rand.NormFloat64()
+		}
+	}
+	return grad
+}
+
+// testOptimizerTransform checks that two Transformers
+// produce the same outputs for a series of gradients.
+func testOptimizerTransform(t *testing.T, l sgd.Learner, actual, expected sgd.Transformer) {
+	for i := 0; i < 5; i++ {
+		grad := randomOptimizerGradient(l)
+		actualGrad := actual.Transform(grad.Copy())
+		expectedGrad := expected.Transform(grad)
+		checkOptimizerGradients(t, l, actualGrad, expectedGrad)
+	}
+}
+
+// testOptimizerResume checks that an optimizer behaves
+// the same after it is serialized and deserialized.
+// The restore function should set up the deserialized
+// optimizer so that it can be used with l.
+func testOptimizerResume(t *testing.T, l sgd.Learner, opt sgd.Transformer,
+	restore func(s serializer.Serializer) sgd.Transformer) {
+	for i := 0; i < 3; i++ {
+		opt.Transform(randomOptimizerGradient(l))
+	}
+	s := opt.(serializer.Serializer)
+	data, err := s.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(s.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := restore(decoded)
+	testOptimizerTransform(t, l, restored, opt)
+}
+
+func checkOptimizerGradients(t *testing.T, l sgd.Learner, actual, expected autofunc.Gradient) {
+	for i, param := range l.Parameters() {
+		actualVec, expectedVec := actual[param], expected[param]
+		for j, x := range expectedVec {
+			if math.Abs(actualVec[j]-x) > 1e-8 {
+				t.Fatalf("param %d entry %d: expected %f but got %f", i, j, x,
+					actualVec[j])
+			}
+		}
+	}
+}
+
+func TestOptimizerParamsUpdate(t *testing.T) {
+	net := optimizerTestNetwork()
+	var params optimizerParams
+	var state []linalg.Vector
+	params.update(net, 0, &state)
+	if len(state) != 4 {
+		t.Fatalf("expected 4 vectors but got %d", len(state))
+	}
+	state[2][0] = 3
+
+	net[0].(*DenseLayer).SetTrainable(false)
+	params.update(net, 0, &state)
+	if len(state) != 2 {
+		t.Fatalf("expected 2 vectors but got %d", len(state))
+	}
+	if state[0][0] != 3 {
+		t.Error("state of remaining parameter was not kept")
+	}
+
+	net[0].(*DenseLayer).SetTrainable(true)
+	params.update(net, 1, &state)
+	if len(state) != 4 {
+		t.Fatalf("expected 4 vectors but got %d", len(state))
+	}
+	if state[2][0] != 3 || state[0][0] != 1 {
+		t.Error("unexpected state after unfreezing")
+	}
+}
+
+func TestOptimizerFreezeDuringTraining(t *testing.T) {
+	samples := VectorSampleSet([]linalg.Vector{{1, -1, 0.5}, {0, 2, -1}},
+		[]linalg.Vector{{1, 0}, {0, 1}})
+	optimizers := map[string]func(l sgd.Learner, g sgd.Gradienter) sgd.Gradienter{
+		"Adam": func(l sgd.Learner, g sgd.Gradienter) sgd.Gradienter {
+			return &AdamOptimizer{Gradienter: g, Learner: l}
+		},
+		"RMSProp": func(l sgd.Learner, g sgd.Gradienter) sgd.Gradienter {
+			return &RMSPropOptimizer{Gradienter: g, Learner: l}
+		},
+		"Momentum": func(l sgd.Learner, g sgd.Gradienter) sgd.Gradienter {
+			return &MomentumOptimizer{Gradienter: g, Learner: l, Momentum: 0.9}
+		},
+		"Adagrad": func(l sgd.Learner, g sgd.Gradienter) sgd.Gradienter {
+			return &AdagradOptimizer{Gradienter: g, Learner: l}
+		},
+		"Adadelta": func(l sgd.Learner, g sgd.Gradienter) sgd.Gradienter {
+			return &AdadeltaOptimizer{Gradienter: g, Learner: l}
+		},
+	}
+	for name, makeOptimizer := range optimizers {
+		net := optimizerTestNetwork()
+		layer := net[0].(*DenseLayer)
+		gradienter := &BatchRGradienter{Learner: net.BatchLearner(), CostFunc: MeanSquaredCost{}}
+		trainer := &Trainer{
+			Network:    net,
+			CostFunc:   MeanSquaredCost{},
+			Gradienter: makeOptimizer(net, gradienter),
+			StepSize:   0.1,
+		}
+		var frozenWeights linalg.Vector
+		trainer.Callback = func(m EpochMetrics) bool {
+			switch m.Epoch {
+			case 1:
+				layer.SetTrainable(false)
+				frozenWeights = layer.Weights.Data.Vector.Copy()
+			case 3:
+				if !reflect.DeepEqual(frozenWeights, layer.Weights.Data.Vector) {
+					t.Errorf("%s: frozen weights changed", name)
+				}
+				layer.SetTrainable(true)
+			}
+			return true
+		}
+		trainer.Train(samples, 5)
+	}
+}
@@ -0,0 +1,225 @@
+package neuralnet
+
+import (
+	"encoding/json"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// DefaultPReLUSlope is the initial negative slope used
+// by NewPReLU.
+const DefaultPReLUSlope = 0.25
+
+// PReLU is a Layer which applies a parametric rectified
+// linear unit.
+// It is like a LeakyReLU, but its negative slopes are
+// learnable parameters.
+//
+// Input component i is scaled by Slopes[i%len(Slopes)]
+// when it is negative.
+// Thus, a PReLU with a single slope shares that slope
+// across all inputs, while a PReLU with one slope per
+// channel of an input tensor (i.e. len(Slopes) equal to
+// the tensor's depth) learns a slope for each channel.
+type PReLU struct {
+	Slopes *autofunc.Variable
+}
+
+// NewPReLU creates a PReLU with the given number of
+// slopes, each initialized to DefaultPReLUSlope.
+func NewPReLU(slopeCount int) *PReLU {
+	res := &PReLU{
+		Slopes: &autofunc.Variable{Vector: make(linalg.Vector, slopeCount)},
+	}
+	for i := range res.Slopes.Vector {
+		res.Slopes.Vector[i] = DefaultPReLUSlope
+	}
+	return res
+}
+
+// DeserializePReLU deserializes a PReLU.
+func DeserializePReLU(d []byte) (*PReLU, error) {
+	var res PReLU
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Parameters returns a slice containing the slope
+// variable.
+func (p *PReLU) Parameters() []*autofunc.Variable {
+	if p.Slopes == nil {
+		panic(uninitPanicMessage)
+	}
+	return []*autofunc.Variable{p.Slopes}
+}
+
+func (p *PReLU) Apply(r autofunc.Result) autofunc.Result {
+	inVec := r.Output()
+	p.checkInput(len(inVec))
+	slopes := p.Slopes.Vector
+	vec := make(linalg.Vector, len(inVec))
+	for i, x := range inVec {
+		if x > 0 {
+			vec[i] = x
+		} else {
+			vec[i] = x * slopes[i%len(slopes)]
+		}
+	}
+	return &preluResult{
+		OutputVec: vec,
+		Input:     r,
+		Layer:     p,
+	}
+}
+
+func (p *PReLU) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	inVec := r.Output()
+	inVecR := r.ROutput()
+	p.checkInput(len(inVec))
+	slopes := p.Slopes.Vector
+	slopesR := v[p.Slopes]
+	vec := make(linalg.Vector, len(inVec))
+	vecR := make(linalg.Vector, len(inVec))
+	for i, x := range inVec {
+		if x > 0 {
+			vec[i] = x
+			vecR[i] = inVecR[i]
+		} else {
+			slopeIdx := i % len(slopes)
+			vec[i] = x * slopes[slopeIdx]
+			vecR[i] = inVecR[i] * slopes[slopeIdx]
+			if slopesR != nil {
+				vecR[i] += x * slopesR[slopeIdx]
+			}
+		}
+	}
+	return &preluRResult{
+		OutputVec:  vec,
+		ROutputVec: vecR,
+		SlopesR:    slopesR,
+		Input:      r,
+		Layer:      p,
+	}
+}
+
+func (p *PReLU) Batch(inputs autofunc.Result, n int) autofunc.Result {
+	return p.Apply(inputs)
+}
+
+func (p *PReLU) BatchR(v autofunc.RVector, inputs autofunc.RResult, n int) autofunc.RResult {
+	return p.ApplyR(v, inputs)
+}
+
+func (p *PReLU) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *PReLU) SerializerType() string {
+	return serializerTypePReLU
+}
+
+func (p *PReLU) checkInput(inLen int) {
+	if p.Slopes == nil {
+		panic(uninitPanicMessage)
+	}
+	if len(p.Slopes.Vector) == 0 || inLen%len(p.Slopes.Vector) != 0 {
+		panic("invalid input size")
+	}
+}
+
+type preluResult struct {
+	OutputVec linalg.Vector
+	Input     autofunc.Result
+	Layer     *PReLU
+}
+
+func (p *preluResult) Output() linalg.Vector {
+	return p.OutputVec
+}
+
+func (p *preluResult) Constant(g autofunc.Gradient) bool {
+	_, ok := g[p.Layer.Slopes]
+	return !ok && p.Input.Constant(g)
+}
+
+func (p *preluResult) PropagateGradient(upstream linalg.Vector, grad autofunc.Gradient) {
+	slopes := p.Layer.Slopes.Vector
+	inVec := p.Input.Output()
+	if slopeGrad, ok := grad[p.Layer.Slopes]; ok {
+		for i, x := range inVec {
+			if x <= 0 {
+				slopeGrad[i%len(slopes)] += upstream[i] * x
+			}
+		}
+	}
+	if p.Input.Constant(grad) {
+		return
+	}
+	for i, x := range inVec {
+		if x <= 0 {
+			upstream[i] *= slopes[i%len(slopes)]
+		}
+	}
+	p.Input.PropagateGradient(upstream, grad)
+}
+
+type preluRResult struct {
+	OutputVec  linalg.Vector
+	ROutputVec linalg.Vector
+	SlopesR    linalg.Vector
+	Input      autofunc.RResult
+	Layer      *PReLU
+}
+
+func (p *preluRResult) Output() linalg.Vector {
+	return p.OutputVec
+}
+
+func (p *preluRResult) ROutput() linalg.Vector {
+	return p.ROutputVec
+}
+
+func (p *preluRResult) Constant(rg autofunc.RGradient, g autofunc.Gradient) bool {
+	_, ok := g[p.Layer.Slopes]
+	_, rok := rg[p.Layer.Slopes]
+	return !ok && !rok && p.Input.Constant(rg, g)
+}
+
+func (p *preluRResult) PropagateRGradient(upstream, upstreamR linalg.Vector,
+	rgrad autofunc.RGradient, grad autofunc.Gradient) {
+	slopes := p.Layer.Slopes.Vector
+	inVec := p.Input.Output()
+	inVecR := p.Input.ROutput()
+	slopeGrad, hasGrad := grad[p.Layer.Slopes]
+	slopeRGrad, hasRGrad := rgrad[p.Layer.Slopes]
+	for i, x := range inVec {
+		if x > 0 {
+			continue
+		}
+		slopeIdx := i % len(slopes)
+		if hasGrad {
+			slopeGrad[slopeIdx] += upstream[i] * x
+		}
+		if hasRGrad {
+			slopeRGrad[slopeIdx] += upstreamR[i]*x + upstream[i]*inVecR[i]
+		}
+	}
+	if p.Input.Constant(rgrad, grad) {
+		return
+	}
+	for i, x := range inVec {
+		if x <= 0 {
+			slopeIdx := i % len(slopes)
+			if p.SlopesR != nil {
+				upstreamR[i] = upstreamR[i]*slopes[slopeIdx] + upstream[i]*p.SlopesR[slopeIdx]
+			} else {
+				upstreamR[i] *= slopes[slopeIdx]
+			}
+			upstream[i] *= slopes[slopeIdx]
+		}
+	}
+	p.Input.PropagateRGradient(upstream, upstreamR, rgrad, grad)
+}
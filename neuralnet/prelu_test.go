@@ -0,0 +1,65 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func TestPReLUOutput(t *testing.T) {
+	layer := &PReLU{Slopes: &autofunc.Variable{Vector: []float64{0.1, 0.5}}}
+	input := &autofunc.Variable{Vector: []float64{-2, -2, 3, -1, 0.5, 4}}
+	expected := []float64{-0.2, -1, 3, -0.5, 0.5, 4}
+	actual := layer.Apply(input).Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d should be %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestPReLUGradients(t *testing.T) {
+	for _, slopeCount := range []int{1, 3} {
+		layer := NewPReLU(slopeCount)
+		input := &autofunc.Variable{Vector: make(linalg.Vector, 9)}
+		vars := append(layer.Parameters(), input)
+		rVec := autofunc.RVector{}
+		for _, v := range vars {
+			rVec[v] = make(linalg.Vector, len(v.Vector))
+			for i := range v.Vector {
+				v.Vector[i] = rand.NormFloat64()
+				rVec[v][i] = rand.NormFloat64()
+			}
+		}
+		t.Run("Gradient", func(t *testing.T) {
+			checker := &functest.FuncChecker{F: layer, Vars: vars, Input: input}
+			checker.FullCheck(t)
+		})
+		t.Run("RGradient", func(t *testing.T) {
+			checker := &functest.RFuncChecker{F: layer, Vars: vars, Input: input, RV: rVec}
+			checker.FullCheck(t)
+		})
+	}
+}
+
+func TestPReLUSerialize(t *testing.T) {
+	layer := NewPReLU(3)
+	layer.Slopes.Vector[1] = 0.7
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, layer) {
+		t.Errorf("expected %#v but got %#v", layer, decoded)
+	}
+}
@@ -0,0 +1,308 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// quantizedMax is the largest magnitude of a quantized
+// weight or input.
+// Using a symmetric range keeps zero exactly
+// representable.
+const quantizedMax = 127
+
+// A QuantizedDenseLayer is an inference-only version of
+// a DenseLayer whose weights are stored as int8 values
+// with a single scale factor for the whole layer, so
+// that each weight is approximately Scale*Weights[i].
+//
+// When the layer is applied, each input vector is also
+// quantized to int8 with its own scale.
+// The products are accumulated as integers, and the
+// final sums are converted back to floating point and
+// added to the (unquantized) biases.
+// Outputs therefore differ slightly from those of the
+// original DenseLayer.
+//
+// A QuantizedDenseLayer has no trainable parameters.
+// Gradients with respect to its input are computed as if
+// the layer were a DenseLayer with the dequantized
+// weights, ignoring the rounding of the input.
+type QuantizedDenseLayer struct {
+	InputCount  int
+	OutputCount int
+
+	// Weights stores the quantized weight matrix in
+	// row-major order, like DenseLayer.Weights.
+	Weights []int8
+
+	// Scale converts quantized weights to real values.
+	Scale float64
+
+	Biases linalg.Vector
+}
+
+// QuantizeDenseLayer creates a QuantizedDenseLayer from
+// the current weights and biases of d.
+//
+// The scale is computed from the range of the weights,
+// so that the weight with the largest magnitude maps to
+// +/-127.
+func QuantizeDenseLayer(d *DenseLayer) *QuantizedDenseLayer {
+	if d.Weights == nil || d.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	return quantizeWeights(d.Weights.Data.Vector, d.InputCount, d.OutputCount,
+		d.Biases.Var.Vector)
+}
+
+// QuantizeNetwork creates a copy of n in which every
+// DenseLayer is replaced by a QuantizedDenseLayer.
+// Other layers, including nested Networks, are shared
+// with n rather than copied.
+//
+// Since a TiedDenseLayer cannot be tied to a
+// QuantizedDenseLayer, every TiedDenseLayer is replaced
+// by a QuantizedDenseLayer as well, using the transpose
+// of its tied weights.
+// The result does not share any weights with n.
+func QuantizeNetwork(n Network) Network {
+	res := make(Network, len(n))
+	for i, layer := range n {
+		switch layer := layer.(type) {
+		case *DenseLayer:
+			res[i] = QuantizeDenseLayer(layer)
+		case *TiedDenseLayer:
+			res[i] = quantizeTiedDenseLayer(layer)
+		default:
+			res[i] = layer
+		}
+	}
+	return res
+}
+
+func quantizeTiedDenseLayer(t *TiedDenseLayer) *QuantizedDenseLayer {
+	d := t.Tied
+	if d == nil || d.Weights == nil || t.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	weights := d.Weights.Data.Vector
+	transposed := make(linalg.Vector, len(weights))
+	for i := 0; i < d.InputCount; i++ {
+		for j := 0; j < d.OutputCount; j++ {
+			transposed[i*d.OutputCount+j] = weights[j*d.InputCount+i]
+		}
+	}
+	return quantizeWeights(transposed, d.OutputCount, d.InputCount, t.Biases.Var.Vector)
+}
+
+func quantizeWeights(weights linalg.Vector, inCount, outCount int,
+	biases linalg.Vector) *QuantizedDenseLayer {
+	res := &QuantizedDenseLayer{
+		InputCount:  inCount,
+		OutputCount: outCount,
+		Weights:     make([]int8, len(weights)),
+		Scale:       weights.MaxAbs() / quantizedMax,
+		Biases:      biases.Copy(),
+	}
+	if res.Scale == 0 {
+		return res
+	}
+	for i, w := range weights {
+		res.Weights[i] = int8(math.Floor(w/res.Scale + 0.5))
+	}
+	return res
+}
+
+// DeserializeQuantizedDenseLayer deserializes a
+// QuantizedDenseLayer.
+func DeserializeQuantizedDenseLayer(d []byte) (*QuantizedDenseLayer, error) {
+	var res QuantizedDenseLayer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Apply applies the layer to a single input.
+func (q *QuantizedDenseLayer) Apply(in autofunc.Result) autofunc.Result {
+	return q.Batch(in, 1)
+}
+
+// ApplyR is like Apply, but for RResults.
+func (q *QuantizedDenseLayer) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return q.BatchR(v, in, 1)
+}
+
+// Batch applies the layer to inputs in batch.
+func (q *QuantizedDenseLayer) Batch(in autofunc.Result, n int) autofunc.Result {
+	q.checkInput(len(in.Output()), n)
+	return &quantizedDenseResult{
+		OutputVec: q.forward(in.Output(), n),
+		Input:     in,
+		Layer:     q,
+		N:         n,
+	}
+}
+
+// BatchR is like Batch, but for RResults.
+func (q *QuantizedDenseLayer) BatchR(v autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	q.checkInput(len(in.Output()), n)
+	return &quantizedDenseRResult{
+		OutputVec:  q.forward(in.Output(), n),
+		ROutputVec: q.multiply(in.ROutput(), n),
+		Input:      in,
+		Layer:      q,
+		N:          n,
+	}
+}
+
+// InputSize returns the length of each input.
+func (q *QuantizedDenseLayer) InputSize() int {
+	return q.InputCount
+}
+
+// OutputSize returns the length of each output.
+func (q *QuantizedDenseLayer) OutputSize() int {
+	return q.OutputCount
+}
+
+// Serialize serializes the layer.
+func (q *QuantizedDenseLayer) Serialize() ([]byte, error) {
+	return json.Marshal(q)
+}
+
+// SerializerType returns the unique ID used to serialize
+// this layer with the serializer package.
+func (q *QuantizedDenseLayer) SerializerType() string {
+	return serializerTypeQuantizedDenseLayer
+}
+
+func (q *QuantizedDenseLayer) checkInput(inLen, n int) {
+	if len(q.Weights) != q.InputCount*q.OutputCount || len(q.Biases) != q.OutputCount {
+		panic(uninitPanicMessage)
+	}
+	if inLen != n*q.InputCount {
+		panic("invalid input size")
+	}
+}
+
+// forward computes the outputs using integer
+// arithmetic.
+func (q *QuantizedDenseLayer) forward(in linalg.Vector, n int) linalg.Vector {
+	res := make(linalg.Vector, n*q.OutputCount)
+	quantized := make([]int8, q.InputCount)
+	for i := 0; i < n; i++ {
+		inVec := in[i*q.InputCount : (i+1)*q.InputCount]
+		outVec := res[i*q.OutputCount : (i+1)*q.OutputCount]
+		copy(outVec, q.Biases)
+		inScale := inVec.MaxAbs() / quantizedMax
+		if inScale == 0 || q.Scale == 0 {
+			continue
+		}
+		for j, x := range inVec {
+			quantized[j] = int8(math.Floor(x/inScale + 0.5))
+		}
+		outScale := inScale * q.Scale
+		for row := range outVec {
+			// With at most 127*127 per product, an int32
+			// holds the sum for over 100,000 inputs.
+			var sum int32
+			weights := q.Weights[row*q.InputCount : (row+1)*q.InputCount]
+			for j, w := range weights {
+				sum += int32(w) * int32(quantized[j])
+			}
+			outVec[row] += float64(sum) * outScale
+		}
+	}
+	return res
+}
+
+// multiply multiplies each vector in a batch by the
+// dequantized weight matrix.
+func (q *QuantizedDenseLayer) multiply(in linalg.Vector, n int) linalg.Vector {
+	res := make(linalg.Vector, n*q.OutputCount)
+	for i := 0; i < n; i++ {
+		inVec := in[i*q.InputCount : (i+1)*q.InputCount]
+		for row := 0; row < q.OutputCount; row++ {
+			var sum float64
+			weights := q.Weights[row*q.InputCount : (row+1)*q.InputCount]
+			for j, w := range weights {
+				sum += float64(w) * inVec[j]
+			}
+			res[i*q.OutputCount+row] = sum * q.Scale
+		}
+	}
+	return res
+}
+
+// multiplyTranspose multiplies each vector in a batch by
+// the transpose of the dequantized weight matrix.
+func (q *QuantizedDenseLayer) multiplyTranspose(upstream linalg.Vector, n int) linalg.Vector {
+	res := make(linalg.Vector, n*q.InputCount)
+	for i := 0; i < n; i++ {
+		outVec := res[i*q.InputCount : (i+1)*q.InputCount]
+		for row := 0; row < q.OutputCount; row++ {
+			u := upstream[i*q.OutputCount+row] * q.Scale
+			weights := q.Weights[row*q.InputCount : (row+1)*q.InputCount]
+			for j, w := range weights {
+				outVec[j] += float64(w) * u
+			}
+		}
+	}
+	return res
+}
+
+type quantizedDenseResult struct {
+	OutputVec linalg.Vector
+	Input     autofunc.Result
+	Layer     *QuantizedDenseLayer
+	N         int
+}
+
+func (q *quantizedDenseResult) Output() linalg.Vector {
+	return q.OutputVec
+}
+
+func (q *quantizedDenseResult) Constant(g autofunc.Gradient) bool {
+	return q.Input.Constant(g)
+}
+
+func (q *quantizedDenseResult) PropagateGradient(upstream linalg.Vector,
+	grad autofunc.Gradient) {
+	if !q.Input.Constant(grad) {
+		q.Input.PropagateGradient(q.Layer.multiplyTranspose(upstream, q.N), grad)
+	}
+}
+
+type quantizedDenseRResult struct {
+	OutputVec  linalg.Vector
+	ROutputVec linalg.Vector
+	Input      autofunc.RResult
+	Layer      *QuantizedDenseLayer
+	N          int
+}
+
+func (q *quantizedDenseRResult) Output() linalg.Vector {
+	return q.OutputVec
+}
+
+func (q *quantizedDenseRResult) ROutput() linalg.Vector {
+	return q.ROutputVec
+}
+
+func (q *quantizedDenseRResult) Constant(rg autofunc.RGradient, g autofunc.Gradient) bool {
+	return q.Input.Constant(rg, g)
+}
+
+func (q *quantizedDenseRResult) PropagateRGradient(upstream, upstreamR linalg.Vector,
+	rgrad autofunc.RGradient, grad autofunc.Gradient) {
+	if !q.Input.Constant(rgrad, grad) {
+		q.Input.PropagateRGradient(q.Layer.multiplyTranspose(upstream, q.N),
+			q.Layer.multiplyTranspose(upstreamR, q.N), rgrad, grad)
+	}
+}
@@ -0,0 +1,160 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func TestQuantizeDenseLayer(t *testing.T) {
+	layer := &DenseLayer{InputCount: 2, OutputCount: 2}
+	layer.Randomize()
+	copy(layer.Weights.Data.Vector, []float64{1.27, -0.5, 0.011, 0})
+	copy(layer.Biases.Var.Vector, []float64{0.25, -1})
+
+	q := QuantizeDenseLayer(layer)
+	if math.Abs(q.Scale-0.01) > 1e-12 {
+		t.Errorf("expected scale 0.01 but got %f", q.Scale)
+	}
+	if expected := []int8{127, -50, 1, 0}; !reflect.DeepEqual(q.Weights, expected) {
+		t.Errorf("expected weights %v but got %v", expected, q.Weights)
+	}
+
+	input := &autofunc.Variable{Vector: []float64{1, -2, 0, 0}}
+	expected := layer.Batch(input, 2).Output()
+	actual := q.Batch(input, 2).Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 0.02 {
+			t.Errorf("output %d should be near %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestQuantizedDenseLayerAccuracy(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	net := Network{NewDenseLayer(20, 30), &ReLU{}, NewDenseLayer(30, 5)}
+	net.RandomizeWithRand(r)
+	quantized := QuantizeNetwork(net)
+
+	var agree int
+	var maxErr float64
+	const count = 1000
+	for i := 0; i < count; i++ {
+		input := make(linalg.Vector, 20)
+		for j := range input {
+			input[j] = r.NormFloat64()
+		}
+		expected, _ := net.Predict(input)
+		actual, _ := quantized.Predict(input)
+		if ArgMax(expected) == ArgMax(actual) {
+			agree++
+		}
+		diff := actual.Copy().Scale(-1).Add(expected).MaxAbs()
+		maxErr = math.Max(maxErr, diff/expected.MaxAbs())
+	}
+	if agree < count*98/100 {
+		t.Errorf("quantized network agrees on only %d/%d predictions", agree, count)
+	}
+	if maxErr > 0.1 {
+		t.Errorf("relative output error of %f is too large", maxErr)
+	}
+}
+
+func TestQuantizedDenseLayerGradient(t *testing.T) {
+	layer := NewDenseLayer(4, 3)
+	q := QuantizeDenseLayer(layer)
+	for i, w := range q.Weights {
+		layer.Weights.Data.Vector[i] = float64(w) * q.Scale
+	}
+
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 8)}
+	inputR := make(linalg.Vector, len(input.Vector))
+	upstream := make(linalg.Vector, 6)
+	upstreamR := make(linalg.Vector, 6)
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+		inputR[i] = rand.NormFloat64()
+	}
+	for i := range upstream {
+		upstream[i] = rand.NormFloat64()
+		upstreamR[i] = rand.NormFloat64()
+	}
+	rv := autofunc.RVector{input: inputR}
+
+	expGrad := autofunc.NewGradient([]*autofunc.Variable{input})
+	expRGrad := autofunc.NewRGradient([]*autofunc.Variable{input})
+	expOut := layer.BatchR(rv, autofunc.NewRVariable(input, rv), 2)
+	expOut.PropagateRGradient(upstream.Copy(), upstreamR.Copy(), expRGrad, expGrad)
+
+	actGrad := autofunc.NewGradient([]*autofunc.Variable{input})
+	actRGrad := autofunc.NewRGradient([]*autofunc.Variable{input})
+	actOut := q.BatchR(rv, autofunc.NewRVariable(input, rv), 2)
+	actOut.PropagateRGradient(upstream.Copy(), upstreamR.Copy(), actRGrad, actGrad)
+
+	pairs := [][2]linalg.Vector{
+		{expOut.ROutput(), actOut.ROutput()},
+		{expGrad[input], actGrad[input]},
+		{expRGrad[input], actRGrad[input]},
+	}
+	for i, pair := range pairs {
+		if pair[0].Copy().Scale(-1).Add(pair[1]).MaxAbs() > 1e-8 {
+			t.Errorf("vector %d: expected %v but got %v", i, pair[0], pair[1])
+		}
+	}
+}
+
+func TestQuantizedDenseLayerSerialize(t *testing.T) {
+	layer := QuantizeDenseLayer(NewDenseLayer(3, 2))
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, layer) {
+		t.Errorf("expected %#v but got %#v", layer, decoded)
+	}
+}
+
+func TestQuantizeNetworkTied(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	encoder := NewDenseLayer(6, 3)
+	encoder.RandomizeWithRand(r)
+	net := Network{encoder, &Sigmoid{}}
+	net = append(net, NewTiedDenseLayer(net, 0))
+	for i := range net[2].(*TiedDenseLayer).Biases.Var.Vector {
+		net[2].(*TiedDenseLayer).Biases.Var.Vector[i] = r.NormFloat64()
+	}
+	quantized := QuantizeNetwork(net)
+	if _, ok := quantized[2].(*QuantizedDenseLayer); !ok {
+		t.Fatalf("expected *QuantizedDenseLayer but got %T", quantized[2])
+	}
+
+	data, err := quantized.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DeserializeNetwork(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := linalg.Vector{1, -0.5, 0.25, 2, 0, -1}
+	expected, _ := net.Predict(input)
+	for _, n := range []Network{quantized, decoded} {
+		actual, err := n.Predict(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := actual.Copy().Scale(-1).Add(expected).MaxAbs(); diff > 0.05 {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	}
+}
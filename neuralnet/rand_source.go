@@ -0,0 +1,68 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+)
+
+// randFloat64 is like rand.Float64, but it uses r if it
+// is non-nil.
+func randFloat64(r *rand.Rand) float64 {
+	if r == nil {
+		return rand.Float64()
+	}
+	return r.Float64()
+}
+
+// randNormFloat64 is like rand.NormFloat64, but it uses
+// r if it is non-nil.
+func randNormFloat64(r *rand.Rand) float64 {
+	if r == nil {
+		return rand.NormFloat64()
+	}
+	return r.NormFloat64()
+}
+
+// randLogGamma samples from a gamma distribution with
+// the given shape and a scale of 1, using r if it is
+// non-nil, and returns the logarithm of the sample.
+//
+// This uses the method from "A Simple Method for
+// Generating Gamma Variables" (Marsaglia and Tsang,
+// 2000), boosting shapes below 1.
+// The result is returned in log space because, for
+// small shapes, the boost factor u^(1/shape) often
+// underflows to 0.
+func randLogGamma(r *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		// Using 1-u keeps the argument of the log in (0, 1].
+		u := 1 - randFloat64(r)
+		return randLogGamma(r, shape+1) + math.Log(u)/shape
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := randNormFloat64(r)
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := randFloat64(r)
+		if math.Log(u) < x*x/2+d-d*v+d*math.Log(v) {
+			return math.Log(d * v)
+		}
+	}
+}
+
+// randBeta samples from a beta distribution with the
+// shape parameters a and b, using r if it is non-nil.
+//
+// The sample is X/(X+Y) for gamma samples X and Y,
+// computed from their logarithms so that it is never
+// 0/0, even for very small shapes.
+func randBeta(r *rand.Rand, a, b float64) float64 {
+	logX := randLogGamma(r, a)
+	logY := randLogGamma(r, b)
+	return 1 / (1 + math.Exp(logY-logX))
+}
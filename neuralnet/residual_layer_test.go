@@ -0,0 +1,91 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func TestResidualLayerOutput(t *testing.T) {
+	inner := Network{NewDenseLayer(3, 3), &HyperbolicTangent{}}
+	layer := &ResidualLayer{Network: inner}
+	input := &autofunc.Variable{Vector: []float64{0.5, -1, 2}}
+	expected := inner.Apply(input).Output().Copy().Add(input.Vector)
+	actual := layer.Apply(input).Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d: expected %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestResidualLayerGradients(t *testing.T) {
+	layer := &ResidualLayer{
+		Network: Network{NewDenseLayer(4, 3), &Sigmoid{}, NewDenseLayer(3, 4)},
+	}
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 4)}
+	params := append(layer.Parameters(), input)
+	rVec := autofunc.RVector{}
+	for _, p := range params {
+		rVec[p] = make(linalg.Vector, len(p.Vector))
+		for i := range rVec[p] {
+			rVec[p][i] = rand.NormFloat64()
+		}
+	}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+	}
+	t.Run("Gradient", func(t *testing.T) {
+		checker := &functest.FuncChecker{F: layer, Vars: params, Input: input}
+		checker.FullCheck(t)
+	})
+	t.Run("RGradient", func(t *testing.T) {
+		checker := &functest.RFuncChecker{F: layer, Vars: params, Input: input, RV: rVec}
+		checker.FullCheck(t)
+	})
+}
+
+func TestResidualLayerSerialize(t *testing.T) {
+	layer := &ResidualLayer{Network: Network{NewDenseLayer(2, 2), &ReLU{}}}
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLayer, ok := decoded.(*ResidualLayer)
+	if !ok {
+		t.Fatalf("expected *ResidualLayer but got %T", decoded)
+	}
+	input := &autofunc.Variable{Vector: []float64{1, -2}}
+	expected := layer.Apply(input).Output()
+	actual := newLayer.Apply(input).Output()
+	if actual.Copy().Scale(-1).Add(expected).MaxAbs() != 0 {
+		t.Errorf("expected output %v but got %v", expected, actual)
+	}
+}
+
+func TestNewNetworkResidual(t *testing.T) {
+	_, err := NewNetwork(
+		NewDenseLayer(3, 4),
+		&ResidualLayer{Network: Network{NewDenseLayer(4, 4), &ReLU{}}},
+		NewDenseLayer(4, 2),
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = NewNetwork(
+		NewDenseLayer(3, 4),
+		&ResidualLayer{Network: Network{NewDenseLayer(4, 5)}},
+	)
+	if err == nil {
+		t.Error("expected error for size-changing residual layer")
+	}
+}
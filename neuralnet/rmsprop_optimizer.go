@@ -0,0 +1,124 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+const (
+	defaultRMSPropDecayRate = 0.9
+	defaultRMSPropDamping   = 1e-8
+)
+
+// RMSPropOptimizer divides each gradient component by
+// the square root of a rolling average of the squares
+// of that component.
+//
+// It is like sgd.RMSProp, except that its rolling
+// average is stored per parameter of a Learner, so
+// that it can be serialized to resume training.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type RMSPropOptimizer struct {
+	Gradienter sgd.Gradienter `json:"-"`
+
+	// Learner determines the order of the parameters
+	// in the rolling average.
+	// It should not change once training starts.
+	Learner sgd.Learner `json:"-"`
+
+	// DecayRate is used when averaging the squares of
+	// gradient components.
+	// A rate close to 1 makes the average change slowly,
+	// while a rate close to 0 quickly forgets old values.
+	// If it is 0, a default is used.
+	DecayRate float64
+
+	// Damping is added to the rolling average before
+	// its square root is taken, preventing divisions
+	// by zero.
+	// If it is 0, a default is used.
+	Damping float64
+
+	// RollingAverage is the current average of the
+	// squares of the gradient entries for each of the
+	// Learner's parameters.
+	// It is set automatically after the first batch.
+	RollingAverage []linalg.Vector
+
+	params optimizerParams
+}
+
+// DeserializeRMSPropOptimizer deserializes an
+// RMSPropOptimizer.
+// The Gradienter and Learner must be set before it can
+// be used again.
+func DeserializeRMSPropOptimizer(d []byte) (*RMSPropOptimizer, error) {
+	var res RMSPropOptimizer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *RMSPropOptimizer) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return r.Transform(r.Gradienter.Gradient(s))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (r *RMSPropOptimizer) WrappedGradienter() sgd.Gradienter {
+	return r.Gradienter
+}
+
+func (r *RMSPropOptimizer) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	firstBatch := r.RollingAverage == nil
+	r.params.update(r.Learner, 0, &r.RollingAverage)
+
+	decay := r.decayRate()
+	damping := r.damping()
+	for i, vec := range learnerGradient(r.Learner, grad) {
+		avgVec := r.RollingAverage[i]
+		for j, x := range vec {
+			if firstBatch {
+				avgVec[j] = x * x
+			} else {
+				avgVec[j] = decay*avgVec[j] + (1-decay)*x*x
+			}
+			vec[j] = x / math.Sqrt(avgVec[j]+damping)
+		}
+	}
+
+	return grad
+}
+
+// Serialize serializes the optimizer's hyper-parameters
+// and rolling average.
+func (r *RMSPropOptimizer) Serialize() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// SerializerType returns the unique ID used to serialize
+// an RMSPropOptimizer with the serializer package.
+func (r *RMSPropOptimizer) SerializerType() string {
+	return serializerTypeRMSPropOptimizer
+}
+
+func (r *RMSPropOptimizer) decayRate() float64 {
+	if r.DecayRate == 0 {
+		return defaultRMSPropDecayRate
+	}
+	return r.DecayRate
+}
+
+func (r *RMSPropOptimizer) damping() float64 {
+	if r.Damping == 0 {
+		return defaultRMSPropDamping
+	}
+	return r.Damping
+}
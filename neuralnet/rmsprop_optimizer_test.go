@@ -0,0 +1,39 @@
+package neuralnet
+
+import (
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+func TestRMSPropOptimizerTransform(t *testing.T) {
+	net := optimizerTestNetwork()
+	actual := &RMSPropOptimizer{Learner: net, DecayRate: 0.8, Damping: 1e-300}
+	expected := &sgd.RMSProp{Resiliency: 0.8}
+	testOptimizerTransform(t, net, actual, expected)
+}
+
+func TestRMSPropOptimizerDamping(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &RMSPropOptimizer{Learner: net}
+	grad := autofunc.NewGradient(net.Parameters())
+	for _, vec := range opt.Transform(grad) {
+		for _, x := range vec {
+			if x != 0 {
+				t.Fatalf("expected zero gradient but got %f", x)
+			}
+		}
+	}
+}
+
+func TestRMSPropOptimizerResume(t *testing.T) {
+	net := optimizerTestNetwork()
+	opt := &RMSPropOptimizer{Learner: net, DecayRate: 0.95}
+	testOptimizerResume(t, net, opt, func(s serializer.Serializer) sgd.Transformer {
+		restored := s.(*RMSPropOptimizer)
+		restored.Learner = net
+		return restored
+	})
+}
@@ -1,26 +1,71 @@
 package neuralnet
 
-import "github.com/unixpickle/serializer"
+import (
+	"fmt"
+	"sync"
+
+	"github.com/unixpickle/serializer"
+)
+
+// FormatVersion is the version of the serialization
+// format used by this package.
+// It is incremented whenever a change to a layer's
+// serialized data would prevent older releases from
+// decoding it.
+// Files written by SaveNetwork record the version which
+// wrote them, and LoadNetwork rejects newer versions.
+//
+// Version 1 serialized DenseLayers as JSON, version 2
+// introduced a more compact binary encoding, and
+// version 3 added the frozen flag to DenseLayers.
+const FormatVersion = 3
 
 const (
-	serializerTypePrefix            = "github.com/unixpickle/weakai/neuralnet."
-	serializerTypeHyperbolicTangent = serializerTypePrefix + "HyperbolicTangent"
-	serializerTypeSigmoid           = serializerTypePrefix + "Sigmoid"
-	serializerTypeSin               = serializerTypePrefix + "Sin"
-	serializerTypeBorderLayer       = serializerTypePrefix + "BorderLayer"
-	serializerTypeUnstackLayer      = serializerTypePrefix + "UnstackLayer"
-	serializerTypeConvLayer         = serializerTypePrefix + "ConvLayer"
-	serializerTypeDenseLayer        = serializerTypePrefix + "DenseLayer"
-	serializerTypeMaxPoolingLayer   = serializerTypePrefix + "MaxPoolingLayer"
-	serializerTypeSoftmaxLayer      = serializerTypePrefix + "SoftmaxLayer"
-	serializerTypeLogSoftmaxLayer   = serializerTypePrefix + "LogSoftmaxLayer"
-	serializerTypeNetwork           = serializerTypePrefix + "Network"
-	serializerTypeReLU              = serializerTypePrefix + "ReLU"
-	serializerTypeRescaleLayer      = serializerTypePrefix + "RescaleLayer"
-	serializerTypeDropoutLayer      = serializerTypePrefix + "DropoutLayer"
-	serializerTypeVecRescaleLayer   = serializerTypePrefix + "VecRescaleLayer"
-	serializerTypeGaussNoiseLayer   = serializerTypePrefix + "GaussNoiseLayer"
-	serializerTypeResidualLayer     = serializerTypePrefix + "ResidualLayer"
+	serializerTypePrefix               = "github.com/unixpickle/weakai/neuralnet."
+	serializerTypeHyperbolicTangent    = serializerTypePrefix + "HyperbolicTangent"
+	serializerTypeSigmoid              = serializerTypePrefix + "Sigmoid"
+	serializerTypeSin                  = serializerTypePrefix + "Sin"
+	serializerTypeBorderLayer          = serializerTypePrefix + "BorderLayer"
+	serializerTypeUnstackLayer         = serializerTypePrefix + "UnstackLayer"
+	serializerTypeConvLayer            = serializerTypePrefix + "ConvLayer"
+	serializerTypeDenseLayer           = serializerTypePrefix + "DenseLayer"
+	serializerTypeTiedDenseLayer       = serializerTypePrefix + "TiedDenseLayer"
+	serializerTypeQuantizedDenseLayer  = serializerTypePrefix + "QuantizedDenseLayer"
+	serializerTypeMaxPoolingLayer      = serializerTypePrefix + "MaxPoolingLayer"
+	serializerTypeSoftmaxLayer         = serializerTypePrefix + "SoftmaxLayer"
+	serializerTypeLogSoftmaxLayer      = serializerTypePrefix + "LogSoftmaxLayer"
+	serializerTypeNetwork              = serializerTypePrefix + "Network"
+	serializerTypeMultiHeadNetwork     = serializerTypePrefix + "MultiHeadNetwork"
+	serializerTypeReLU                 = serializerTypePrefix + "ReLU"
+	serializerTypeLeakyReLU            = serializerTypePrefix + "LeakyReLU"
+	serializerTypeELU                  = serializerTypePrefix + "ELU"
+	serializerTypeSwish                = serializerTypePrefix + "Swish"
+	serializerTypeGELU                 = serializerTypePrefix + "GELU"
+	serializerTypeSoftplus             = serializerTypePrefix + "Softplus"
+	serializerTypeMish                 = serializerTypePrefix + "Mish"
+	serializerTypeSoftsign             = serializerTypePrefix + "Softsign"
+	serializerTypeHardSigmoid          = serializerTypePrefix + "HardSigmoid"
+	serializerTypeHardTanh             = serializerTypePrefix + "HardTanh"
+	serializerTypeRescaleLayer         = serializerTypePrefix + "RescaleLayer"
+	serializerTypeTemperatureScaler    = serializerTypePrefix + "TemperatureScaler"
+	serializerTypeDropoutLayer         = serializerTypePrefix + "DropoutLayer"
+	serializerTypeVecRescaleLayer      = serializerTypePrefix + "VecRescaleLayer"
+	serializerTypeGaussNoiseLayer      = serializerTypePrefix + "GaussNoiseLayer"
+	serializerTypeResidualLayer        = serializerTypePrefix + "ResidualLayer"
+	serializerTypeStochasticDepthLayer = serializerTypePrefix + "StochasticDepthLayer"
+	serializerTypeBatchNormLayer       = serializerTypePrefix + "BatchNormLayer"
+	serializerTypeLayerNormLayer       = serializerTypePrefix + "LayerNormLayer"
+	serializerTypeAvgPoolingLayer      = serializerTypePrefix + "AvgPoolingLayer"
+	serializerTypeAdamOptimizer        = serializerTypePrefix + "AdamOptimizer"
+	serializerTypeRMSPropOptimizer     = serializerTypePrefix + "RMSPropOptimizer"
+	serializerTypeMomentumOptimizer    = serializerTypePrefix + "MomentumOptimizer"
+	serializerTypeAdagradOptimizer     = serializerTypePrefix + "AdagradOptimizer"
+	serializerTypeAdadeltaOptimizer    = serializerTypePrefix + "AdadeltaOptimizer"
+	serializerTypeEmbeddingLayer       = serializerTypePrefix + "EmbeddingLayer"
+	serializerTypeConcatLayer          = serializerTypePrefix + "ConcatLayer"
+	serializerTypePReLU                = serializerTypePrefix + "PReLU"
+	serializerTypeEMA                  = serializerTypePrefix + "EMA"
+	serializerTypeCosineLayer          = serializerTypePrefix + "CosineLayer"
 )
 
 func init() {
@@ -32,6 +77,34 @@ func init() {
 		func(d []byte) (serializer.Serializer, error) {
 			return &ReLU{}, nil
 		})
+	serializer.RegisterTypedDeserializer(serializerTypeLeakyReLU,
+		DeserializeLeakyReLU)
+	serializer.RegisterTypedDeserializer(serializerTypeELU,
+		DeserializeELU)
+	serializer.RegisterTypedDeserializer(serializerTypeSwish,
+		DeserializeSwish)
+	serializer.RegisterDeserializer(serializerTypeGELU,
+		func(d []byte) (serializer.Serializer, error) {
+			return &GELU{}, nil
+		})
+	serializer.RegisterDeserializer(serializerTypeSoftplus,
+		func(d []byte) (serializer.Serializer, error) {
+			return &Softplus{}, nil
+		})
+	serializer.RegisterDeserializer(serializerTypeMish,
+		func(d []byte) (serializer.Serializer, error) {
+			return &Mish{}, nil
+		})
+	serializer.RegisterDeserializer(serializerTypeSoftsign,
+		func(d []byte) (serializer.Serializer, error) {
+			return &Softsign{}, nil
+		})
+	serializer.RegisterDeserializer(serializerTypeHardSigmoid,
+		func(d []byte) (serializer.Serializer, error) {
+			return &HardSigmoid{}, nil
+		})
+	serializer.RegisterTypedDeserializer(serializerTypeHardTanh,
+		DeserializeHardTanh)
 	serializer.RegisterDeserializer(serializerTypeHyperbolicTangent,
 		func(d []byte) (serializer.Serializer, error) {
 			return &HyperbolicTangent{}, nil
@@ -44,8 +117,14 @@ func init() {
 		DeserializeConvLayer)
 	serializer.RegisterTypedDeserializer(serializerTypeDenseLayer,
 		DeserializeDenseLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeTiedDenseLayer,
+		DeserializeTiedDenseLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeQuantizedDenseLayer,
+		DeserializeQuantizedDenseLayer)
 	serializer.RegisterTypedDeserializer(serializerTypeNetwork,
 		DeserializeNetwork)
+	serializer.RegisterTypedDeserializer(serializerTypeMultiHeadNetwork,
+		DeserializeMultiHeadNetwork)
 	serializer.RegisterTypedDeserializer(serializerTypeBorderLayer,
 		DeserializeBorderLayer)
 	serializer.RegisterTypedDeserializer(serializerTypeSoftmaxLayer,
@@ -58,6 +137,8 @@ func init() {
 		DeserializeUnstackLayer)
 	serializer.RegisterTypedDeserializer(serializerTypeRescaleLayer,
 		DeserializeRescaleLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeTemperatureScaler,
+		DeserializeTemperatureScaler)
 	serializer.RegisterTypedDeserializer(serializerTypeDropoutLayer,
 		DeserializeDropoutLayer)
 	serializer.RegisterTypedDeserializer(serializerTypeVecRescaleLayer,
@@ -66,4 +147,68 @@ func init() {
 		DeserializeGaussNoiseLayer)
 	serializer.RegisterTypedDeserializer(serializerTypeResidualLayer,
 		DeserializeResidualLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeStochasticDepthLayer,
+		DeserializeStochasticDepthLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeBatchNormLayer,
+		DeserializeBatchNormLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeLayerNormLayer,
+		DeserializeLayerNormLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeAvgPoolingLayer,
+		DeserializeAvgPoolingLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeAdamOptimizer,
+		DeserializeAdamOptimizer)
+	serializer.RegisterTypedDeserializer(serializerTypeRMSPropOptimizer,
+		DeserializeRMSPropOptimizer)
+	serializer.RegisterTypedDeserializer(serializerTypeMomentumOptimizer,
+		DeserializeMomentumOptimizer)
+	serializer.RegisterTypedDeserializer(serializerTypeAdagradOptimizer,
+		DeserializeAdagradOptimizer)
+	serializer.RegisterTypedDeserializer(serializerTypeAdadeltaOptimizer,
+		DeserializeAdadeltaOptimizer)
+	serializer.RegisterTypedDeserializer(serializerTypeEmbeddingLayer,
+		DeserializeEmbeddingLayer)
+	serializer.RegisterTypedDeserializer(serializerTypeConcatLayer,
+		DeserializeConcatLayer)
+	serializer.RegisterTypedDeserializer(serializerTypePReLU,
+		DeserializePReLU)
+	serializer.RegisterTypedDeserializer(serializerTypeEMA,
+		DeserializeEMA)
+	serializer.RegisterTypedDeserializer(serializerTypeCosineLayer,
+		DeserializeCosineLayer)
+}
+
+var registerLock sync.Mutex
+
+// RegisterLayer registers a deserializer for a custom
+// Layer type, so that Networks (and other containers,
+// like ResidualLayers) which include it can be
+// deserialized.
+// The typeName must match the SerializerType of the
+// layer.
+//
+// Registration must happen before any data containing
+// the layer is deserialized, since deserialization
+// fails for unregistered types.
+// Typically, a package which defines a custom layer
+// registers it in an init function.
+//
+// An error is returned if typeName is already in use.
+func RegisterLayer(typeName string, fn func([]byte) (Layer, error)) error {
+	registerLock.Lock()
+	defer registerLock.Unlock()
+	if serializer.GetDeserializer(typeName) != nil {
+		return fmt.Errorf("register layer: type already registered: %s", typeName)
+	}
+	serializer.RegisterDeserializer(typeName, func(d []byte) (serializer.Serializer, error) {
+		return fn(d)
+	})
+	return nil
+}
+
+// RegisterActivation is like RegisterLayer, but it is
+// meant for custom activation functions.
+// In this package, activation functions are ordinary
+// Layers.
+func RegisterActivation(typeName string, deserialize func([]byte) (Layer, error)) error {
+	return RegisterLayer(typeName, deserialize)
 }
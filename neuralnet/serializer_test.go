@@ -0,0 +1,80 @@
+package neuralnet
+
+import (
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/serializer"
+)
+
+const testSquareActivationType = "github.com/unixpickle/weakai/neuralnet.testSquare"
+
+// testSquareActivation is a custom activation which is
+// registered by the tests, like a third-party one.
+type testSquareActivation struct{}
+
+func (_ testSquareActivation) Apply(r autofunc.Result) autofunc.Result {
+	return autofunc.Square(r)
+}
+
+func (_ testSquareActivation) ApplyR(v autofunc.RVector, r autofunc.RResult) autofunc.RResult {
+	return autofunc.SquareR(r)
+}
+
+func (_ testSquareActivation) Serialize() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (_ testSquareActivation) SerializerType() string {
+	return testSquareActivationType
+}
+
+func TestRegisterActivation(t *testing.T) {
+	deserialize := func(d []byte) (Layer, error) {
+		return testSquareActivation{}, nil
+	}
+	if err := RegisterActivation(testSquareActivationType, deserialize); err != nil {
+		t.Fatal(err)
+	}
+	defer serializer.UpdateDeserializer(testSquareActivationType, nil)
+
+	if err := RegisterActivation(testSquareActivationType, deserialize); err == nil {
+		t.Error("expected error for duplicate registration")
+	}
+	if err := RegisterActivation(serializerTypeSigmoid, deserialize); err == nil {
+		t.Error("expected error for built-in type")
+	}
+
+	net := Network{NewDenseLayer(2, 2), testSquareActivation{}}
+	data, err := serializer.SerializeWithType(net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.DeserializeWithType(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(Network)[1].(testSquareActivation); !ok {
+		t.Errorf("expected testSquareActivation but got %T", decoded.(Network)[1])
+	}
+}
+
+func TestRegisterLayer(t *testing.T) {
+	const typeName = testSquareActivationType + "Layer"
+	deserialize := func(d []byte) (Layer, error) {
+		return testSquareActivation{}, nil
+	}
+	if err := RegisterLayer(typeName, deserialize); err != nil {
+		t.Fatal(err)
+	}
+	defer serializer.UpdateDeserializer(typeName, nil)
+	if err := RegisterLayer(typeName, deserialize); err == nil {
+		t.Error("expected error for duplicate registration")
+	}
+	layer, err := serializer.GetDeserializer(typeName)(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := layer.(testSquareActivation); !ok {
+		t.Errorf("expected testSquareActivation but got %T", layer)
+	}
+}
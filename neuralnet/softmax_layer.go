@@ -7,6 +7,12 @@ import (
 	"github.com/unixpickle/num-analysis/linalg"
 )
 
+// SoftmaxLayer is a layer which applies the softmax
+// function with a given temperature.
+//
+// Before exponentiating, the largest input is subtracted
+// from every input, which does not change the result but
+// prevents the exponentials from overflowing.
 type SoftmaxLayer autofunc.Softmax
 
 func DeserializeSoftmaxLayer(d []byte) (*SoftmaxLayer, error) {
@@ -18,13 +24,25 @@ func DeserializeSoftmaxLayer(d []byte) (*SoftmaxLayer, error) {
 }
 
 func (s *SoftmaxLayer) Apply(in autofunc.Result) autofunc.Result {
-	soft := (*autofunc.Softmax)(s)
-	return soft.Apply(in)
+	if s.Temperature != 0 && s.Temperature != 1 {
+		in = autofunc.Scale(in, 1/s.Temperature)
+	}
+	exps := autofunc.Exp{}.Apply(autofunc.AddScaler(in, -maxVecValue(in.Output())))
+	return autofunc.Pool(exps, func(exps autofunc.Result) autofunc.Result {
+		sum := autofunc.SumAll(exps)
+		return autofunc.ScaleFirst(exps, autofunc.Inverse(sum))
+	})
 }
 
 func (s *SoftmaxLayer) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
-	soft := (*autofunc.Softmax)(s)
-	return soft.ApplyR(v, in)
+	if s.Temperature != 0 && s.Temperature != 1 {
+		in = autofunc.ScaleR(in, 1/s.Temperature)
+	}
+	exps := autofunc.Exp{}.ApplyR(v, autofunc.AddScalerR(in, -maxVecValue(in.Output())))
+	return autofunc.PoolR(exps, func(exps autofunc.RResult) autofunc.RResult {
+		sum := autofunc.SumAllR(exps)
+		return autofunc.ScaleFirstR(exps, autofunc.InverseR(sum))
+	})
 }
 
 func (s *SoftmaxLayer) Serialize() ([]byte, error) {
@@ -79,6 +97,13 @@ func (s *LogSoftmaxLayer) SerializerType() string {
 	return serializerTypeLogSoftmaxLayer
 }
 
+func maxVecValue(v linalg.Vector) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	return v[maxVecIdx(v)]
+}
+
 func maxVecIdx(v linalg.Vector) int {
 	var maxVal float64
 	var maxIdx int
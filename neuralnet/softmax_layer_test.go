@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
 )
 
 func TestSoftmaxLayerOutput(t *testing.T) {
@@ -29,6 +31,32 @@ func TestSoftmaxLayerOutput(t *testing.T) {
 	}
 }
 
+func TestSoftmaxLayerLargeInputs(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{1000, 999, -1000}}
+	output := (&SoftmaxLayer{}).Apply(input).Output()
+	expOutput := []float64{1 / (1 + math.Exp(-1)), 1 / (1 + math.E), 0}
+	for i, x := range expOutput {
+		actual := output[i]
+		if math.IsNaN(actual) || math.Abs(actual-x) > 1e-5 {
+			t.Errorf("invalid output %d: got %f expected %f", i, actual, x)
+		}
+	}
+}
+
+func TestSoftmaxLayerGradients(t *testing.T) {
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 5)}
+	rVec := autofunc.RVector{input: make(linalg.Vector, len(input.Vector))}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+		rVec[input][i] = rand.NormFloat64()
+	}
+	vars := []*autofunc.Variable{input}
+	for _, layer := range []*SoftmaxLayer{{}, {Temperature: 2}} {
+		checker := &functest.RFuncChecker{F: layer, Vars: vars, Input: input, RV: rVec}
+		checker.FullCheck(t)
+	}
+}
+
 func TestLogSoftmaxLayerOutput(t *testing.T) {
 	input := &autofunc.Variable{
 		Vector: []float64{
@@ -50,6 +78,32 @@ func TestLogSoftmaxLayerOutput(t *testing.T) {
 	}
 }
 
+func TestLogSoftmaxLayerStability(t *testing.T) {
+	input := &autofunc.Variable{Vector: []float64{1000, 0, -1000, 999}}
+	output := (&LogSoftmaxLayer{}).Apply(input)
+	logSum := 1000 + math.Log1p(math.Exp(-1))
+	expected := []float64{1000 - logSum, -logSum, -1000 - logSum, 999 - logSum}
+	for i, x := range expected {
+		if math.Abs(output.Output()[i]-x) > 1e-8 {
+			t.Errorf("output %d: expected %f but got %f", i, x, output.Output()[i])
+		}
+	}
+
+	cost := NLLCost{}.Cost(linalg.Vector{0, 0, 0, 1}, output)
+	if actual := cost.Output()[0]; math.Abs(actual-(logSum-999)) > 1e-8 {
+		t.Errorf("expected cost %f but got %f", logSum-999, actual)
+	}
+	grad := autofunc.NewGradient([]*autofunc.Variable{input})
+	cost.PropagateGradient(linalg.Vector{1}, grad)
+	softmax := 1 / (1 + math.Exp(-1))
+	expGrad := []float64{softmax, 0, 0, -softmax}
+	for i, x := range expGrad {
+		if math.Abs(grad[input][i]-x) > 1e-8 {
+			t.Errorf("gradient %d: expected %f but got %f", i, x, grad[input][i])
+		}
+	}
+}
+
 func BenchmarkSoftmaxForward(b *testing.B) {
 	rand.Seed(123)
 	inputVec := make([]float64, 3000)
@@ -0,0 +1,124 @@
+package neuralnet
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+// SpectralNorm estimates the spectral norm (i.e. the
+// largest singular value) of the weight matrix using
+// the given number of power iterations, which must be
+// at least 1.
+//
+// The estimated singular vector is cached in the layer
+// and reused by the next call, so when the weights only
+// change slightly between calls (e.g. once per training
+// step), a single iteration per call is usually enough.
+// Because of this cache, SpectralNorm should not be
+// called concurrently on the same layer.
+func (d *DenseLayer) SpectralNorm(iterations int) float64 {
+	if d.Weights == nil {
+		panic(uninitPanicMessage)
+	}
+	if iterations < 1 {
+		panic("at least one iteration is required")
+	}
+	if len(d.spectralVec) != d.InputCount {
+		d.spectralVec = make(linalg.Vector, d.InputCount)
+		for i := range d.spectralVec {
+			d.spectralVec[i] = randNormFloat64(nil)
+		}
+	}
+
+	weights := d.Weights.Data.Vector
+	vec := d.spectralVec
+	left := make(linalg.Vector, d.OutputCount)
+	var norm float64
+	for i := 0; i < iterations; i++ {
+		for row := range left {
+			left[row] = weights[row*d.InputCount : (row+1)*d.InputCount].Dot(vec)
+		}
+		leftNorm := math.Sqrt(left.Dot(left))
+		if leftNorm == 0 {
+			return 0
+		}
+		left.Scale(1 / leftNorm)
+
+		right := make(linalg.Vector, d.InputCount)
+		for row, x := range left {
+			rowVec := weights[row*d.InputCount : (row+1)*d.InputCount]
+			right.Add(rowVec.Copy().Scale(x))
+		}
+		norm = math.Sqrt(right.Dot(right))
+		vec = right.Scale(1 / norm)
+	}
+	d.spectralVec = vec
+	return norm
+}
+
+// SpectralNormalize divides the weights by their
+// spectral norm, as estimated by SpectralNorm, so that
+// the weight matrix has a spectral norm of roughly 1.
+// The biases are left untouched.
+//
+// It returns the spectral norm from before the weights
+// were normalized.
+// If the weights are all zero, they are left unchanged.
+func (d *DenseLayer) SpectralNormalize(iterations int) float64 {
+	norm := d.SpectralNorm(iterations)
+	if norm != 0 {
+		d.Weights.Data.Vector.Scale(1 / norm)
+	}
+	return norm
+}
+
+// A SpectralNormalizer is a Gradienter which applies
+// SpectralNormalize to a list of DenseLayers, which can
+// help stabilize the training of GAN discriminators.
+//
+// Like a MaxNormConstrainer, it normalizes the layers
+// before every gradient computation.
+// Call Normalize once training finishes to normalize
+// the layers after the final step as well.
+type SpectralNormalizer struct {
+	Gradienter sgd.Gradienter
+	Layers     []*DenseLayer
+
+	// Iterations is the number of power iterations to
+	// run per step.
+	// If it is 0, 1 is used.
+	Iterations int
+
+	// Norms stores the spectral norm of each layer from
+	// before the most recent normalization, which can be
+	// used to monitor training.
+	Norms []float64
+}
+
+func (s *SpectralNormalizer) Gradient(samples sgd.SampleSet) autofunc.Gradient {
+	s.Normalize()
+	return s.Gradienter.Gradient(samples)
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (s *SpectralNormalizer) WrappedGradienter() sgd.Gradienter {
+	return s.Gradienter
+}
+
+// Normalize applies SpectralNormalize to every layer
+// and updates s.Norms.
+func (s *SpectralNormalizer) Normalize() {
+	iterations := s.Iterations
+	if iterations == 0 {
+		iterations = 1
+	}
+	if len(s.Norms) != len(s.Layers) {
+		s.Norms = make([]float64, len(s.Layers))
+	}
+	for i, layer := range s.Layers {
+		s.Norms[i] = layer.SpectralNormalize(iterations)
+	}
+}
@@ -0,0 +1,81 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+func TestDenseSpectralNorm(t *testing.T) {
+	layer := NewDenseLayer(3, 2)
+	copy(layer.Weights.Data.Vector, []float64{0, 3, 0, -2, 0, 0})
+	if norm := layer.SpectralNorm(20); math.Abs(norm-3) > 1e-6 {
+		t.Errorf("expected norm 3 but got %f", norm)
+	}
+
+	for _, size := range [][2]int{{6, 6}, {8, 3}, {3, 8}} {
+		layer := &DenseLayer{InputCount: size[0], OutputCount: size[1]}
+		layer.InitOrthogonalWithRand(1.5, rand.New(rand.NewSource(1337)))
+		if norm := layer.SpectralNorm(5); math.Abs(norm-1.5) > 1e-6 {
+			t.Errorf("size %v: expected norm 1.5 but got %f", size, norm)
+		}
+	}
+}
+
+func TestDenseSpectralNormCache(t *testing.T) {
+	layer := NewDenseLayer(10, 10)
+	exact := layer.SpectralNorm(200)
+	for i := 0; i < 3; i++ {
+		if norm := layer.SpectralNorm(1); math.Abs(norm-exact) > 1e-4 {
+			t.Errorf("call %d: expected norm %f but got %f", i, exact, norm)
+		}
+	}
+}
+
+func TestDenseSpectralNormalize(t *testing.T) {
+	layer := NewDenseLayer(5, 4)
+	biases := layer.Biases.Var.Vector.Copy()
+	before := layer.SpectralNorm(100)
+	if norm := layer.SpectralNormalize(1); math.Abs(norm-before) > 1e-6 {
+		t.Errorf("expected returned norm %f but got %f", before, norm)
+	}
+	if norm := layer.SpectralNorm(100); math.Abs(norm-1) > 1e-6 {
+		t.Errorf("expected norm 1 after normalizing but got %f", norm)
+	}
+	if !reflect.DeepEqual(layer.Biases.Var.Vector, biases) {
+		t.Error("biases were modified")
+	}
+}
+
+func TestSpectralNormalizer(t *testing.T) {
+	rand.Seed(123)
+	var inputs, outputs []linalg.Vector
+	for i := 0; i < 20; i++ {
+		in := linalg.Vector{rand.NormFloat64(), rand.NormFloat64()}
+		inputs = append(inputs, in)
+		outputs = append(outputs, linalg.Vector{5*in[0] - 5*in[1], in[0]})
+	}
+	samples := VectorSampleSet(inputs, outputs)
+	layer := NewDenseLayer(2, 2)
+	net := Network{layer}
+	normalizer := &SpectralNormalizer{
+		Gradienter: &BatchRGradienter{
+			Learner:  net.BatchLearner(),
+			CostFunc: MeanSquaredCost{},
+		},
+		Layers:     []*DenseLayer{layer},
+		Iterations: 2,
+	}
+	sgd.SGD(normalizer, samples, 0.01, 20, 5)
+	if len(normalizer.Norms) != 1 || normalizer.Norms[0] <= 0 {
+		t.Fatalf("unexpected norms: %v", normalizer.Norms)
+	}
+	normalizer.Normalize()
+	if norm := layer.SpectralNorm(100); math.Abs(norm-1) > 1e-3 {
+		t.Errorf("expected norm 1 but got %f", norm)
+	}
+}
@@ -0,0 +1,149 @@
+package neuralnet
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/serializer"
+)
+
+// A StochasticDepthLayer is a ResidualLayer whose
+// wrapped layers are randomly skipped during training,
+// as in "Deep Networks with Stochastic Depth" (Huang et
+// al., 2016).
+//
+// In training mode, each evaluation of the layer keeps
+// the wrapped layers with probability
+// SurvivalProbability, computing x+f(x), and otherwise
+// computes just the identity x.
+// The decision is made once per call, so it applies to
+// every sample in a batch.
+// In usage mode, the layer computes x+p*f(x), where p is
+// SurvivalProbability, which is the expected output
+// during training.
+//
+// Like a DropoutLayer, a StochasticDepthLayer in
+// training mode may return different values each time
+// it is evaluated.
+// Each returned Result only depends on the wrapped
+// layers if they were kept, so back-propagation always
+// follows the same path as the forward pass.
+type StochasticDepthLayer struct {
+	Network Network
+
+	// SurvivalProbability is the probability that the
+	// wrapped layers are kept in training mode.
+	SurvivalProbability float64
+
+	// Training is true if the wrapped layers should be
+	// skipped stochastically rather than scaled.
+	Training bool
+
+	// Rand is used to decide which evaluations skip the
+	// wrapped layers.
+	// If it is nil, the global math/rand source is used.
+	// Since a rand.Rand is not safe for concurrent use,
+	// calls to Rand are synchronized by the layer.
+	Rand *rand.Rand `json:"-"`
+
+	randLock sync.Mutex
+}
+
+// DeserializeStochasticDepthLayer deserializes a
+// StochasticDepthLayer.
+func DeserializeStochasticDepthLayer(d []byte) (*StochasticDepthLayer, error) {
+	var res StochasticDepthLayer
+	if err := serializer.DeserializeAny(d, &res.Network, &res.SurvivalProbability,
+		&res.Training); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// SetTraining sets whether or not the wrapped layers
+// should be skipped stochastically.
+func (s *StochasticDepthLayer) SetTraining(training bool) {
+	s.Training = training
+}
+
+// SetRand sets the source used to decide when to skip
+// the wrapped layers, making the decisions
+// reproducible.
+// If r is nil, the global math/rand source is used.
+func (s *StochasticDepthLayer) SetRand(r *rand.Rand) {
+	s.randLock.Lock()
+	defer s.randLock.Unlock()
+	s.Rand = r
+}
+
+// Apply applies the layer.
+func (s *StochasticDepthLayer) Apply(in autofunc.Result) autofunc.Result {
+	return s.Batch(in, 1)
+}
+
+// ApplyR applies the layer.
+func (s *StochasticDepthLayer) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return s.BatchR(rv, in, 1)
+}
+
+// Batch applies the layer in batch.
+func (s *StochasticDepthLayer) Batch(in autofunc.Result, n int) autofunc.Result {
+	scale, keep := s.blockScale()
+	if !keep {
+		return in
+	}
+	b := s.Network.BatchLearner()
+	return autofunc.Pool(in, func(inPool autofunc.Result) autofunc.Result {
+		out := b.Batch(inPool, n)
+		if scale != 1 {
+			out = autofunc.Scale(out, scale)
+		}
+		return autofunc.Add(inPool, out)
+	})
+}
+
+// BatchR applies the layer in batch.
+func (s *StochasticDepthLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	scale, keep := s.blockScale()
+	if !keep {
+		return in
+	}
+	b := s.Network.BatchLearner()
+	return autofunc.PoolR(in, func(inPool autofunc.RResult) autofunc.RResult {
+		out := b.BatchR(rv, inPool, n)
+		if scale != 1 {
+			out = autofunc.ScaleR(out, scale)
+		}
+		return autofunc.AddR(inPool, out)
+	})
+}
+
+// Parameters returns the parameters of the network.
+func (s *StochasticDepthLayer) Parameters() []*autofunc.Variable {
+	return s.Network.Parameters()
+}
+
+// SerializerType returns the unique ID used to serialize
+// a StochasticDepthLayer with the serializer package.
+func (s *StochasticDepthLayer) SerializerType() string {
+	return serializerTypeStochasticDepthLayer
+}
+
+// Serialize serializes the layer.
+func (s *StochasticDepthLayer) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(s.Network, s.SurvivalProbability, s.Training)
+}
+
+// blockScale decides how to apply the wrapped layers
+// for one evaluation, returning the factor for their
+// output and whether they should be applied at all.
+func (s *StochasticDepthLayer) blockScale() (scale float64, keep bool) {
+	if !s.Training {
+		return s.SurvivalProbability, true
+	}
+	s.randLock.Lock()
+	defer s.randLock.Unlock()
+	return 1, randFloat64(s.Rand) < s.SurvivalProbability
+}
@@ -0,0 +1,138 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+type stochasticDepthTestFunc struct {
+	Layer *StochasticDepthLayer
+	N     int
+}
+
+func (s stochasticDepthTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return s.Layer.Batch(in, s.N)
+}
+
+func (s stochasticDepthTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return s.Layer.BatchR(v, in, s.N)
+}
+
+func TestStochasticDepthUsage(t *testing.T) {
+	inner := Network{NewDenseLayer(3, 3), &HyperbolicTangent{}}
+	layer := &StochasticDepthLayer{Network: inner, SurvivalProbability: 0.7}
+	input := &autofunc.Variable{Vector: []float64{0.5, -1, 2}}
+	expected := inner.Apply(input).Output().Copy().Scale(0.7).Add(input.Vector)
+	actual := layer.Apply(input).Output()
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("output %d: expected %f but got %f", i, x, actual[i])
+		}
+	}
+
+	input = &autofunc.Variable{Vector: make(linalg.Vector, 6)}
+	params := append(layer.Parameters(), input)
+	rVec := autofunc.RVector{}
+	for _, p := range params {
+		rVec[p] = make(linalg.Vector, len(p.Vector))
+		for i := range rVec[p] {
+			rVec[p][i] = rand.NormFloat64()
+		}
+	}
+	for i := range input.Vector {
+		input.Vector[i] = rand.NormFloat64()
+	}
+	checker := &functest.RFuncChecker{
+		F:     stochasticDepthTestFunc{Layer: layer, N: 2},
+		Vars:  params,
+		Input: input,
+		RV:    rVec,
+	}
+	checker.FullCheck(t)
+}
+
+func TestStochasticDepthTraining(t *testing.T) {
+	inner := Network{NewDenseLayer(2, 2)}
+	layer := &StochasticDepthLayer{Network: inner, SurvivalProbability: 0.25}
+	layer.SetTraining(true)
+	layer.SetRand(rand.New(rand.NewSource(1337)))
+
+	input := &autofunc.Variable{Vector: []float64{1, -2}}
+	kept := inner.Apply(input).Output().Copy().Add(input.Vector)
+	var keepCount int
+	const trials = 2000
+	for i := 0; i < trials; i++ {
+		out := layer.Apply(input)
+		grad := autofunc.NewGradient(layer.Parameters())
+		out.PropagateGradient(linalg.Vector{1, 1}, grad)
+		var gradMag float64
+		for _, g := range grad {
+			gradMag += g.MaxAbs()
+		}
+		if out.Output().Copy().Scale(-1).Add(input.Vector).MaxAbs() == 0 {
+			if gradMag != 0 {
+				t.Fatalf("trial %d: skipped layers got gradient", i)
+			}
+		} else if out.Output().Copy().Scale(-1).Add(kept).MaxAbs() < 1e-8 {
+			if gradMag == 0 {
+				t.Fatalf("trial %d: kept layers got no gradient", i)
+			}
+			keepCount++
+		} else {
+			t.Fatalf("trial %d: unexpected output %v", i, out.Output())
+		}
+	}
+	if frac := float64(keepCount) / trials; math.Abs(frac-0.25) > 0.05 {
+		t.Errorf("expected survival rate 0.25 but got %f", frac)
+	}
+
+	layer.SetRand(rand.New(rand.NewSource(1)))
+	var first []bool
+	for i := 0; i < 20; i++ {
+		first = append(first, layer.Apply(input).Output()[0] != input.Vector[0])
+	}
+	layer.SetRand(rand.New(rand.NewSource(1)))
+	for i, x := range first {
+		if (layer.Apply(input).Output()[0] != input.Vector[0]) != x {
+			t.Fatalf("evaluation %d differs with the same seed", i)
+		}
+	}
+}
+
+func TestStochasticDepthSerialize(t *testing.T) {
+	layer := &StochasticDepthLayer{
+		Network:             Network{NewDenseLayer(2, 2), &ReLU{}},
+		SurvivalProbability: 0.8,
+		Training:            true,
+	}
+	data, err := layer.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(layer.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLayer, ok := decoded.(*StochasticDepthLayer)
+	if !ok {
+		t.Fatalf("expected *StochasticDepthLayer but got %T", decoded)
+	}
+	if newLayer.SurvivalProbability != 0.8 || !newLayer.Training {
+		t.Errorf("unexpected fields: %f %v", newLayer.SurvivalProbability,
+			newLayer.Training)
+	}
+	layer.SetTraining(false)
+	newLayer.SetTraining(false)
+	input := &autofunc.Variable{Vector: []float64{1, -2}}
+	expected := layer.Apply(input).Output()
+	actual := newLayer.Apply(input).Output()
+	if actual.Copy().Scale(-1).Add(expected).MaxAbs() != 0 {
+		t.Errorf("expected output %v but got %v", expected, actual)
+	}
+}
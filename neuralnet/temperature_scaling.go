@@ -0,0 +1,150 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+const (
+	temperatureFitIterations = 100
+	maxInverseTemperature    = 1e6
+)
+
+// A TemperatureScaler is a Layer which divides its input
+// by a temperature, which can be fit to calibrate the
+// probabilities of a trained classifier, as described in
+// "On Calibration of Modern Neural Networks" (Guo et
+// al., 2017).
+//
+// The scaler should be placed between a classifier's
+// logits and its softmax, e.g. in
+//
+//	Network{classifier, scaler, &SoftmaxLayer{}}
+//
+// Since dividing by a temperature does not change the
+// order of the logits, it does not affect which class
+// is predicted.
+type TemperatureScaler struct {
+	// Temperature is the value by which inputs are
+	// divided.
+	// If it is 0, 1 is used, making the layer the
+	// identity.
+	Temperature float64
+}
+
+func DeserializeTemperatureScaler(d []byte) (*TemperatureScaler, error) {
+	var res TemperatureScaler
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Fit sets the temperature to minimize the negative
+// log-likelihood of the expected outputs of the samples
+// under softmax(logits/T), where the logits are the
+// outputs of the layer.
+// It returns the new temperature.
+//
+// The layer should output logits, i.e. it should not
+// include a softmax, and the expected outputs should be
+// one-hot vectors (or other probability distributions),
+// as for SoftmaxCECost.
+// The samples should be held-out data, not the data the
+// layer was trained on.
+//
+// Since the negative log-likelihood is convex in 1/T,
+// the temperature is found by bisecting on the
+// derivative with respect to 1/T.
+// If the logits classify every sample perfectly, the
+// likelihood keeps improving as T approaches 0, so the
+// temperature is made small enough that the predicted
+// probabilities are all 0 or 1 to within rounding.
+func (t *TemperatureScaler) Fit(layer autofunc.Func, s sgd.SampleSet) float64 {
+	var logits, targets []linalg.Vector
+	for i := 0; i < s.Len(); i++ {
+		sample := s.GetSample(i).(VectorSample)
+		out := layer.Apply(&autofunc.Variable{Vector: sample.Input}).Output()
+		if len(out) != len(sample.Output) {
+			panic("invalid output size")
+		}
+		logits = append(logits, out)
+		targets = append(targets, sample.Output)
+	}
+
+	// The derivative is increasing, so it brackets the
+	// minimum between a non-positive and a non-negative
+	// point.
+	low, high := 0.0, 1.0
+	for temperatureNLLDeriv(logits, targets, high) < 0 {
+		if high >= maxInverseTemperature {
+			t.Temperature = 1 / maxInverseTemperature
+			return t.Temperature
+		}
+		low = high
+		high *= 2
+	}
+	for i := 0; i < temperatureFitIterations; i++ {
+		mid := (low + high) / 2
+		if temperatureNLLDeriv(logits, targets, mid) < 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	t.Temperature = 2 / (low + high)
+	return t.Temperature
+}
+
+func (t *TemperatureScaler) Apply(in autofunc.Result) autofunc.Result {
+	return autofunc.Scale(in, 1/t.temperature())
+}
+
+func (t *TemperatureScaler) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return autofunc.ScaleR(in, 1/t.temperature())
+}
+
+func (t *TemperatureScaler) Serialize() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+func (t *TemperatureScaler) SerializerType() string {
+	return serializerTypeTemperatureScaler
+}
+
+func (t *TemperatureScaler) temperature() float64 {
+	if t.Temperature == 0 {
+		return 1
+	}
+	return t.Temperature
+}
+
+// temperatureNLLDeriv computes the derivative of the
+// total negative log-likelihood of the targets with
+// respect to the inverse temperature beta.
+//
+// For each sample, this is E[z]-t.z, where z are the
+// logits, t is the target, and the expectation is taken
+// under softmax(beta*z).
+func temperatureNLLDeriv(logits, targets []linalg.Vector, beta float64) float64 {
+	var res float64
+	for i, z := range logits {
+		maxLogit := math.Inf(-1)
+		for _, x := range z {
+			maxLogit = math.Max(maxLogit, x)
+		}
+		var expSum, weightedSum float64
+		for j, x := range z {
+			e := math.Exp(beta * (x - maxLogit))
+			expSum += e
+			weightedSum += e * x
+			res -= targets[i][j] * x
+		}
+		res += weightedSum / expSum
+	}
+	return res
+}
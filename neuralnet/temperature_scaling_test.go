@@ -0,0 +1,97 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+func TestTemperatureScalerFit(t *testing.T) {
+	// The samples are labeled according to softmax(z/2),
+	// so the fitted temperature should be close to 2.
+	r := rand.New(rand.NewSource(1337))
+	samples := sgd.SliceSampleSet{}
+	for i := 0; i < 5000; i++ {
+		logits := linalg.Vector{r.NormFloat64() * 3, r.NormFloat64() * 3, r.NormFloat64() * 3}
+		var probs []float64
+		var sum float64
+		for _, x := range logits {
+			probs = append(probs, math.Exp(x/2))
+			sum += probs[len(probs)-1]
+		}
+		target := make(linalg.Vector, len(logits))
+		u := r.Float64() * sum
+		for j, p := range probs {
+			if u < p || j == len(probs)-1 {
+				target[j] = 1
+				break
+			}
+			u -= p
+		}
+		samples = append(samples, VectorSample{Input: logits, Output: target})
+	}
+
+	var scaler TemperatureScaler
+	identity := Network{}
+	temp := scaler.Fit(identity, samples)
+	if temp != scaler.Temperature {
+		t.Errorf("returned %f but set %f", temp, scaler.Temperature)
+	}
+	if math.Abs(temp-2) > 0.1 {
+		t.Errorf("expected temperature near 2 but got %f", temp)
+	}
+
+	fittedCost := TotalCost(SoftmaxCECost{}, &scaler, samples)
+	for _, other := range []float64{temp * 0.9, temp * 1.1} {
+		cost := TotalCost(SoftmaxCECost{}, &TemperatureScaler{Temperature: other}, samples)
+		if cost < fittedCost {
+			t.Errorf("temperature %f has cost %f below fitted cost %f", other, cost,
+				fittedCost)
+		}
+	}
+}
+
+func TestTemperatureScalerSeparable(t *testing.T) {
+	samples := sgd.SliceSampleSet{
+		VectorSample{Input: linalg.Vector{2, -1}, Output: linalg.Vector{1, 0}},
+		VectorSample{Input: linalg.Vector{-1, 3}, Output: linalg.Vector{0, 1}},
+	}
+	var scaler TemperatureScaler
+	if temp := scaler.Fit(Network{}, samples); temp <= 0 || temp >= 1 {
+		t.Fatalf("expected a small positive temperature but got %f", temp)
+	}
+	if cost := TotalCost(SoftmaxCECost{}, &scaler, samples); cost > 1e-8 {
+		t.Errorf("expected cost near zero but got %e", cost)
+	}
+}
+
+func TestTemperatureScalerApply(t *testing.T) {
+	input := &autofunc.Variable{Vector: linalg.Vector{2, -1, 4}}
+	out := (&TemperatureScaler{}).Apply(input).Output()
+	if out.Copy().Scale(-1).Add(input.Vector).MaxAbs() != 0 {
+		t.Errorf("zero temperature should be the identity, but got %v", out)
+	}
+	scaler := &TemperatureScaler{Temperature: 2}
+	expected := linalg.Vector{1, -0.5, 2}
+	out = scaler.Apply(input).Output()
+	if out.Copy().Scale(-1).Add(expected).MaxAbs() > 1e-8 {
+		t.Errorf("expected %v but got %v", expected, out)
+	}
+
+	data, err := scaler.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(scaler.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newScaler, ok := decoded.(*TemperatureScaler); !ok || newScaler.Temperature != 2 {
+		t.Errorf("unexpected deserialized scaler: %v", decoded)
+	}
+}
@@ -0,0 +1,173 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// A TiedDenseLayer is a fully-connected layer which
+// uses the transpose of another DenseLayer's weight
+// matrix, as in a tied autoencoder.
+// Gradients with respect to the weights are accumulated
+// into the weights of the tied DenseLayer, so both
+// layers are trained through the same parameters.
+// The TiedDenseLayer only has its own biases.
+//
+// The tie is recorded as TiedIndex, the index of the
+// DenseLayer within the Network containing both layers.
+// When a Network is deserialized or cloned, its
+// TiedDenseLayers are tied to the corresponding layers
+// of the new Network.
+type TiedDenseLayer struct {
+	// Tied is the layer whose weights are shared.
+	// It is not serialized, since it is determined by
+	// TiedIndex.
+	Tied *DenseLayer `json:"-"`
+
+	// TiedIndex is the index of Tied in the enclosing
+	// Network.
+	TiedIndex int
+
+	Biases *autofunc.LinAdd
+}
+
+// NewTiedDenseLayer creates a TiedDenseLayer which is
+// tied to n[index] and has zero biases.
+// The new layer maps n[index].OutputCount inputs to
+// n[index].InputCount outputs.
+// It panics if n[index] is not a *DenseLayer.
+//
+// The returned layer is meant to be appended to n (or
+// appear later in a Network which shares the prefix n),
+// so that the tie can be restored after serialization.
+func NewTiedDenseLayer(n Network, index int) *TiedDenseLayer {
+	tied, ok := n[index].(*DenseLayer)
+	if !ok {
+		panic(fmt.Sprintf("layer %d is not a *DenseLayer", index))
+	}
+	return &TiedDenseLayer{
+		Tied:      tied,
+		TiedIndex: index,
+		Biases: &autofunc.LinAdd{
+			Var: &autofunc.Variable{
+				Vector: make(linalg.Vector, tied.InputCount),
+			},
+		},
+	}
+}
+
+// DeserializeTiedDenseLayer deserializes a
+// TiedDenseLayer.
+// The resulting layer is not tied to anything until it
+// is part of a deserialized Network, or until Tied is
+// set manually.
+func DeserializeTiedDenseLayer(d []byte) (*TiedDenseLayer, error) {
+	var res TiedDenseLayer
+	if err := json.Unmarshal(d, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Parameters returns a slice containing the bias
+// variable.
+// The tied weights are not included, since they are
+// reported by the tied DenseLayer.
+func (t *TiedDenseLayer) Parameters() []*autofunc.Variable {
+	if t.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	return []*autofunc.Variable{t.Biases.Var}
+}
+
+func (t *TiedDenseLayer) Apply(in autofunc.Result) autofunc.Result {
+	return t.Batch(in, 1)
+}
+
+func (t *TiedDenseLayer) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return t.BatchR(v, in, 1)
+}
+
+func (t *TiedDenseLayer) Batch(in autofunc.Result, n int) autofunc.Result {
+	t.checkInput(len(in.Output()), n)
+	d := t.Tied
+	weights := autofunc.Transpose(d.Weights.Data, d.OutputCount, d.InputCount)
+	product := autofunc.MatMulVecs(weights, d.InputCount, d.OutputCount, in)
+	biasBatcher := &autofunc.FuncBatcher{F: t.Biases}
+	return biasBatcher.Batch(product, n)
+}
+
+func (t *TiedDenseLayer) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	t.checkInput(len(in.Output()), n)
+	d := t.Tied
+	weights := autofunc.TransposeR(autofunc.NewRVariable(d.Weights.Data, rv),
+		d.OutputCount, d.InputCount)
+	product := autofunc.MatMulVecsR(weights, d.InputCount, d.OutputCount, in)
+	biasBatcher := &autofunc.RFuncBatcher{F: t.Biases}
+	return biasBatcher.BatchR(rv, product, n)
+}
+
+// InputSize returns the length of each input, which is
+// the output size of the tied layer.
+func (t *TiedDenseLayer) InputSize() int {
+	if t.Tied == nil {
+		panic(uninitPanicMessage)
+	}
+	return t.Tied.OutputCount
+}
+
+// OutputSize returns the length of each output, which
+// is the input size of the tied layer.
+func (t *TiedDenseLayer) OutputSize() int {
+	if t.Tied == nil {
+		panic(uninitPanicMessage)
+	}
+	return t.Tied.InputCount
+}
+
+// Serialize serializes the layer's biases and the index
+// of its tied layer.
+func (t *TiedDenseLayer) Serialize() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a TiedDenseLayer with the serializer package.
+func (t *TiedDenseLayer) SerializerType() string {
+	return serializerTypeTiedDenseLayer
+}
+
+func (t *TiedDenseLayer) checkInput(inLen, n int) {
+	if t.Tied == nil || t.Tied.Weights == nil || t.Biases == nil {
+		panic(uninitPanicMessage)
+	}
+	if inLen != n*t.Tied.OutputCount {
+		panic("invalid input size")
+	}
+}
+
+// tieLayers sets the Tied field of every TiedDenseLayer
+// in n based on its TiedIndex.
+func tieLayers(n Network) error {
+	for i, layer := range n {
+		tied, ok := layer.(*TiedDenseLayer)
+		if !ok {
+			continue
+		}
+		if tied.TiedIndex < 0 || tied.TiedIndex >= len(n) {
+			return fmt.Errorf("layer %d is tied to out-of-range layer %d", i,
+				tied.TiedIndex)
+		}
+		dense, ok := n[tied.TiedIndex].(*DenseLayer)
+		if !ok {
+			return fmt.Errorf("layer %d is tied to layer %d, which is not a DenseLayer",
+				i, tied.TiedIndex)
+		}
+		tied.Tied = dense
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/functest"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+type tiedDenseTestFunc struct {
+	Layer *TiedDenseLayer
+	N     int
+}
+
+func (t tiedDenseTestFunc) Apply(in autofunc.Result) autofunc.Result {
+	return t.Layer.Batch(in, t.N)
+}
+
+func (t tiedDenseTestFunc) ApplyR(v autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return t.Layer.BatchR(v, in, t.N)
+}
+
+func TestTiedDenseLayerOutput(t *testing.T) {
+	encoder := &DenseLayer{InputCount: 3, OutputCount: 2}
+	encoder.Randomize()
+	copy(encoder.Weights.Data.Vector, []float64{1, 2, 3, -1, 0, 2})
+	layer := NewTiedDenseLayer(Network{encoder}, 0)
+	copy(layer.Biases.Var.Vector, []float64{0.5, -0.5, 1})
+
+	input := &autofunc.Variable{Vector: []float64{2, 1, 0, -1}}
+	output := layer.Batch(input, 2).Output()
+	expected := []float64{1.5, 3.5, 9, 1.5, -0.5, -1}
+	for i, x := range expected {
+		if math.Abs(output[i]-x) > 1e-8 {
+			t.Errorf("output %d: expected %f but got %f", i, x, output[i])
+		}
+	}
+	if layer.InputSize() != 2 || layer.OutputSize() != 3 {
+		t.Errorf("unexpected sizes %d->%d", layer.InputSize(), layer.OutputSize())
+	}
+}
+
+func TestTiedDenseLayerGradients(t *testing.T) {
+	encoder := NewDenseLayer(3, 2)
+	layer := NewTiedDenseLayer(Network{encoder}, 0)
+	input := &autofunc.Variable{Vector: make(linalg.Vector, 6)}
+	params := append(encoder.Parameters(), layer.Parameters()...)
+	params = append(params, input)
+	rVec := autofunc.RVector{}
+	for _, p := range params {
+		rVec[p] = make(linalg.Vector, len(p.Vector))
+		for i := range p.Vector {
+			p.Vector[i] = rand.NormFloat64()
+			rVec[p][i] = rand.NormFloat64()
+		}
+	}
+	f := tiedDenseTestFunc{Layer: layer, N: 3}
+	checker := &functest.RFuncChecker{F: f, Vars: params, Input: input, RV: rVec}
+	checker.FullCheck(t)
+}
+
+func TestTiedDenseLayerNetwork(t *testing.T) {
+	encoder := NewDenseLayer(4, 2)
+	net := Network{encoder, &Sigmoid{}}
+	net = append(net, NewTiedDenseLayer(net, 0))
+
+	if len(net.Parameters()) != 3 {
+		t.Fatalf("expected 3 parameters but got %d", len(net.Parameters()))
+	}
+
+	data, err := net.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.GetDeserializer(net.SerializerType())(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloned, err := net.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := &autofunc.Variable{Vector: []float64{1, -1, 0.5, 2}}
+	expected := net.Apply(input).Output()
+	for _, copied := range []Network{decoded.(Network), cloned} {
+		tied := copied[2].(*TiedDenseLayer)
+		if tied.Tied != copied[0] {
+			t.Fatal("layer is not tied to its copied encoder")
+		}
+		if tied.Tied == encoder {
+			t.Fatal("layer is tied to the original encoder")
+		}
+		actual := copied.Apply(input).Output()
+		if actual.Copy().Scale(-1).Add(expected).MaxAbs() > 1e-8 {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	}
+
+	badNet := Network{&Sigmoid{}, &TiedDenseLayer{TiedIndex: 0}}
+	data, err = badNet.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DeserializeNetwork(data); err == nil {
+		t.Error("expected error for layer tied to a non-DenseLayer")
+	}
+
+	if _, err := NewNetwork(net...); err != nil {
+		t.Error(err)
+	}
+	untied := &TiedDenseLayer{TiedIndex: 0}
+	if _, err := NewNetwork(encoder, untied); err == nil {
+		t.Error("expected error for untied layer")
+	}
+}
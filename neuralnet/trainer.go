@@ -0,0 +1,359 @@
+package neuralnet
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+// EpochMetrics describes the state of training after
+// an epoch.
+type EpochMetrics struct {
+	// Epoch is the index of the epoch, starting at 0.
+	Epoch int
+
+	// TrainingCost is the total cost on the training
+	// samples after the epoch.
+	TrainingCost float64
+
+	// ValidationCost is the total cost on the
+	// validation samples after the epoch, or 0 if
+	// there are no validation samples.
+	ValidationCost float64
+
+	// Elapsed is the time since training started.
+	Elapsed time.Duration
+}
+
+// A Trainer trains a Network with SGD, optionally
+// stopping early once the cost on a set of validation
+// samples stops improving.
+//
+// All SampleSets must contain VectorSamples.
+type Trainer struct {
+	Network  Network
+	CostFunc CostFunc
+
+	// Gradienter computes (and possibly transforms) the
+	// gradients used for each step, e.g. an AdamOptimizer
+	// wrapping a BatchRGradienter.
+	// If it is nil, a BatchRGradienter for Network and
+	// CostFunc is used.
+	Gradienter sgd.Gradienter
+
+	StepSize float64
+
+	// BatchSize is the number of samples in each
+	// mini-batch.
+	// If it is 0, every step uses all of the samples.
+	BatchSize int
+
+	// Average, if true, divides the gradient of each
+	// mini-batch by the number of samples in it, so that
+	// the step size does not depend on BatchSize and the
+	// final (possibly smaller) batch of an epoch is not
+	// under-weighted.
+	// It only affects the default Gradienter; to average
+	// with a custom Gradienter, wrap the innermost
+	// gradienter in an AverageGradienter.
+	Average bool
+
+	// Validation is used to decide when to stop
+	// training.
+	// If it is nil, training runs for every epoch.
+	Validation sgd.SampleSet
+
+	// Patience is the number of epochs to wait for the
+	// validation cost to improve before stopping.
+	// If it is 0, training never stops early, but the
+	// best parameters are still restored at the end.
+	Patience int
+
+	// Callback, if non-nil, is called after each epoch
+	// with the epoch's metrics, including the last epoch
+	// before early stopping.
+	// If it returns false, training stops.
+	//
+	// For example, this prints a simple progress bar:
+	//
+	//     trainer.Callback = func(m neuralnet.EpochMetrics) bool {
+	//         done := (m.Epoch + 1) * 20 / maxEpochs
+	//         fmt.Printf("\r[%s%s] cost=%f (%v)", strings.Repeat("#", done),
+	//             strings.Repeat(" ", 20-done), m.TrainingCost, m.Elapsed)
+	//         return true
+	//     }
+	//
+	// The Callback may call SaveCheckpoint to save the
+	// state of training after the epoch.
+	Callback func(m EpochMetrics) bool
+
+	// Seed, if non-zero, makes the order of the samples
+	// reproducible: before epoch i, the samples are
+	// shuffled by a source seeded with Seed+i.
+	// If Seed is 0, the global math/rand source is used.
+	//
+	// With a Seed, a run which is resumed from a
+	// checkpoint continues exactly like the original run.
+	// This assumes that the gradients themselves are
+	// deterministic.
+	// For example, a BatchRGradienter sums sub-batches on
+	// several goroutines in an arbitrary order, unless
+	// BatchSize is at most its MaxBatchSize or its
+	// MaxGoroutines is 1.
+	Seed int64
+
+	progress trainerProgress
+	resuming bool
+}
+
+// trainerProgress is the state of a Trainer which is
+// carried from one epoch to the next.
+type trainerProgress struct {
+	Epoch     int
+	BestEpoch int
+	BestCost  float64
+	Best      Network
+	Metrics   []EpochMetrics
+}
+
+// Train runs up to maxEpochs epochs of SGD on the
+// samples and returns the metrics for each epoch.
+//
+// Training stops early if the Callback returns false.
+// If there are validation samples, the Network is
+// restored to its state from the epoch with the lowest
+// validation cost, including state which is not made
+// of parameters, like the running statistics of a
+// BatchNormLayer.
+// The Network's layers are updated in place, so
+// references to them remain valid.
+//
+// If the Trainer was just restored with LoadCheckpoint,
+// Train resumes from the checkpoint's epoch rather than
+// starting over, and the returned metrics include the
+// epochs from before the checkpoint.
+func (t *Trainer) Train(samples sgd.SampleSet, maxEpochs int) []EpochMetrics {
+	gradienter := t.Gradienter
+	if gradienter == nil {
+		gradienter = &BatchRGradienter{
+			Learner:  t.Network.BatchLearner(),
+			CostFunc: t.CostFunc,
+		}
+		if t.Average {
+			gradienter = &AverageGradienter{Gradienter: gradienter}
+		}
+	}
+	batcher := t.Network.BatchLearner()
+
+	if !t.resuming {
+		t.progress = trainerProgress{BestEpoch: -1, BestCost: math.Inf(1)}
+	}
+	t.resuming = false
+	p := &t.progress
+
+	startTime := time.Now()
+	if len(p.Metrics) > 0 {
+		startTime = startTime.Add(-p.Metrics[len(p.Metrics)-1].Elapsed)
+	}
+
+	for epoch := p.Epoch; epoch < maxEpochs; epoch++ {
+		t.runEpoch(gradienter, samples, epoch)
+		m := EpochMetrics{
+			Epoch:        epoch,
+			TrainingCost: TotalCostBatcher(t.CostFunc, batcher, samples, t.BatchSize),
+		}
+		if t.Validation != nil {
+			m.ValidationCost = TotalCostBatcher(t.CostFunc, batcher, t.Validation,
+				t.BatchSize)
+		}
+		m.Elapsed = time.Since(startTime)
+		p.Metrics = append(p.Metrics, m)
+		p.Epoch = epoch + 1
+
+		var stop bool
+		if t.Validation != nil {
+			if m.ValidationCost < p.BestCost {
+				p.BestCost = m.ValidationCost
+				p.BestEpoch = epoch
+				p.Best = snapshotNetwork(t.Network)
+			} else if t.Patience > 0 && epoch-p.BestEpoch >= t.Patience {
+				stop = true
+			}
+		}
+
+		// The Callback sees every epoch, including the one
+		// which triggers early stopping.
+		if t.Callback != nil && !t.Callback(m) {
+			stop = true
+		}
+		if stop {
+			break
+		}
+	}
+
+	if p.Best != nil {
+		if err := copyNetworkState(t.Network, p.Best); err != nil {
+			panic("failed to restore network: " + err.Error())
+		}
+	}
+	return p.Metrics
+}
+
+// runEpoch runs one epoch of SGD, shuffling the samples
+// according to t.Seed.
+func (t *Trainer) runEpoch(g sgd.Gradienter, samples sgd.SampleSet, epoch int) {
+	batchSize := t.BatchSize
+	if batchSize == 0 {
+		batchSize = samples.Len()
+	}
+	if t.Seed == 0 {
+		sgd.SGD(g, samples, t.StepSize, 1, batchSize)
+		return
+	}
+	// The shuffled set is built from a permutation rather
+	// than with samples.Copy(), since the Copy method of
+	// sgd.SliceSampleSet returns the original slice, and
+	// the order of the samples must not depend on the
+	// previous epochs.
+	r := rand.New(rand.NewSource(t.Seed + int64(epoch)))
+	s := make(sgd.SliceSampleSet, samples.Len())
+	for i, j := range r.Perm(samples.Len()) {
+		s[i] = samples.GetSample(j)
+	}
+	for i := 0; i < s.Len(); i += batchSize {
+		end := i + batchSize
+		if end > s.Len() {
+			end = s.Len()
+		}
+		g.Gradient(s.Subset(i, end)).AddToVars(-t.StepSize)
+	}
+}
+
+// An AverageGradienter divides the gradients from its
+// wrapped Gradienter by the number of samples they were
+// computed on, turning a total gradient into a mean.
+//
+// Like the wrapped Gradienter's, the returned gradients
+// are only valid until the next call to Gradient.
+type AverageGradienter struct {
+	Gradienter sgd.Gradienter
+}
+
+func (a *AverageGradienter) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	grad := a.Gradienter.Gradient(s)
+	if s.Len() > 0 {
+		grad.Scale(1 / float64(s.Len()))
+	}
+	return grad
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (a *AverageGradienter) WrappedGradienter() sgd.Gradienter {
+	return a.Gradienter
+}
+
+// snapshotNetwork creates a deep copy of n, which can
+// later be restored with copyNetworkState.
+func snapshotNetwork(n Network) Network {
+	res, err := n.Clone()
+	if err != nil {
+		panic("failed to snapshot network: " + err.Error())
+	}
+	return res
+}
+
+// copyNetworkState copies the parameters and other
+// state of the layers in src into the corresponding
+// layers of dst.
+// It fails if the two networks are structured
+// differently, possibly after copying some of the
+// state.
+func copyNetworkState(dst, src Network) error {
+	if len(dst) != len(src) {
+		return fmt.Errorf("expected %d layers but got %d", len(dst), len(src))
+	}
+	for i, layer := range dst {
+		if err := copyLayerState(layer, src[i]); err != nil {
+			return fmt.Errorf("layer %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+func copyLayerState(dst, src Layer) error {
+	if reflect.TypeOf(dst) != reflect.TypeOf(src) {
+		return fmt.Errorf("expected %T but got %T", dst, src)
+	}
+	switch dst := dst.(type) {
+	case Network:
+		return copyNetworkState(dst, src.(Network))
+	case *ResidualLayer:
+		return copyNetworkState(dst.Network, src.(*ResidualLayer).Network)
+	case *StochasticDepthLayer:
+		return copyNetworkState(dst.Network, src.(*StochasticDepthLayer).Network)
+	case *ConcatLayer:
+		return copyNetworkState(dst.Layers, src.(*ConcatLayer).Layers)
+	case *MultiHeadNetwork:
+		srcHeads := src.(*MultiHeadNetwork)
+		if len(dst.Heads) != len(srcHeads.Heads) {
+			return fmt.Errorf("expected %d heads but got %d", len(dst.Heads),
+				len(srcHeads.Heads))
+		}
+		if err := copyNetworkState(dst.Trunk, srcHeads.Trunk); err != nil {
+			return err
+		}
+		for i, head := range dst.Heads {
+			if err := copyNetworkState(head, srcHeads.Heads[i]); err != nil {
+				return fmt.Errorf("head %d: %s", i, err)
+			}
+		}
+		return nil
+	case *DenseLayer:
+		// Parameters omits the weights of frozen layers.
+		srcDense := src.(*DenseLayer)
+		return copyVectors(
+			[]linalg.Vector{dst.Weights.Data.Vector, dst.Biases.Var.Vector},
+			[]linalg.Vector{srcDense.Weights.Data.Vector, srcDense.Biases.Var.Vector},
+		)
+	case *BatchNormLayer:
+		srcNorm := src.(*BatchNormLayer)
+		return copyVectors(
+			[]linalg.Vector{dst.Scales.Vector, dst.Biases.Vector, dst.RunningMean,
+				dst.RunningVariance},
+			[]linalg.Vector{srcNorm.Scales.Vector, srcNorm.Biases.Vector,
+				srcNorm.RunningMean, srcNorm.RunningVariance},
+		)
+	case sgd.Learner:
+		var dstVecs, srcVecs []linalg.Vector
+		for _, p := range dst.Parameters() {
+			dstVecs = append(dstVecs, p.Vector)
+		}
+		for _, p := range src.(sgd.Learner).Parameters() {
+			srcVecs = append(srcVecs, p.Vector)
+		}
+		return copyVectors(dstVecs, srcVecs)
+	}
+	return nil
+}
+
+func copyVectors(dst, src []linalg.Vector) error {
+	if len(dst) != len(src) {
+		return fmt.Errorf("expected %d vectors but got %d", len(dst), len(src))
+	}
+	for i, vec := range dst {
+		if len(vec) != len(src[i]) {
+			return fmt.Errorf("vector %d has length %d but expected %d", i,
+				len(src[i]), len(vec))
+		}
+	}
+	for i, vec := range dst {
+		copy(vec, src[i])
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package neuralnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+// trainerCheckpoint is the data stored by
+// SaveCheckpoint.
+type trainerCheckpoint struct {
+	Network     []byte
+	Gradienters []checkpointGradienter
+
+	Epoch       int
+	BestEpoch   int
+	BestCost    float64
+	BestNetwork []byte
+	Metrics     []EpochMetrics
+}
+
+type checkpointGradienter struct {
+	Type  string
+	State json.RawMessage
+}
+
+// SaveCheckpoint saves the state of training to a file,
+// so that it can be resumed with LoadCheckpoint.
+// It is meant to be called from the Callback, which
+// runs after each epoch.
+//
+// A checkpoint contains:
+//
+//   - the serialized Network, including state which is
+//     not made of parameters, like the running
+//     statistics of a BatchNormLayer;
+//   - the state of every optimizer (e.g. the moments of
+//     an AdamOptimizer) and the Step of every
+//     ScheduledGradienter in the Gradienter chain,
+//     which is followed through GradienterWrappers;
+//   - the number of completed epochs, the metrics so
+//     far, and the best validation cost and Network.
+//
+// The sample order does not need to be saved, since it
+// is determined by the Seed and the epoch.
+// The configuration of the Gradienter chain and the
+// random sources of layers like DropoutLayer are not
+// saved, so they must be recreated before calling
+// LoadCheckpoint.
+//
+// The file is written to a temporary path and then
+// renamed, so an interruption while saving does not
+// corrupt an existing checkpoint.
+func (t *Trainer) SaveCheckpoint(path string) error {
+	p := t.progress
+	checkpoint := &trainerCheckpoint{
+		Epoch:     p.Epoch,
+		BestEpoch: p.BestEpoch,
+		Metrics:   p.Metrics,
+	}
+	var err error
+	checkpoint.Network, err = t.Network.Serialize()
+	if err != nil {
+		return fmt.Errorf("save checkpoint: %s", err)
+	}
+	if p.BestEpoch >= 0 {
+		checkpoint.BestCost = p.BestCost
+		checkpoint.BestNetwork, err = p.Best.Serialize()
+		if err != nil {
+			return fmt.Errorf("save checkpoint: %s", err)
+		}
+	}
+	for _, g := range gradienterChain(t.Gradienter) {
+		entry := checkpointGradienter{Type: fmt.Sprintf("%T", g)}
+		switch g := g.(type) {
+		case *ScheduledGradienter:
+			entry.State, err = json.Marshal(g.Step)
+		case serializer.Serializer:
+			entry.State, err = g.Serialize()
+		}
+		if err != nil {
+			return fmt.Errorf("save checkpoint: %s", err)
+		}
+		checkpoint.Gradienters = append(checkpoint.Gradienters, entry)
+	}
+
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("save checkpoint: %s", err)
+	}
+	tempPath := path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// LoadCheckpoint restores the state of training from a
+// file created by SaveCheckpoint, so that the next call
+// to Train resumes where the checkpoint left off.
+//
+// The Trainer must be set up like the one which saved
+// the checkpoint, with the same Network architecture
+// and the same chain of Gradienters, since the saved
+// state is copied into them.
+// The layers of the Network are updated in place, so
+// Gradienters and other references to them remain
+// valid.
+func (t *Trainer) LoadCheckpoint(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var checkpoint trainerCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return fmt.Errorf("load checkpoint: %s", err)
+	}
+
+	chain := gradienterChain(t.Gradienter)
+	if len(chain) != len(checkpoint.Gradienters) {
+		return fmt.Errorf("load checkpoint: expected %d gradienters but got %d",
+			len(checkpoint.Gradienters), len(chain))
+	}
+	for i, g := range chain {
+		entry := checkpoint.Gradienters[i]
+		if actualType := fmt.Sprintf("%T", g); actualType != entry.Type {
+			return fmt.Errorf("load checkpoint: gradienter %d is %s but expected %s",
+				i, actualType, entry.Type)
+		}
+	}
+	network, err := loadCheckpointNetwork(t.Network, checkpoint.Network)
+	if err != nil {
+		return fmt.Errorf("load checkpoint: %s", err)
+	}
+	var best Network
+	if checkpoint.BestEpoch >= 0 {
+		best, err = loadCheckpointNetwork(t.Network, checkpoint.BestNetwork)
+		if err != nil {
+			return fmt.Errorf("load checkpoint: best network: %s", err)
+		}
+	}
+	if err := copyNetworkState(t.Network, network); err != nil {
+		return fmt.Errorf("load checkpoint: %s", err)
+	}
+	for i, g := range chain {
+		state := checkpoint.Gradienters[i].State
+		switch g := g.(type) {
+		case *ScheduledGradienter:
+			err = json.Unmarshal(state, &g.Step)
+		case serializer.Serializer:
+			// The optimizers serialize to JSON, and decoding
+			// into them keeps the fields which are not
+			// serialized, like their Learners.
+			err = json.Unmarshal(state, g)
+		}
+		if err != nil {
+			return fmt.Errorf("load checkpoint: gradienter %d: %s", i, err)
+		}
+	}
+
+	t.progress = trainerProgress{
+		Epoch:     checkpoint.Epoch,
+		BestEpoch: checkpoint.BestEpoch,
+		BestCost:  checkpoint.BestCost,
+		Best:      best,
+		Metrics:   checkpoint.Metrics,
+	}
+	if checkpoint.BestEpoch < 0 {
+		t.progress.BestCost = math.Inf(1)
+	}
+	t.resuming = true
+	return nil
+}
+
+// loadCheckpointNetwork deserializes a Network from a
+// checkpoint and copies it into a clone of n, which
+// checks that the two Networks have the same structure.
+func loadCheckpointNetwork(n Network, data []byte) (Network, error) {
+	loaded, err := DeserializeNetwork(data)
+	if err != nil {
+		return nil, err
+	}
+	res := snapshotNetwork(n)
+	if err := copyNetworkState(res, loaded); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// gradienterChain lists g and every Gradienter wrapped
+// by it, from the outermost to the innermost.
+func gradienterChain(g sgd.Gradienter) []sgd.Gradienter {
+	var res []sgd.Gradienter
+	for g != nil {
+		res = append(res, g)
+		if wrapper, ok := g.(GradienterWrapper); ok {
+			g = wrapper.WrappedGradienter()
+		} else {
+			g = nil
+		}
+	}
+	return res
+}
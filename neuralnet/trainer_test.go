@@ -0,0 +1,411 @@
+package neuralnet
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+func TestTrainerEarlyStopping(t *testing.T) {
+	rand.Seed(123)
+	var inputs, outputs []linalg.Vector
+	for i := 0; i < 20; i++ {
+		in := linalg.Vector{rand.NormFloat64(), rand.NormFloat64()}
+		inputs = append(inputs, in)
+		outputs = append(outputs, linalg.Vector{in[0] - 2*in[1]})
+	}
+	samples := VectorSampleSet(inputs, outputs)
+
+	// The validation targets are unrelated to the training
+	// targets, so the validation cost will stop improving.
+	var valInputs, valOutputs []linalg.Vector
+	for i := 0; i < 10; i++ {
+		valInputs = append(valInputs, linalg.Vector{rand.NormFloat64(), rand.NormFloat64()})
+		valOutputs = append(valOutputs, linalg.Vector{rand.NormFloat64()})
+	}
+	validation := VectorSampleSet(valInputs, valOutputs)
+
+	net := Network{NewDenseLayer(2, 1)}
+	trainer := &Trainer{
+		Network:    net,
+		CostFunc:   MeanSquaredCost{},
+		StepSize:   0.01,
+		BatchSize:  5,
+		Validation: validation,
+		Patience:   3,
+	}
+	var callbacks int
+	trainer.Callback = func(m EpochMetrics) bool {
+		callbacks++
+		return true
+	}
+	metrics := trainer.Train(samples, 1000)
+	if len(metrics) == 1000 {
+		t.Fatal("training did not stop early")
+	}
+	if callbacks != len(metrics) {
+		t.Errorf("expected %d callbacks but got %d", len(metrics), callbacks)
+	}
+
+	bestIdx := 0
+	for i, m := range metrics {
+		if m.Epoch != i {
+			t.Errorf("metric %d has epoch %d", i, m.Epoch)
+		}
+		if m.ValidationCost < metrics[bestIdx].ValidationCost {
+			bestIdx = i
+		}
+	}
+	if len(metrics)-1-bestIdx != 3 {
+		t.Errorf("expected to stop 3 epochs after %d, but stopped at %d", bestIdx,
+			len(metrics)-1)
+	}
+
+	actual := TotalCost(MeanSquaredCost{}, net, validation)
+	if math.Abs(actual-metrics[bestIdx].ValidationCost) > 1e-8 {
+		t.Errorf("expected restored validation cost %f but got %f",
+			metrics[bestIdx].ValidationCost, actual)
+	}
+}
+
+func TestTrainerRestoreState(t *testing.T) {
+	rand.Seed(123)
+	var inputs, outputs, valInputs, valOutputs []linalg.Vector
+	for i := 0; i < 20; i++ {
+		in := linalg.Vector{rand.NormFloat64(), rand.NormFloat64()}
+		inputs = append(inputs, in)
+		outputs = append(outputs, linalg.Vector{in[0] - 2*in[1]})
+		valInputs = append(valInputs, linalg.Vector{rand.NormFloat64(), rand.NormFloat64()})
+		valOutputs = append(valOutputs, linalg.Vector{rand.NormFloat64()})
+	}
+
+	dense := NewDenseLayer(2, 3)
+	norm := NewBatchNormLayer(3)
+	norm.Training = true
+	net := Network{dense, norm, NewDenseLayer(3, 1)}
+	trainer := &Trainer{
+		Network:    net,
+		CostFunc:   MeanSquaredCost{},
+		StepSize:   0.01,
+		BatchSize:  5,
+		Validation: VectorSampleSet(valInputs, valOutputs),
+		Patience:   3,
+	}
+	var means, weights []linalg.Vector
+	trainer.Callback = func(m EpochMetrics) bool {
+		means = append(means, norm.RunningMean.Copy())
+		weights = append(weights, dense.Weights.Data.Vector.Copy())
+
+		// The frozen weights must still be restored.
+		if m.Epoch == 1 {
+			dense.SetTrainable(false)
+		}
+		return true
+	}
+	metrics := trainer.Train(VectorSampleSet(inputs, outputs), 100)
+
+	bestIdx := 0
+	for i, m := range metrics {
+		if m.ValidationCost < metrics[bestIdx].ValidationCost {
+			bestIdx = i
+		}
+	}
+	if trainer.Network[1] != norm || trainer.Network[0] != dense {
+		t.Error("layers were replaced")
+	}
+	if norm.RunningMean.Copy().Scale(-1).Add(means[bestIdx]).MaxAbs() != 0 {
+		t.Errorf("expected running mean %v but got %v", means[bestIdx], norm.RunningMean)
+	}
+	if dense.Weights.Data.Vector.Copy().Scale(-1).Add(weights[bestIdx]).MaxAbs() != 0 {
+		t.Errorf("expected weights %v but got %v", weights[bestIdx],
+			dense.Weights.Data.Vector)
+	}
+}
+
+func TestTrainerNoValidation(t *testing.T) {
+	samples := sgd.SliceSampleSet{
+		VectorSample{Input: linalg.Vector{1}, Output: linalg.Vector{2}},
+	}
+	trainer := &Trainer{
+		Network:   Network{NewDenseLayer(1, 1)},
+		CostFunc:  MeanSquaredCost{},
+		StepSize:  0.1,
+		BatchSize: 1,
+	}
+	metrics := trainer.Train(samples, 20)
+	if len(metrics) != 20 {
+		t.Fatalf("expected 20 epochs but got %d", len(metrics))
+	}
+	if metrics[19].TrainingCost >= metrics[0].TrainingCost {
+		t.Error("training cost did not decrease")
+	}
+}
+
+func TestTrainerFullBatch(t *testing.T) {
+	samples := sgd.SliceSampleSet{
+		VectorSample{Input: linalg.Vector{1, -1}, Output: linalg.Vector{2}},
+		VectorSample{Input: linalg.Vector{0.5, 2}, Output: linalg.Vector{-1}},
+		VectorSample{Input: linalg.Vector{-3, 1}, Output: linalg.Vector{0.5}},
+	}
+	for _, seed := range []int64{0, 1337} {
+		net := Network{NewDenseLayer(2, 1)}
+		expected, err := net.Clone()
+		if err != nil {
+			t.Fatal(err)
+		}
+		(&BatchRGradienter{
+			Learner:  expected.BatchLearner(),
+			CostFunc: MeanSquaredCost{},
+		}).Gradient(samples).AddToVars(-0.1)
+
+		trainer := &Trainer{
+			Network:  net,
+			CostFunc: MeanSquaredCost{},
+			StepSize: 0.1,
+			Seed:     seed,
+		}
+		trainer.Train(samples, 1)
+
+		expParams := expected.Parameters()
+		for i, p := range net.Parameters() {
+			diff := p.Vector.Copy().Scale(-1).Add(expParams[i].Vector).MaxAbs()
+			if diff > 1e-8 {
+				t.Errorf("seed %d, parameter %d: expected %v but got %v", seed, i,
+					expParams[i].Vector, p.Vector)
+			}
+		}
+	}
+}
+
+func TestTrainerCallback(t *testing.T) {
+	samples := sgd.SliceSampleSet{
+		VectorSample{Input: linalg.Vector{1}, Output: linalg.Vector{2}},
+	}
+	var calls []EpochMetrics
+	trainer := &Trainer{
+		Network:   Network{NewDenseLayer(1, 1)},
+		CostFunc:  MeanSquaredCost{},
+		StepSize:  0.1,
+		BatchSize: 1,
+		Callback: func(m EpochMetrics) bool {
+			calls = append(calls, m)
+			return m.Epoch < 4
+		},
+	}
+	metrics := trainer.Train(samples, 20)
+	if len(metrics) != 5 || len(calls) != 5 {
+		t.Fatalf("expected 5 epochs and callbacks but got %d and %d",
+			len(metrics), len(calls))
+	}
+	for i, m := range calls {
+		if m != metrics[i] {
+			t.Errorf("callback %d got %v but expected %v", i, m, metrics[i])
+		}
+		if i > 0 && m.Elapsed < calls[i-1].Elapsed {
+			t.Errorf("elapsed time decreased at epoch %d", i)
+		}
+	}
+}
+
+func TestTrainerAverage(t *testing.T) {
+	samples := sgd.SliceSampleSet{
+		VectorSample{Input: linalg.Vector{1, -1}, Output: linalg.Vector{2}},
+		VectorSample{Input: linalg.Vector{0.5, 2}, Output: linalg.Vector{-1}},
+		VectorSample{Input: linalg.Vector{-3, 1}, Output: linalg.Vector{0.5}},
+	}
+	net := Network{NewDenseLayer(2, 1)}
+	expected, err := net.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With a single batch, one epoch is exactly one step
+	// along the mean gradient.
+	grad := (&BatchRGradienter{
+		Learner:  expected.BatchLearner(),
+		CostFunc: MeanSquaredCost{},
+	}).Gradient(samples)
+	grad.AddToVars(-0.1 / float64(samples.Len()))
+
+	trainer := &Trainer{
+		Network:   net,
+		CostFunc:  MeanSquaredCost{},
+		StepSize:  0.1,
+		BatchSize: 10,
+		Average:   true,
+	}
+	trainer.Train(samples, 1)
+
+	expParams := expected.Parameters()
+	for i, p := range net.Parameters() {
+		diff := p.Vector.Copy().Scale(-1).Add(expParams[i].Vector).MaxAbs()
+		if diff > 1e-8 {
+			t.Errorf("parameter %d: expected %v but got %v", i, expParams[i].Vector,
+				p.Vector)
+		}
+	}
+}
+
+func TestAverageGradienter(t *testing.T) {
+	samples := sgd.SliceSampleSet{
+		VectorSample{Input: linalg.Vector{1, -1}, Output: linalg.Vector{2}},
+		VectorSample{Input: linalg.Vector{0.5, 2}, Output: linalg.Vector{-1}},
+	}
+	net := Network{NewDenseLayer(2, 1)}
+	total := (&BatchRGradienter{
+		Learner:  net.BatchLearner(),
+		CostFunc: MeanSquaredCost{},
+	}).Gradient(samples).Copy()
+	avg := (&AverageGradienter{
+		Gradienter: &BatchRGradienter{
+			Learner:  net.BatchLearner(),
+			CostFunc: MeanSquaredCost{},
+		},
+	}).Gradient(samples)
+	for _, p := range net.Parameters() {
+		expected := total[p].Copy().Scale(0.5)
+		if diff := expected.Copy().Scale(-1).Add(avg[p]).MaxAbs(); diff > 1e-8 {
+			t.Errorf("expected %v but got %v", expected, avg[p])
+		}
+	}
+}
+
+func TestTrainerCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trainer_checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint")
+
+	samples := sgd.SliceSampleSet{}
+	for i := 0; i < 20; i++ {
+		in := linalg.Vector{rand.NormFloat64(), rand.NormFloat64()}
+		out := linalg.Vector{math.Sin(in[0]) * in[1]}
+		samples = append(samples, VectorSample{Input: in, Output: out})
+	}
+	newTrainer := func(seed int64) *Trainer {
+		norm := NewBatchNormLayer(4)
+		norm.Training = true
+		net := Network{NewDenseLayer(2, 4), norm, &HyperbolicTangent{}, NewDenseLayer(4, 1)}
+		net.RandomizeWithRand(rand.New(rand.NewSource(seed)))
+		adam := &AdamOptimizer{
+			Gradienter: &BatchRGradienter{
+				Learner:  net.BatchLearner(),
+				CostFunc: MeanSquaredCost{},
+			},
+			Learner: net,
+		}
+		return &Trainer{
+			Network:  net,
+			CostFunc: MeanSquaredCost{},
+			Gradienter: &ScheduledGradienter{
+				Gradienter: &checkpointTestWrapper{Gradienter: adam},
+				Schedule:   &ExponentialDecay{Initial: 1, Factor: 0.99},
+			},
+			StepSize:   0.01,
+			BatchSize:  3,
+			Validation: samples[:5],
+			Seed:       1337,
+		}
+	}
+
+	uninterrupted := newTrainer(1)
+	expMetrics := uninterrupted.Train(samples, 6)
+
+	interrupted := newTrainer(1)
+	interrupted.Callback = func(m EpochMetrics) bool {
+		if m.Epoch == 2 {
+			if err := interrupted.SaveCheckpoint(path); err != nil {
+				t.Fatal(err)
+			}
+			return false
+		}
+		return true
+	}
+	interrupted.Train(samples, 6)
+
+	resumed := newTrainer(2)
+	if err := resumed.LoadCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	metrics := resumed.Train(samples, 6)
+
+	if len(metrics) != len(expMetrics) {
+		t.Fatalf("expected %d epochs but got %d", len(expMetrics), len(metrics))
+	}
+	for i, m := range metrics {
+		exp := expMetrics[i]
+		m.Elapsed, exp.Elapsed = 0, 0
+		if m != exp {
+			t.Errorf("epoch %d: expected %v but got %v", i, exp, m)
+		}
+	}
+	expParams := uninterrupted.Network.Parameters()
+	for i, p := range resumed.Network.Parameters() {
+		if !reflect.DeepEqual(p.Vector, expParams[i].Vector) {
+			t.Errorf("parameter %d: expected %v but got %v", i, expParams[i].Vector,
+				p.Vector)
+		}
+	}
+	expNorm := uninterrupted.Network[1].(*BatchNormLayer)
+	actualNorm := resumed.Network[1].(*BatchNormLayer)
+	if !reflect.DeepEqual(actualNorm.RunningMean, expNorm.RunningMean) ||
+		!reflect.DeepEqual(actualNorm.RunningVariance, expNorm.RunningVariance) {
+		t.Errorf("expected running statistics %v, %v but got %v, %v",
+			expNorm.RunningMean, expNorm.RunningVariance,
+			actualNorm.RunningMean, actualNorm.RunningVariance)
+	}
+}
+
+// checkpointTestWrapper is a GradienterWrapper which is
+// not known to the checkpointing code.
+type checkpointTestWrapper struct {
+	Gradienter sgd.Gradienter
+}
+
+func (c *checkpointTestWrapper) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return c.Gradienter.Gradient(s)
+}
+
+func (c *checkpointTestWrapper) WrappedGradienter() sgd.Gradienter {
+	return c.Gradienter
+}
+
+func TestTrainerCheckpointMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trainer_checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint")
+
+	net := Network{NewDenseLayer(2, 1)}
+	gradienter := &BatchRGradienter{Learner: net.BatchLearner(), CostFunc: MeanSquaredCost{}}
+	trainer := &Trainer{
+		Network:    net,
+		CostFunc:   MeanSquaredCost{},
+		Gradienter: &AdamOptimizer{Gradienter: gradienter, Learner: net},
+	}
+	if err := trainer.SaveCheckpoint(path); err != nil {
+		t.Fatal(err)
+	}
+	trainer.Gradienter = &RMSPropOptimizer{Gradienter: gradienter, Learner: net}
+	if err := trainer.LoadCheckpoint(path); err == nil {
+		t.Error("expected error for mismatched gradienter")
+	}
+
+	trainer.Gradienter = &AdamOptimizer{Gradienter: gradienter, Learner: net}
+	trainer.Network = Network{NewDenseLayer(2, 2)}
+	if err := trainer.LoadCheckpoint(path); err == nil {
+		t.Error("expected error for mismatched network")
+	}
+}
@@ -0,0 +1,108 @@
+package neuralnet
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/sgd"
+)
+
+// WeightDecay implements L2 regularization by adding
+// Decay*w to the gradient of every variable w in
+// Variables.
+// Since SGD subtracts gradients from the variables,
+// this shrinks the variables towards zero.
+//
+// To regularize all of a Network's weights without
+// regularizing its biases, use Network.Weights() for
+// Variables.
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type WeightDecay struct {
+	Gradienter sgd.Gradienter
+	Variables  []*autofunc.Variable
+	Decay      float64
+}
+
+func (w *WeightDecay) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return w.Transform(w.Gradienter.Gradient(s))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (w *WeightDecay) WrappedGradienter() sgd.Gradienter {
+	return w.Gradienter
+}
+
+func (w *WeightDecay) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	for _, variable := range w.Variables {
+		if gradVec, ok := grad[variable]; ok {
+			gradVec.Add(variable.Vector.Copy().Scale(w.Decay))
+		}
+	}
+	return grad
+}
+
+// L1Decay implements L1 regularization by adding
+// Decay*sign(w) to the gradient of every component w
+// of every variable in Variables.
+// Components which are exactly zero are not changed,
+// making it possible for weights to become sparse.
+//
+// Like WeightDecay, this can be applied to a Network's
+// weights (but not its biases) via Network.Weights().
+//
+// When used as a Gradienter, this will use its wrapped
+// Gradienter to acquire gradients and then pass said
+// gradients to Transform.
+type L1Decay struct {
+	Gradienter sgd.Gradienter
+	Variables  []*autofunc.Variable
+	Decay      float64
+}
+
+func (l *L1Decay) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	return l.Transform(l.Gradienter.Gradient(s))
+}
+
+// WrappedGradienter returns the wrapped Gradienter.
+func (l *L1Decay) WrappedGradienter() sgd.Gradienter {
+	return l.Gradienter
+}
+
+func (l *L1Decay) Transform(grad autofunc.Gradient) autofunc.Gradient {
+	for _, variable := range l.Variables {
+		gradVec, ok := grad[variable]
+		if !ok {
+			continue
+		}
+		for i, x := range variable.Vector {
+			if x > 0 {
+				gradVec[i] += l.Decay
+			} else if x < 0 {
+				gradVec[i] -= l.Decay
+			}
+		}
+	}
+	return grad
+}
+
+// WeightSparsity returns the fraction of the components
+// of the given variables whose absolute values are less
+// than threshold.
+func WeightSparsity(vars []*autofunc.Variable, threshold float64) float64 {
+	var total, sparse int
+	for _, variable := range vars {
+		for _, x := range variable.Vector {
+			if math.Abs(x) < threshold {
+				sparse++
+			}
+		}
+		total += len(variable.Vector)
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(sparse) / float64(total)
+}
@@ -0,0 +1,124 @@
+package neuralnet
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+)
+
+func TestWeightDecayTransform(t *testing.T) {
+	net := optimizerTestNetwork()
+	decay := &WeightDecay{Variables: net.Weights(), Decay: 0.1}
+	grad := randomOptimizerGradient(net)
+	expected := grad.Copy()
+	for _, layer := range net {
+		if dense, ok := layer.(*DenseLayer); ok {
+			weights := dense.Weights.Data
+			expected[weights].Add(weights.Vector.Copy().Scale(0.1))
+		}
+	}
+	checkOptimizerGradients(t, net, decay.Transform(grad), expected)
+}
+
+func TestWeightDecayShrink(t *testing.T) {
+	rand.Seed(123)
+	var inputs, outputs []linalg.Vector
+	for i := 0; i < 20; i++ {
+		inputs = append(inputs, linalg.Vector{0, 0, 0})
+		outputs = append(outputs, linalg.Vector{0, 0})
+	}
+	samples := VectorSampleSet(inputs, outputs)
+
+	net := Network{&DenseLayer{InputCount: 3, OutputCount: 2}}
+	net.Randomize()
+	weights := net.Weights()[0]
+	initialNorm := weights.Vector.Mag()
+	biases := net[0].(*DenseLayer).Biases.Var.Vector.Copy()
+
+	gradienter := &WeightDecay{
+		Gradienter: &BatchRGradienter{
+			Learner:  net.BatchLearner(),
+			CostFunc: MeanSquaredCost{},
+		},
+		Variables: net.Weights(),
+		Decay:     0.5,
+	}
+	sgd.SGD(gradienter, samples, 0.1, 10, 20)
+
+	// Since the inputs are zero, the cost gradient w.r.t.
+	// the weights is zero, and only the decay matters.
+	expectedNorm := initialNorm * math.Pow(1-0.1*0.5, 10)
+	if math.Abs(weights.Vector.Mag()-expectedNorm) > 1e-8 {
+		t.Errorf("expected weight norm %f but got %f", expectedNorm,
+			weights.Vector.Mag())
+	}
+
+	// The biases are trained towards zero by the cost,
+	// but they should not be affected by decay.
+	noDecayNet := Network{&DenseLayer{InputCount: 3, OutputCount: 2}}
+	noDecayNet.Randomize()
+	noDecayBiases := noDecayNet[0].(*DenseLayer).Biases.Var
+	copy(noDecayBiases.Vector, biases)
+	sgd.SGD(&BatchRGradienter{
+		Learner:  noDecayNet.BatchLearner(),
+		CostFunc: MeanSquaredCost{},
+	}, samples, 0.1, 10, 20)
+	actualBiases := net[0].(*DenseLayer).Biases.Var.Vector
+	for i, x := range noDecayBiases.Vector {
+		if math.Abs(x-actualBiases[i]) > 1e-8 {
+			t.Errorf("bias %d: expected %f but got %f", i, x, actualBiases[i])
+		}
+	}
+}
+
+func TestNetworkWeights(t *testing.T) {
+	net := optimizerTestNetwork()
+	net = append(net, &ResidualLayer{Network: Network{NewDenseLayer(2, 2)}})
+	weights := net.Weights()
+	if len(weights) != 3 {
+		t.Fatalf("expected 3 weight variables but got %d", len(weights))
+	}
+	var expected []*autofunc.Variable
+	for i, param := range net.Parameters() {
+		// DenseLayer parameters are weights followed
+		// by biases.
+		if i%2 == 0 {
+			expected = append(expected, param)
+		}
+	}
+	for i, w := range weights {
+		if w != expected[i] {
+			t.Errorf("weight %d is not the expected variable", i)
+		}
+	}
+}
+
+func TestL1DecayTransform(t *testing.T) {
+	variable := &autofunc.Variable{Vector: linalg.Vector{-2, 0, 0.5, 3}}
+	grad := autofunc.Gradient{variable: linalg.Vector{1, 1, 1, 1}}
+	decay := &L1Decay{Variables: []*autofunc.Variable{variable}, Decay: 0.25}
+	actual := decay.Transform(grad)[variable]
+	expected := linalg.Vector{0.75, 1, 1.25, 1.25}
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("entry %d: expected %f but got %f", i, x, actual[i])
+		}
+	}
+}
+
+func TestWeightSparsity(t *testing.T) {
+	vars := []*autofunc.Variable{
+		{Vector: linalg.Vector{0, 0.5, -0.01}},
+		{Vector: linalg.Vector{1e-4, -2}},
+	}
+	if actual := WeightSparsity(vars, 0.1); math.Abs(actual-0.6) > 1e-8 {
+		t.Errorf("expected sparsity 0.6 but got %f", actual)
+	}
+	if actual := WeightSparsity(nil, 0.1); actual != 0 {
+		t.Errorf("expected sparsity 0 but got %f", actual)
+	}
+}
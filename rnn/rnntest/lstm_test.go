@@ -1,8 +1,11 @@
 package rnntest
 
 import (
+	"math"
 	"testing"
 
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
 	"github.com/unixpickle/weakai/rnn"
 )
 
@@ -10,3 +13,36 @@ func TestLSTM(t *testing.T) {
 	b := rnn.NewLSTM(4, 2)
 	NewChecker4In(b, b).FullCheck(t)
 }
+
+func TestLSTMSerialize(t *testing.T) {
+	b := rnn.NewLSTM(3, 2)
+	data, err := serializer.SerializeWithType(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := serializer.DeserializeWithType(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newBlock, ok := decoded.(*rnn.LSTM)
+	if !ok {
+		t.Fatalf("expected *rnn.LSTM but got %T", decoded)
+	}
+
+	seqs := [][]linalg.Vector{
+		{{1, -0.5, 0.3}, {0.2, 0.7, -1}, {-0.4, 0.1, 0.9}},
+		{{0.5, 0.5, -0.5}},
+	}
+	expected := (&rnn.Runner{Block: b}).RunAll(seqs)
+	actual := (&rnn.Runner{Block: newBlock}).RunAll(seqs)
+	for i, seq := range expected {
+		for j, vec := range seq {
+			for k, x := range vec {
+				if math.Abs(actual[i][j][k]-x) > 1e-8 {
+					t.Errorf("seq %d time %d output %d: expected %f but got %f",
+						i, j, k, x, actual[i][j][k])
+				}
+			}
+		}
+	}
+}
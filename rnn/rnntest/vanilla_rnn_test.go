@@ -0,0 +1,16 @@
+package rnntest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/weakai/neuralnet"
+	"github.com/unixpickle/weakai/rnn"
+)
+
+func TestVanillaRNN(t *testing.T) {
+	b := rnn.NewVanillaRNN(4, 2, nil)
+	NewChecker4In(b, b).FullCheck(t)
+
+	b = rnn.NewVanillaRNN(4, 3, &neuralnet.Sigmoid{})
+	NewChecker4In(b, b).FullCheck(t)
+}
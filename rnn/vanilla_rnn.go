@@ -0,0 +1,48 @@
+package rnn
+
+import (
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// VanillaRNN is an RNN whose new state is computed by
+// applying an activation function to an affine
+// transformation of its input and its previous state.
+// A VanillaRNN outputs its current state, so that Blocks
+// may be stacked on top of it to use this state.
+type VanillaRNN interface {
+	Block
+	serializer.Serializer
+	sgd.Learner
+}
+
+// NewVanillaRNN creates a block for a vanilla RNN with
+// the given number of hidden units.
+//
+// The input-to-hidden and hidden-to-hidden weights are
+// stored in a single neuralnet.DenseLayer, whose first
+// inputSize columns correspond to the input.
+// The weights are initialized with Xavier initialization.
+//
+// If activation is nil, neuralnet.HyperbolicTangent is
+// used.
+func NewVanillaRNN(inputSize, hiddenSize int, activation neuralnet.Layer) VanillaRNN {
+	if activation == nil {
+		activation = &neuralnet.HyperbolicTangent{}
+	}
+	matrix := &neuralnet.DenseLayer{
+		InputCount:  inputSize + hiddenSize,
+		OutputCount: hiddenSize,
+	}
+	matrix.InitXavier()
+
+	network := neuralnet.Network{
+		matrix,
+		activation,
+	}
+
+	return &StateOutBlock{
+		Block: NewNetworkBlock(network, hiddenSize),
+	}
+}